@@ -40,4 +40,466 @@ CREATE TABLE user (
     
     PRIMARY KEY (id)
 );
---rollback DROP TABLE user;
\ No newline at end of file
+--rollback DROP TABLE user;
+
+--changeset josephspurrier:4
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE refresh_token (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NOT NULL,
+    family_id VARCHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    
+    expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (token_hash),
+    KEY (family_id),
+    CONSTRAINT `f_refresh_token_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE refresh_token;
+
+--changeset josephspurrier:5
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE token_revocation (
+    jti VARCHAR(36) NOT NULL,
+    
+    expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    KEY (expires_at),
+    
+    PRIMARY KEY (jti)
+);
+--rollback DROP TABLE token_revocation;
+
+--changeset josephspurrier:6
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE role (
+    id TINYINT(1) UNSIGNED NOT NULL AUTO_INCREMENT,
+    
+    name VARCHAR(50) NOT NULL,
+    
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (name),
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE role;
+
+--changeset josephspurrier:7
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE permission (
+    id SMALLINT(2) UNSIGNED NOT NULL AUTO_INCREMENT,
+    
+    name VARCHAR(100) NOT NULL,
+    
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (name),
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE permission;
+
+--changeset josephspurrier:8
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE role_permission (
+    role_id TINYINT(1) UNSIGNED NOT NULL,
+    permission_id SMALLINT(2) UNSIGNED NOT NULL,
+    
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    CONSTRAINT `f_role_permission_role` FOREIGN KEY (`role_id`) REFERENCES `role` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_role_permission_permission` FOREIGN KEY (`permission_id`) REFERENCES `permission` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (role_id, permission_id)
+);
+--rollback DROP TABLE role_permission;
+
+--changeset josephspurrier:9
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE user_role (
+    user_id VARCHAR(36) NOT NULL,
+    role_id TINYINT(1) UNSIGNED NOT NULL,
+    
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    CONSTRAINT `f_user_role_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_user_role_role` FOREIGN KEY (`role_id`) REFERENCES `role` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (user_id, role_id)
+);
+--rollback DROP TABLE user_role;
+
+--changeset josephspurrier:10
+INSERT INTO `role` (`id`, `name`, `created_at`, `updated_at`) VALUES
+(1, 'admin', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(2, 'user',  CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+INSERT INTO `permission` (`id`, `name`, `created_at`, `updated_at`) VALUES
+(1, 'user:read',       CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(2, 'user:update',     CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(3, 'user:delete',     CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(4, 'user:delete_all', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(5, 'role:read',       CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+(6, 'role:assign',     CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+INSERT INTO `role_permission` (`role_id`, `permission_id`) VALUES
+(1, 1),
+(1, 2),
+(1, 3),
+(1, 4),
+(1, 5),
+(1, 6),
+(2, 1),
+(2, 2),
+(2, 3);
+INSERT INTO `user_role` (`user_id`, `role_id`)
+SELECT `id`, 2 FROM `user`;
+--rollback DELETE FROM user_role;
+--rollback DELETE FROM role_permission;
+--rollback DELETE FROM permission;
+--rollback DELETE FROM role;
+
+--changeset josephspurrier:11
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE api_key (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix CHAR(8) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scope VARCHAR(255) NOT NULL DEFAULT '',
+    
+    last_used_at TIMESTAMP NULL DEFAULT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (key_hash),
+    CONSTRAINT `f_api_key_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE api_key;
+
+--changeset josephspurrier:12
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE user_token (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NOT NULL,
+    purpose VARCHAR(20) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    
+    expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (token_hash),
+    KEY (user_id, purpose),
+    CONSTRAINT `f_user_token_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE user_token;
+
+--changeset josephspurrier:13
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE user_revocation (
+    user_id VARCHAR(36) NOT NULL,
+    
+    revoked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    CONSTRAINT `f_user_revocation_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (user_id)
+);
+--rollback DROP TABLE user_revocation;
+
+--changeset josephspurrier:14
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE user_totp (
+    user_id VARCHAR(36) NOT NULL,
+    
+    secret VARCHAR(64) NOT NULL,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    
+    confirmed_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    CONSTRAINT `f_user_totp_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (user_id)
+);
+--rollback DROP TABLE user_totp;
+
+--changeset josephspurrier:15
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE recovery_code (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NOT NULL,
+    code_hash CHAR(60) NOT NULL,
+    
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    CONSTRAINT `f_recovery_code_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE recovery_code;
+
+--changeset josephspurrier:16
+ALTER TABLE user_token ADD attempts TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER token_hash;
+--rollback ALTER TABLE user_token DROP COLUMN attempts;
+
+--changeset josephspurrier:17
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE login_throttle (
+    scope VARCHAR(10) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    
+    failures INT UNSIGNED NOT NULL DEFAULT 0,
+    blocked_until TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    PRIMARY KEY (scope, subject)
+);
+CREATE TABLE lockout_event (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NULL DEFAULT NULL,
+    email VARCHAR(255) NOT NULL,
+    event VARCHAR(10) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    failures INT UNSIGNED NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP NULL DEFAULT NULL,
+    created_by VARCHAR(36) NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    CONSTRAINT `f_lockout_event_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE SET NULL ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+INSERT INTO `permission` (`id`, `name`, `created_at`, `updated_at`) VALUES
+(7, 'user:unlock', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+INSERT INTO `role_permission` (`role_id`, `permission_id`) VALUES
+(1, 7);
+--rollback DELETE FROM role_permission WHERE permission_id = 7;
+--rollback DELETE FROM permission WHERE id = 7;
+--rollback DROP TABLE lockout_event;
+--rollback DROP TABLE login_throttle;
+
+--changeset josephspurrier:18
+ALTER TABLE user MODIFY password VARCHAR(255) NOT NULL;
+ALTER TABLE recovery_code MODIFY code_hash VARCHAR(255) NOT NULL;
+--rollback ALTER TABLE recovery_code MODIFY code_hash CHAR(60) NOT NULL;
+--rollback ALTER TABLE user MODIFY password CHAR(60) NOT NULL;
+
+--changeset josephspurrier:19
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE oauth_client (
+    id VARCHAR(36) NOT NULL,
+    
+    name VARCHAR(100) NOT NULL,
+    secret_hash CHAR(64) NOT NULL DEFAULT '',
+    scope VARCHAR(1000) NOT NULL DEFAULT '',
+    redirect_uris TEXT NOT NULL,
+    
+    created_by VARCHAR(36) NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    PRIMARY KEY (id)
+);
+CREATE TABLE oauth_code (
+    id VARCHAR(36) NOT NULL,
+    
+    client_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    redirect_uri VARCHAR(1000) NOT NULL DEFAULT '',
+    scope VARCHAR(1000) NOT NULL DEFAULT '',
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',
+    
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (code_hash),
+    CONSTRAINT `f_oauth_code_client` FOREIGN KEY (`client_id`) REFERENCES `oauth_client` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_oauth_code_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+CREATE TABLE oauth_refresh_token (
+    id VARCHAR(36) NOT NULL,
+    
+    client_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    family_id VARCHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scope VARCHAR(1000) NOT NULL DEFAULT '',
+    
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (token_hash),
+    KEY (family_id),
+    CONSTRAINT `f_oauth_refresh_token_client` FOREIGN KEY (`client_id`) REFERENCES `oauth_client` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT `f_oauth_refresh_token_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+INSERT INTO `permission` (`id`, `name`, `created_at`, `updated_at`) VALUES
+(8, 'oauth:client', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+INSERT INTO `role_permission` (`role_id`, `permission_id`) VALUES
+(1, 8);
+--rollback DELETE FROM role_permission WHERE permission_id = 8;
+--rollback DELETE FROM permission WHERE id = 8;
+--rollback DROP TABLE oauth_refresh_token;
+--rollback DROP TABLE oauth_code;
+--rollback DROP TABLE oauth_client;
+
+--changeset josephspurrier:20
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE oidc_login (
+    id VARCHAR(36) NOT NULL,
+    
+    provider VARCHAR(50) NOT NULL,
+    state_hash CHAR(64) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (state_hash),
+    
+    PRIMARY KEY (id)
+);
+CREATE TABLE user_identity (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NOT NULL,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    UNIQUE KEY (issuer, subject),
+    CONSTRAINT `f_user_identity_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+--rollback DROP TABLE user_identity;
+--rollback DROP TABLE oidc_login;
+
+--changeset josephspurrier:21
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+CREATE TABLE user_session (
+    id VARCHAR(36) NOT NULL,
+    
+    user_id VARCHAR(36) NOT NULL,
+    family_id VARCHAR(36) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    
+    issued_at TIMESTAMP NULL DEFAULT NULL,
+    expires_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    
+    KEY (family_id),
+    CONSTRAINT `f_user_session_user` FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    
+    PRIMARY KEY (id)
+);
+INSERT INTO `permission` (`id`, `name`, `created_at`, `updated_at`) VALUES
+(9, 'token:introspect', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+INSERT INTO `role_permission` (`role_id`, `permission_id`) VALUES
+(1, 9);
+--rollback DELETE FROM role_permission WHERE permission_id = 9;
+--rollback DELETE FROM permission WHERE id = 9;
+--rollback DROP TABLE user_session;
+
+--changeset josephspurrier:22
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+ALTER TABLE user MODIFY deleted_at TIMESTAMP NULL DEFAULT NULL;
+UPDATE user SET deleted_at = NULL;
+ALTER TABLE user ADD KEY (deleted_at);
+INSERT INTO `permission` (`id`, `name`, `created_at`, `updated_at`) VALUES
+(10, 'user:restore', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+INSERT INTO `role_permission` (`role_id`, `permission_id`) VALUES
+(1, 10);
+--rollback DELETE FROM role_permission WHERE permission_id = 10;
+--rollback DELETE FROM permission WHERE id = 10;
+--rollback ALTER TABLE user DROP KEY deleted_at;
+--rollback UPDATE user SET deleted_at = 0 WHERE deleted_at IS NULL;
+--rollback ALTER TABLE user MODIFY deleted_at TIMESTAMP DEFAULT 0;
+
+--changeset josephspurrier:23
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+DELETE s FROM user_session s
+JOIN user_session n ON n.family_id = s.family_id AND n.id > s.id;
+UPDATE user_session SET id = family_id;
+UPDATE user_session s
+SET expires_at = (SELECT MAX(r.expires_at) FROM refresh_token r WHERE r.family_id = s.id)
+WHERE EXISTS (SELECT 1 FROM refresh_token r WHERE r.family_id = s.id);
+ALTER TABLE user_session
+    DROP KEY family_id,
+    DROP COLUMN family_id,
+    CHANGE issued_at last_seen_at TIMESTAMP NULL DEFAULT NULL;
+--rollback ALTER TABLE user_session CHANGE last_seen_at issued_at TIMESTAMP NULL DEFAULT NULL, ADD family_id VARCHAR(36) NOT NULL DEFAULT '' AFTER user_id, ADD KEY (family_id);
+--rollback UPDATE user_session SET family_id = id;
+
+--changeset josephspurrier:24
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+ALTER TABLE user_session ADD client_id VARCHAR(36) NOT NULL DEFAULT '' AFTER user_id;
+--rollback ALTER TABLE user_session DROP COLUMN client_id;
+
+--changeset josephspurrier:25
+SET sql_mode = 'NO_AUTO_VALUE_ON_ZERO';
+ALTER TABLE user ADD pending_email VARCHAR(100) NULL DEFAULT NULL AFTER email;
+--rollback ALTER TABLE user DROP COLUMN pending_email;
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"app/webapi/pkg/apikey"
+	"app/webapi/pkg/database"
+	"app/webapi/pkg/env"
+	"app/webapi/pkg/query"
+	"app/webapi/store"
+)
+
+// connect will connect to the database using the environment variables.
+func connect(prefix string) (*database.DBW, error) {
+	dbc := new(database.Connection)
+
+	// Load the struct from environment variables.
+	err := env.Unmarshal(dbc, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := dbc.Connect(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.New(connection), nil
+}
+
+// apikeyCreate will create an API key for the user with the email and output
+// the key. The key cannot be retrieved again.
+func apikeyCreate(prefix, email, name string, scopes []string, days int) error {
+	db, err := connect(prefix)
+	if err != nil {
+		return err
+	}
+
+	// Find the owner of the key.
+	u := store.NewUser(db, query.New(db))
+	exists, err := u.FindOneByField(u, "email", email)
+	if err != nil {
+		return err
+	} else if !exists {
+		return errors.New("user not found")
+	}
+
+	k := apikey.New(db)
+	ID, key, err := k.Create(u.ID, name, scopes, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("ID:", ID)
+	fmt.Println("Key:", key)
+	fmt.Println("Store the key somewhere safe, it will not be shown again.")
+
+	return nil
+}
+
+// apikeyList will output all the API keys.
+func apikeyList(prefix string) error {
+	db, err := connect(prefix)
+	if err != nil {
+		return err
+	}
+
+	k := apikey.New(db)
+	items, err := k.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPREFIX\tOWNER\tNAME\tSCOPE\tLAST USED\tEXPIRES\tREVOKED")
+	for _, v := range items {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", v.ID, v.Prefix,
+			v.UserID, v.Name, strings.Join(v.Scopes(), ","),
+			formatTime(v.LastUsedAt), formatTime(v.ExpiresAt),
+			formatTime(v.RevokedAt))
+	}
+
+	return w.Flush()
+}
+
+// apikeyRevoke will revoke an API key.
+func apikeyRevoke(prefix, ID string) error {
+	db, err := connect(prefix)
+	if err != nil {
+		return err
+	}
+
+	k := apikey.New(db)
+	revoked, err := k.Revoke(ID)
+	if err != nil {
+		return err
+	} else if !revoked {
+		return errors.New("api key not found or already revoked")
+	}
+
+	fmt.Println("API key revoked:", ID)
+
+	return nil
+}
+
+// formatTime returns the time as a string or a dash if it is not set.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
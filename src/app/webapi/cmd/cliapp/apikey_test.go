@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/apikey"
+	"app/webapi/pkg/query"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	u := store.NewUser(db, query.New(db))
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	// Create a key.
+	out := run(t, "apikey", "create", "jsmith@example.com", "batch",
+		"--scope", "user:read", "--scope", "user:update", "--days", "30",
+		"--envprefix", unique)
+
+	ID, key := "", ""
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "ID: ") {
+			ID = strings.TrimPrefix(line, "ID: ")
+		} else if strings.HasPrefix(line, "Key: ") {
+			key = strings.TrimPrefix(line, "Key: ")
+		}
+	}
+	assert.Equal(t, 36, len(ID))
+	assert.Equal(t, 43, len(key))
+
+	k := apikey.New(db)
+	item, err := k.Authenticate(key)
+	assert.Nil(t, err)
+	assert.Equal(t, userID, item.UserID)
+	assert.Equal(t, "batch", item.Name)
+	assert.Equal(t, []string{"user:read", "user:update"}, item.Scopes())
+	assert.NotNil(t, item.ExpiresAt)
+
+	// List the keys.
+	out = run(t, "apikey", "list", "--envprefix", unique)
+	assert.Contains(t, out, ID)
+	assert.Contains(t, out, key[:8])
+	assert.Contains(t, out, "user:read,user:update")
+	assert.NotContains(t, out, key)
+
+	// Revoke the key.
+	out = run(t, "apikey", "revoke", ID, "--envprefix", unique)
+	assert.Contains(t, out, "API key revoked")
+
+	_, err = k.Authenticate(key)
+	assert.Equal(t, apikey.ErrKeyInvalid, err)
+
+	testutil.TeardownDatabase(unique)
+}
+
+// run will call the application with the arguments and return the output.
+func run(t *testing.T, args ...string) string {
+	// Set the arguments.
+	os.Args = append([]string{"cliapp"}, args...)
+
+	// Redirect stdout.
+	backupd := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	// Call the application.
+	main()
+
+	// Get the output.
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	os.Stdout = backupd
+
+	return string(out)
+}
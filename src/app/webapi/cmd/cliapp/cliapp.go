@@ -30,6 +30,26 @@ var (
 	cDBDown      = cDB.Command("down", "Apply a specific number of rollbacks to the database.")
 	cDBDownCount = cDBDown.Arg("count", "Number of rollbacks [int].").Required().Int()
 	cDBDownFile  = cDBDown.Arg("file", "Filename of the migration file [string].").Required().String()
+
+	cAPIKey       = app.Command("apikey", "Manage the API keys for service-to-service clients.")
+	cAPIKeyPrefix = cAPIKey.Flag("envprefix", "Prefix for environment variables.").String()
+
+	cAPIKeyCreate       = cAPIKey.Command("create", "Create an API key for a user.")
+	cAPIKeyCreateEmail  = cAPIKeyCreate.Arg("email", "Email of the user that owns the key [string].").Required().String()
+	cAPIKeyCreateName   = cAPIKeyCreate.Arg("name", "Name to identify the key [string].").Required().String()
+	cAPIKeyCreateScopes = cAPIKeyCreate.Flag("scope", "Permission to grant to the key, can be repeated.").Strings()
+	cAPIKeyCreateDays   = cAPIKeyCreate.Flag("days", "Number of days until the key expires, 0 never expires.").Default("0").Int()
+
+	cAPIKeyList = cAPIKey.Command("list", "List all the API keys.")
+
+	cAPIKeyRevoke   = cAPIKey.Command("revoke", "Revoke an API key.")
+	cAPIKeyRevokeID = cAPIKeyRevoke.Arg("id", "ID of the key [string].").Required().String()
+
+	cPurge       = app.Command("purge", "Permanently remove the records that were deleted.")
+	cPurgePrefix = cPurge.Flag("envprefix", "Prefix for environment variables.").String()
+
+	cPurgeUsers     = cPurge.Command("users", "Remove the users that were deleted before the retention period.")
+	cPurgeUsersDays = cPurgeUsers.Flag("days", "Number of days to keep the deleted users.").Default("30").Int()
 )
 
 func main() {
@@ -71,5 +91,30 @@ func main() {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+	case cAPIKeyCreate.FullCommand():
+		err := apikeyCreate(*cAPIKeyPrefix, *cAPIKeyCreateEmail, *cAPIKeyCreateName,
+			*cAPIKeyCreateScopes, *cAPIKeyCreateDays)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case cAPIKeyList.FullCommand():
+		err := apikeyList(*cAPIKeyPrefix)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case cAPIKeyRevoke.FullCommand():
+		err := apikeyRevoke(*cAPIKeyPrefix, *cAPIKeyRevokeID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case cPurgeUsers.FullCommand():
+		err := purgeUsers(*cPurgePrefix, *cPurgeUsersDays)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
 }
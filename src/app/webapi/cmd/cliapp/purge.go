@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"app/webapi/pkg/query"
+	"app/webapi/store"
+)
+
+// purgeUsers will permanently remove the users that were deleted more than
+// the number of days ago.
+func purgeUsers(prefix string, days int) error {
+	db, err := connect(prefix)
+	if err != nil {
+		return err
+	}
+
+	u := store.NewUser(db, query.New(db))
+	count, err := u.Purge(u, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Users purged:", count)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/query"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeUsers(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	q := query.New(db)
+	u := store.NewUser(db, q)
+	oldID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	newID, err := u.Create("Jane", "Doe", "jdoe@example.com", "password")
+	assert.Nil(t, err)
+	activeID, err := u.Create("Jim", "Smith", "jim@example.com", "password")
+	assert.Nil(t, err)
+
+	// One user was deleted before the retention period.
+	for _, ID := range []string{oldID, newID} {
+		count, err := u.DeleteOneByID(u, ID)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	}
+	_, err = db.Exec(`UPDATE user SET deleted_at = DATE_SUB(NOW(), INTERVAL 40 DAY) WHERE id = ?`, oldID)
+	assert.Nil(t, err)
+
+	out := run(t, "purge", "users", "--days", "30", "--envprefix", unique)
+	assert.Contains(t, out, "Users purged: 1")
+
+	for _, v := range []struct {
+		ID    string
+		found bool
+	}{
+		{oldID, false},
+		{newID, true},
+		{activeID, true},
+	} {
+		found, err := q.WithDeleted().ExistsByID(u, v.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, v.found, found, v.ID)
+	}
+
+	testutil.TeardownDatabase(unique)
+}
@@ -38,7 +38,7 @@ func main() {
 	// Set up the service, routes, and the handlers.
 	core := webapi.Services(config, l)
 	mux := webapi.Routes(core)
-	httpServer, httpsServer := webapi.Handlers(config, l, mux)
+	httpServer, httpsServer := webapi.Handlers(config, core, mux)
 
 	// Start the listeners based on the config.
 	config.Server.Run(httpServer, httpsServer, l)
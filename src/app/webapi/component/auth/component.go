@@ -2,21 +2,50 @@ package auth
 
 import (
 	"app/webapi/component"
+	"app/webapi/pkg/oidc"
 )
 
 // New returns a new instance of the endpoint.
 func New(bc component.Core) *Endpoint {
+	providers := make(map[string]*oidc.Provider)
+	for _, c := range bc.Auth.OIDC {
+		providers[c.Name] = oidc.New(c)
+	}
+
 	return &Endpoint{
-		Core: bc,
+		Core:      bc,
+		providers: providers,
 	}
 }
 
 // Endpoint contains the dependencies.
 type Endpoint struct {
 	component.Core
+	providers map[string]*oidc.Provider
 }
 
 // Routes will set up the endpoints.
 func (p *Endpoint) Routes(router component.IRouter) {
-	router.Get("/v1/auth", p.Index)
+	if p.Auth.AnonymousToken {
+		router.Get("/v1/auth", p.Index)
+	}
+	router.Post("/v1/auth/login", p.Login)
+	router.Post("/v1/auth/refresh", p.Refresh)
+	router.Post("/v1/auth/logout", p.Logout)
+	router.Post("/v1/auth/introspect", p.Require(component.PermissionIntrospect, p.Introspect))
+	router.Post("/v1/auth/password/forgot", p.PasswordForgot)
+	router.Post("/v1/auth/password/reset", p.PasswordReset)
+	router.Post("/v1/auth/magic", p.MagicLink)
+	router.Post("/v1/auth/magic/verify", p.MagicLinkVerify)
+	router.Get("/v1/auth/verify", p.Verify)
+	router.Post("/v1/auth/verify", p.Verify)
+	router.Post("/v1/auth/2fa/enroll", p.TwoFactorEnroll)
+	router.Post("/v1/auth/2fa/confirm", p.TwoFactorConfirm)
+	router.Post("/v1/auth/2fa/disable", p.TwoFactorDisable)
+	router.Post("/v1/auth/2fa/verify", p.TwoFactorVerify)
+	if len(p.providers) > 0 {
+		router.Get("/v1/auth/oidc", p.OIDCLogin)
+		router.Get("/v1/auth/oidc/callback", p.OIDCCallback)
+	}
+	router.Get("/.well-known/jwks.json", p.JWKS)
 }
@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/cookie"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// responseCookies returns the cookies of a response by name.
+func responseCookies(cookies []*http.Cookie) map[string]*http.Cookie {
+	m := make(map[string]*http.Cookie)
+	for _, v := range cookies {
+		m[v.Name] = v
+	}
+	return m
+}
+
+func TestLoginCookie(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.Cookie.Enabled = true
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "password")
+	form.Add("cookie", "true")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	// The tokens are only in the cookies.
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, r.Body.Data.Token)
+	assert.Empty(t, r.Body.Data.RefreshToken)
+	assert.NotEmpty(t, r.Body.Data.CSRFToken)
+
+	cookies := responseCookies(w.Result().Cookies())
+	assert.True(t, cookies[cookie.AccessTokenName].HttpOnly)
+	assert.NotEmpty(t, cookies[cookie.RefreshTokenName].Value)
+	assert.Equal(t, r.Body.Data.CSRFToken, cookies[cookie.CSRFTokenName].Value)
+
+	// The refresh needs the CSRF token.
+	w = testrequest.SendFormCookies(t, core, w.Result().Cookies(), "", "POST", "/v1/auth/refresh", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	list := []*http.Cookie{cookies[cookie.RefreshTokenName], cookies[cookie.CSRFTokenName]}
+	w = testrequest.SendFormCookies(t, core, list, r.Body.Data.CSRFToken, "POST", "/v1/auth/refresh", nil)
+
+	rr := new(model.AuthRefreshResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &rr.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, rr.Body.Data.RefreshToken)
+	assert.NotEmpty(t, rr.Body.Data.CSRFToken)
+
+	// The refresh token was rotated.
+	refreshed := responseCookies(w.Result().Cookies())
+	assert.NotEqual(t, cookies[cookie.RefreshTokenName].Value, refreshed[cookie.RefreshTokenName].Value)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginCookieDisabled(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "password")
+	form.Add("cookie", "true")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "cookies are not enabled")
+
+	// The cookie is ignored and the refresh token is required.
+	w = testrequest.SendFormCookies(t, core, []*http.Cookie{
+		{Name: cookie.RefreshTokenName, Value: "token"},
+	}, "", "POST", "/v1/auth/refresh", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
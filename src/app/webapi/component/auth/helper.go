@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"app/webapi/internal/principal"
+	"app/webapi/internal/throttle"
+	"app/webapi/model"
+	"app/webapi/pkg/securegen"
+	"app/webapi/pkg/totp"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+)
+
+// recoveryCodeCount is the number of recovery codes given to a user.
+const recoveryCodeCount = 10
+
+// issueTokens returns a new access token and a new refresh token in the
+// specified refresh token family. The current roles of the user are embedded
+// in the access token. The refresh token family is recorded as a session of
+// the user with the client of the request.
+func (p *Endpoint) issueTokens(r *http.Request, userID string, familyID string) (string, string, error) {
+	roles, err := store.NewRole(p.DB, p.Q).NamesByUserID(userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Set the token ID so the session can be found from the token.
+	tokenID, err := securegen.UUID()
+	if err != nil {
+		return "", "", err
+	}
+
+	t, err := p.Token.Generate(webtoken.Claims{
+		ID:        tokenID,
+		Subject:   userID,
+		Roles:     roles,
+		SessionID: familyID,
+	}, p.Auth.AccessTokenDuration())
+	if err != nil {
+		return "", "", err
+	}
+
+	rt := store.NewRefreshToken(p.DB, p.Q)
+	refresh, err := rt.Create(userID, familyID, p.Auth.RefreshTokenDuration())
+	if err != nil {
+		return "", "", err
+	}
+
+	us := store.NewUserSession(p.DB, p.Q)
+	err = us.Save(familyID, userID, "", r.UserAgent(), throttle.ClientIP(r), p.Auth.RefreshTokenDuration())
+	if err != nil {
+		return "", "", err
+	}
+
+	return t, refresh, nil
+}
+
+// revokeSessions will revoke every session of a user with the refresh tokens
+// and the access tokens issued in them.
+func (p *Endpoint) revokeSessions(userID string) error {
+	us := store.NewUserSession(p.DB, p.Q)
+	sessions := us.NewGroup()
+	err := us.FindAllActiveByUser(sessions, userID)
+	if err != nil {
+		return err
+	}
+
+	// The access tokens of a session expire within the access token duration.
+	expiresAt := time.Now().Add(p.Auth.AccessTokenDuration())
+	for _, v := range *sessions {
+		if err = p.Revocation.Revoke(v.ID, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	// Revoke the refresh tokens so no new access tokens can be issued.
+	err = store.NewRefreshToken(p.DB, p.Q).RevokeUser(userID)
+	if err != nil {
+		return err
+	}
+
+	err = store.NewOAuthRefreshToken(p.DB, p.Q).RevokeUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return us.RevokeUser(userID)
+}
+
+// revokeSession will revoke one session with its refresh tokens and the
+// access tokens issued in it. The ID of the session is the refresh token
+// family.
+func (p *Endpoint) revokeSession(sessionID string) error {
+	err := p.Revocation.Revoke(sessionID, time.Now().Add(p.Auth.AccessTokenDuration()))
+	if err != nil {
+		return err
+	}
+
+	err = store.NewRefreshToken(p.DB, p.Q).RevokeFamily(sessionID)
+	if err != nil {
+		return err
+	}
+
+	err = store.NewOAuthRefreshToken(p.DB, p.Q).RevokeFamily(sessionID)
+	if err != nil {
+		return err
+	}
+
+	return store.NewUserSession(p.DB, p.Q).RevokeOne(sessionID)
+}
+
+// errCookieDisabled is returned when a client asks for the tokens in cookies
+// while cookies are not enabled.
+var errCookieDisabled = errors.New("cookies are not enabled")
+
+// useCookie returns true if the client asked for the tokens in cookies.
+func (p *Endpoint) useCookie(value string) (bool, error) {
+	if value != "true" {
+		return false, nil
+	} else if !p.Auth.Cookie.Enabled {
+		return false, errCookieDisabled
+	}
+	return true, nil
+}
+
+// completeLogin will write the tokens of a user whose credentials were
+// checked. If the user enabled two-factor authentication, a two-factor token
+// that can only be exchanged with a code is written instead.
+func (p *Endpoint) completeLogin(w http.ResponseWriter, r *http.Request, u *store.User, useCookie bool) (int, error) {
+	if u.StatusID != store.StatusActive {
+		return http.StatusForbidden, errLoginInactive
+	}
+
+	enabled, err := p.twoFactorEnabled(u.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if enabled {
+		resp := new(model.AuthLoginResponse)
+		resp.Body.Status = http.StatusText(http.StatusOK)
+
+		ut := store.NewUserToken(p.DB, p.Q)
+		resp.Body.Data.TwoFactorToken, err = ut.Create(u.ID, store.TokenTwoFactor, twoFactorDuration)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return p.Response.JSON(w, resp.Body)
+	}
+
+	return p.writeLogin(w, r, u.ID, useCookie)
+}
+
+// twoFactorEnabled returns true if the user must send a second factor to
+// log in.
+func (p *Endpoint) twoFactorEnabled(userID string) (bool, error) {
+	tf := store.NewUserTOTP(p.DB, p.Q)
+	exists, err := tf.FindOneByID(tf, userID)
+	if err != nil {
+		return false, err
+	}
+	return exists && tf.Enabled(), nil
+}
+
+// writeLogin will write the tokens of a new login. A browser client gets the
+// tokens in cookies and the CSRF token in the body instead.
+func (p *Endpoint) writeLogin(w http.ResponseWriter, r *http.Request, userID string, useCookie bool) (int, error) {
+	// Start a new refresh token family.
+	familyID, err := securegen.UUID()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Generate the tokens.
+	t, rt, err := p.issueTokens(r, userID, familyID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.AuthLoginResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.ExpiresIn = int(p.Auth.AccessTokenDuration().Seconds())
+
+	if useCookie {
+		resp.Body.Data.CSRFToken, err = p.Auth.Cookie.SetTokens(w, t, p.Auth.AccessTokenDuration(),
+			rt, p.Auth.RefreshTokenDuration())
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	} else {
+		resp.Body.Data.Token = t
+		resp.Body.Data.RefreshToken = rt
+	}
+
+	return p.Response.JSON(w, resp.Body)
+}
+
+// userCaller returns the caller if the caller authenticated with a token
+// issued to a user.
+func userCaller(r *http.Request) (*principal.Principal, int, error) {
+	caller, ok := principal.FromRequest(r)
+	if !ok {
+		return nil, http.StatusUnauthorized, errors.New("authorization token is missing")
+	} else if len(caller.KeyID) > 0 {
+		return nil, http.StatusBadRequest, errors.New("api keys cannot manage two-factor authentication")
+	} else if len(caller.ClientID) > 0 {
+		return nil, http.StatusBadRequest, errors.New("oauth clients cannot manage two-factor authentication")
+	}
+
+	return caller, http.StatusOK, nil
+}
+
+// checkSecondFactor returns true if the code is a valid TOTP code or an
+// unused recovery code of the user. A valid code is marked as used so it
+// cannot be used again.
+func (p *Endpoint) checkSecondFactor(tf *store.UserTOTP, code string) (bool, error) {
+	// Check the TOTP code first since it is the most common.
+	step, ok, err := totp.New().Match(tf.Secret, code)
+	if err != nil {
+		return false, err
+	} else if ok {
+		affected, err := tf.UseStep(tf.UserID, step)
+		return affected > 0, err
+	}
+
+	// Only check the recovery codes if the code looks like one because each
+	// hash is slow to compare.
+	code = normalizeRecoveryCode(code)
+	if len(code) != 10 {
+		return false, nil
+	}
+
+	rc := store.NewRecoveryCode(p.DB, p.Q)
+	group, err := rc.FindUnused(tf.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range *group {
+		if p.Password.MatchString(v.CodeHash, code) {
+			affected, err := rc.MarkUsed(v.ID)
+			return affected > 0, err
+		}
+	}
+
+	return false, nil
+}
+
+// newRecoveryCodes returns the recovery codes to show the user and the
+// hashes of the codes to store.
+func (p *Endpoint) newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b, err := securegen.Bytes(6)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(b))
+		hash, err := p.Password.HashString(code)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hash)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode returns the code without separators in lower case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.Replace(code, "-", "", -1)
+	code = strings.Replace(code, " ", "", -1)
+	return strings.ToLower(code)
+}
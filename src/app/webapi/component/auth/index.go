@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
 )
 
 // Index .
@@ -15,7 +16,7 @@ import (
 // Responses:
 //   200: AuthIndexResponse
 func (p *Endpoint) Index(w http.ResponseWriter, r *http.Request) (int, error) {
-	t, err := p.Token.Generate("1", 8*time.Hour)
+	t, err := p.Token.Generate(webtoken.Claims{Subject: "1"}, 8*time.Hour)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -12,6 +12,7 @@ import (
 	"app/webapi/internal/testrequest"
 	"app/webapi/internal/testutil"
 	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,7 +21,7 @@ func TestIndex(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
 	core, m := component.NewCoreMock(db)
 
-	m.Token.GenerateFunc = func(userID string, duration time.Duration) (string, error) {
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
 		b := []byte("0123456789ABCDEF0123456789ABCDEF")
 		enc := base64.StdEncoding.EncodeToString(b)
 		return enc, nil
@@ -43,7 +44,7 @@ func TestIndexError(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
 	core, m := component.NewCoreMock(db)
 
-	m.Token.GenerateFunc = func(userID string, duration time.Duration) (string, error) {
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
 		return "", errors.New("generate error")
 	}
 
@@ -59,3 +60,15 @@ func TestIndexError(t *testing.T) {
 
 	testutil.TeardownDatabase(unique)
 }
+
+func TestIndexDisabled(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.AnonymousToken = false
+
+	w := testrequest.SendForm(t, core, "GET", "/v1/auth", nil)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"app/webapi/model"
+)
+
+// Introspect .
+// swagger:route POST /v1/auth/introspect auth AuthIntrospect
+//
+// Determine if an access token is active and return its claims as described
+// in RFC 7662. A token that is invalid, expired, or revoked is not active.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: AuthIntrospectResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Introspect(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthIntrospect
+	type request struct {
+		// in: formData
+		// Required: true
+		Token string `json:"token" validate:"required"`
+		// TokenTypeHint is accepted but not needed because only access tokens
+		// can be introspected.
+		//
+		// in: formData
+		TokenTypeHint string `json:"token_type_hint"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	resp := new(model.AuthIntrospectResponse)
+
+	// A token that cannot be verified is not active.
+	claims, err := p.Token.Verify(req.Token)
+	if err != nil {
+		return p.Response.JSON(w, resp.Body)
+	}
+
+	// Determine if the token was revoked before it expired.
+	revoked, err := p.Revocation.IsRevoked(claims.ID)
+	if err == nil && !revoked && len(claims.SessionID) > 0 {
+		revoked, err = p.Revocation.IsRevoked(claims.SessionID)
+	}
+	if err == nil && !revoked && len(claims.ClientID) > 0 {
+		revoked, err = p.Revocation.IsRevoked(claims.ClientID)
+	}
+	if err == nil && !revoked {
+		// A token with a session is also revoked with the session, so one
+		// issued in the same second as the revocation of the user is from a
+		// new login.
+		issuedAt := claims.IssuedAt
+		if len(claims.SessionID) > 0 {
+			issuedAt = issuedAt.Add(time.Second)
+		}
+		revoked, err = p.Revocation.IsUserRevoked(claims.Subject, issuedAt)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if revoked {
+		return p.Response.JSON(w, resp.Body)
+	}
+
+	resp.Body.Active = true
+	resp.Body.Scope = strings.Join(claims.Scopes, " ")
+	resp.Body.ClientID = claims.ClientID
+	resp.Body.TokenType = "Bearer"
+	resp.Body.Exp = claims.ExpiresAt.Unix()
+	resp.Body.Iat = claims.IssuedAt.Unix()
+	resp.Body.Nbf = claims.NotBefore.Unix()
+	resp.Body.Sub = claims.Subject
+	resp.Body.Aud = claims.Audience
+	resp.Body.Iss = claims.Issuer
+	resp.Body.Jti = claims.ID
+	return p.Response.JSON(w, resp.Body)
+}
@@ -0,0 +1,91 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntrospect(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	now := time.Now()
+	m.Token.VerifyFunc = func(s string) (*webtoken.Claims, error) {
+		if s != "token" {
+			return nil, webtoken.ErrMalformed
+		}
+		return &webtoken.Claims{
+			ID:        "jti",
+			Subject:   "client",
+			ClientID:  "client",
+			Scopes:    []string{"user:read", "user:update"},
+			IssuedAt:  now,
+			NotBefore: now,
+			ExpiresAt: now.Add(time.Hour),
+		}, nil
+	}
+
+	// The caller is a client that is trusted to introspect tokens.
+	caller := &principal.Principal{
+		ClientID: "resource",
+		Scopes:   []string{component.PermissionIntrospect},
+	}
+
+	form := url.Values{}
+	form.Add("token", "token")
+	form.Add("token_type_hint", "access_token")
+	w := testrequest.SendFormAs(t, core, caller, "POST", "/v1/auth/introspect", form)
+
+	r := new(model.AuthIntrospectResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, r.Body.Active)
+	assert.Equal(t, "user:read user:update", r.Body.Scope)
+	assert.Equal(t, "client", r.Body.ClientID)
+	assert.Equal(t, "jti", r.Body.Jti)
+	assert.Equal(t, now.Add(time.Hour).Unix(), r.Body.Exp)
+
+	// A revoked token is not active.
+	assert.Nil(t, core.Revocation.Revoke("jti", now.Add(time.Hour)))
+	w = testrequest.SendFormAs(t, core, caller, "POST", "/v1/auth/introspect", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"active":false}`, w.Body.String())
+
+	// An invalid token is not active.
+	form.Set("token", "invalid")
+	w = testrequest.SendFormAs(t, core, caller, "POST", "/v1/auth/introspect", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"active":false}`, w.Body.String())
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestIntrospectForbidden(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	form := url.Values{}
+	form.Add("token", "token")
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "user",
+		Roles:  []string{principal.RoleUser},
+	}, "POST", "/v1/auth/introspect", form)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"net/http"
+
+	"app/webapi/model"
+)
+
+// JWKS .
+// swagger:route GET /.well-known/jwks.json auth AuthJWKS
+//
+// Get the public keys that verify the access tokens.
+//
+// Responses:
+//   200: AuthJWKSResponse
+func (p *Endpoint) JWKS(w http.ResponseWriter, r *http.Request) (int, error) {
+	arr := make([]model.AuthJWKSResponseKey, 0)
+	for _, k := range p.Token.JWKS() {
+		arr = append(arr, model.AuthJWKSResponseKey{
+			Kty: k.Kty,
+			Kid: k.Kid,
+			Use: k.Use,
+			Alg: k.Alg,
+			N:   k.N,
+			E:   k.E,
+			Crv: k.Crv,
+			X:   k.X,
+			Y:   k.Y,
+		})
+	}
+
+	resp := new(model.AuthJWKSResponse)
+	resp.Body.Keys = arr
+	return p.Response.JSON(w, resp.Body)
+}
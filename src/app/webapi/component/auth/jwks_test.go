@@ -0,0 +1,41 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKS(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	m.Token.JWKSFunc = func() []webtoken.JSONWebKey {
+		return []webtoken.JSONWebKey{
+			{Kty: "OKP", Kid: "2019-01", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "abc"},
+		}
+	}
+
+	w := testrequest.SendForm(t, core, "GET", "/.well-known/jwks.json", nil)
+
+	r := new(model.AuthJWKSResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(r.Body.Keys))
+	assert.Equal(t, "2019-01", r.Body.Keys[0].Kid)
+	assert.Equal(t, "EdDSA", r.Body.Keys[0].Alg)
+	assert.Equal(t, "abc", r.Body.Keys[0].X)
+	assert.NotContains(t, w.Body.String(), `"n"`)
+
+	testutil.TeardownDatabase(unique)
+}
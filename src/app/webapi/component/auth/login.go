@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"app/webapi/internal/throttle"
+	"app/webapi/store"
+)
+
+// twoFactorDuration is the lifetime of the token that is exchanged with a
+// code when two-factor authentication is enabled.
+const twoFactorDuration = 5 * time.Minute
+
+// errLoginFailed is returned for every failed login so the response does not
+// reveal whether the account exists.
+var errLoginFailed = errors.New("email or password is incorrect")
+
+// errLoginInactive is returned when the credentials are correct, but the user
+// has not verified their email address.
+var errLoginInactive = errors.New("user is inactive, verify the email address to activate it")
+
+// Login .
+// swagger:route POST /v1/auth/login auth AuthLogin
+//
+// Exchange an email and password for an access token. If the user enabled
+// two-factor authentication, a two-factor token is returned instead. After
+// too many failed logins, the next logins are delayed and then the account is
+// locked for a while.
+//
+// Responses:
+//   200: AuthLoginResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   423: LockedResponse
+//   429: TooManyRequestsResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Login(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthLogin
+	type request struct {
+		// in: formData
+		// Required: true
+		Email string `json:"email" validate:"required,email"`
+		// in: formData
+		// Required: true
+		Password string `json:"password" validate:"required"`
+		// Cookie set to true writes the tokens to cookies for a browser
+		// client.
+		//
+		// in: formData
+		Cookie string `json:"cookie" validate:"omitempty,oneof=true false"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	useCookie, err := p.useCookie(req.Cookie)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Reject the login early while the account or IP address is blocked so a
+	// guess does not cost a password hash.
+	ip := throttle.ClientIP(r)
+	lt := throttle.New(p.DB, p.Q, p.Auth)
+	if status, err := lt.Blocked(w, req.Email, ip); err != nil {
+		return status, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Get the item by email.
+	exists, err := u.FindOneByField(u, "email", req.Email)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		// Hash the password anyway so the response time does not reveal
+		// whether the account exists.
+		p.Password.HashString(req.Password)
+		if err = lt.Failed("", req.Email, ip); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusUnauthorized, errLoginFailed
+	}
+
+	// Ensure the password matches.
+	if !p.Password.MatchString(u.Password, req.Password) {
+		if err = lt.Failed(u.ID, req.Email, ip); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusUnauthorized, errLoginFailed
+	}
+
+	// Upgrade the stored hash while the password is available if it was
+	// created with weaker settings.
+	if p.Password.NeedsRehash(u.Password) {
+		hash, err := p.Password.HashString(req.Password)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if err = u.UpdatePassword(u.ID, hash); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	// Forget the failed logins of the account. With two-factor
+	// authentication they are only forgotten once the second factor is
+	// verified so a new login does not allow more guesses of the code.
+	enabled, err := p.twoFactorEnabled(u.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !enabled {
+		if err = lt.Succeeded(req.Email); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	return p.completeLogin(w, r, u, useCookie)
+}
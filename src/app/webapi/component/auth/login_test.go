@@ -0,0 +1,338 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLogin(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	role := store.NewRole(core.DB, core.Q)
+	assert.Nil(t, role.Assign(ID, "user"))
+	assert.Nil(t, role.Assign(ID, "admin"))
+
+	tokenUserID := ""
+	tokenSessionID := ""
+	var tokenRoles []string
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
+		tokenUserID = claims.Subject
+		tokenSessionID = claims.SessionID
+		tokenRoles = claims.Roles
+		return "token", nil
+	}
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "OK", r.Body.Status)
+	assert.Equal(t, "token", r.Body.Data.Token)
+	assert.NotEmpty(t, r.Body.Data.RefreshToken)
+	assert.Equal(t, 900, r.Body.Data.ExpiresIn)
+	assert.Equal(t, ID, tokenUserID)
+	assert.Equal(t, []string{"admin", "user"}, tokenRoles)
+
+	// The login is recorded as a session of the user that lasts as long as
+	// the refresh token.
+	us := store.NewUserSession(core.DB, core.Q)
+	found, err := us.FindOneActiveByUser(tokenSessionID, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "192.0.2.1", us.IPAddress)
+	assert.True(t, us.ExpiresAt.After(time.Now().Add(time.Hour)))
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginInactive(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	_, err = u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "user is inactive")
+
+	// The status is not revealed without the correct password.
+	form.Set("password", "wrongpassword")
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginFailed(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	_, err = u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+
+	bodies := make([]string, 0)
+
+	for _, v := range []string{
+		"jsmith@example.com wrongpassword",
+		"nobody@example.com password",
+	} {
+		arr := strings.Split(v, " ")
+
+		form := url.Values{}
+		form.Add("email", arr[0])
+		form.Add("password", arr[1])
+
+		w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+		r := new(model.UnauthorizedResponse)
+		err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Unauthorized", r.Body.Status)
+		bodies = append(bodies, w.Body.String())
+	}
+
+	// The responses must not reveal whether the email exists.
+	assert.Equal(t, bodies[0], bodies[1])
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginValidation(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	form := url.Values{}
+	form.Add("email", "jsmith")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginLockout(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginBackoffAfter = 10
+	core.Auth.LoginLockoutAfter = 3
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "wrongpassword")
+
+	for i := 0; i < 3; i++ {
+		w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// The correct password is rejected while the account is locked.
+	form.Set("password", "password")
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+	r := new(model.LockedResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusLocked, w.Code)
+	assert.Contains(t, r.Body.Message, "account is locked")
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.Nil(t, err)
+	assert.True(t, retry > 0 && retry <= 15*60)
+
+	// The lockout is recorded.
+	le := store.NewLockoutEvent(core.DB, core.Q)
+	group := le.NewGroup()
+	assert.Nil(t, le.FindAllByEmail(group, "jsmith@example.com"))
+	assert.Equal(t, 1, len(*group))
+	assert.Equal(t, store.LockoutLocked, (*group)[0].Event)
+	assert.Equal(t, ID, *(*group)[0].UserID)
+	assert.Equal(t, 3, (*group)[0].Failures)
+
+	// An email that does not exist is locked the same way.
+	form.Set("email", "nobody@example.com")
+	form.Set("password", "wrongpassword")
+	for i := 0; i < 3; i++ {
+		w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusLocked, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginBackoff(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginBackoffAfter = 1
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	// Count earlier failures so the next one doubles the delay a few times.
+	lt := store.NewLoginThrottle(core.DB, core.Q)
+	for i := 0; i < 4; i++ {
+		_, err = lt.Fail(store.ThrottleAccount, "jsmith@example.com", time.Hour)
+		assert.Nil(t, err)
+	}
+
+	form := url.Values{}
+	form.Add("email", "JSmith@example.com")
+	form.Add("password", "wrongpassword")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The next login is delayed, even with the correct password.
+	form.Set("password", "password")
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+	r := new(model.TooManyRequestsResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, r.Body.Message, "too many failed logins")
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.Nil(t, err)
+	assert.True(t, retry > 0 && retry <= 16)
+
+	// A successful login forgets the failures.
+	assert.Nil(t, lt.Block(store.ThrottleAccount, "jsmith@example.com", 0))
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	exists, err := lt.Find(store.ThrottleAccount, "jsmith@example.com")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginIPBackoff(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginIPBackoffAfter = 1
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	// Count earlier failures from the address of the test requests.
+	lt := store.NewLoginThrottle(core.DB, core.Q)
+	for i := 0; i < 4; i++ {
+		_, err = lt.Fail(store.ThrottleIP, "192.0.2.1", time.Hour)
+		assert.Nil(t, err)
+	}
+
+	form := url.Values{}
+	form.Add("email", "nobody@example.com")
+	form.Add("password", "password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Every account is delayed from the same address.
+	form.Set("email", "jsmith@example.com")
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLoginRehash(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	// Create a hash with a lower cost than the current setting.
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.Nil(t, err)
+	assert.True(t, core.Password.NeedsRehash(string(hash)))
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", string(hash))
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The hash is replaced with one that uses the current cost.
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.NotEqual(t, string(hash), u.Password)
+	assert.False(t, core.Password.NeedsRehash(u.Password))
+	assert.True(t, core.Password.MatchString(u.Password, "password"))
+
+	testutil.TeardownDatabase(unique)
+}
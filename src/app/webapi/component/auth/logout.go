@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/internal/cookie"
+	"app/webapi/internal/principal"
+	"app/webapi/store"
+)
+
+// Logout .
+// swagger:route POST /v1/auth/logout auth AuthLogout
+//
+// Revoke the access token and the session it was issued in with its refresh
+// tokens. A refresh token that is provided is revoked as well. A browser
+// client that logged in with cookies has the cookies removed.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Logout(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthLogout
+	type request struct {
+		// in: formData
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Get the caller.
+	caller, ok := principal.FromRequest(r)
+	if !ok {
+		return http.StatusUnauthorized, errors.New("authorization token is missing")
+	} else if len(caller.KeyID) > 0 {
+		return http.StatusBadRequest, errors.New("api keys must be revoked instead")
+	}
+
+	// Revoke the access token until it expires.
+	err := p.Revocation.Revoke(caller.TokenID, caller.ExpiresAt)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Revoke the session with the refresh tokens and the other access tokens
+	// issued in it.
+	if len(caller.SessionID) > 0 {
+		if err = p.revokeSession(caller.SessionID); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	// A browser client sends the refresh token in a cookie. The middleware
+	// already checked the CSRF token.
+	fromCookie := len(cookie.AccessToken(r)) > 0 && p.Auth.Cookie.Enabled
+	if len(req.RefreshToken) == 0 && fromCookie {
+		req.RefreshToken = cookie.RefreshToken(r)
+	}
+
+	// Revoke the refresh token family if it belongs to the same user.
+	if len(req.RefreshToken) > 0 {
+		rt := store.NewRefreshToken(p.DB, p.Q)
+		exists, err := rt.FindOneByToken(req.RefreshToken)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if exists && rt.UserID == caller.UserID {
+			err = rt.RevokeFamily(rt.FamilyID)
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+	}
+
+	if fromCookie {
+		p.Auth.Cookie.Clear(w)
+	}
+
+	return p.Response.OK(w, "logged out")
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogout(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	token, err := rt.Create(ID, "family", time.Hour)
+	assert.Nil(t, err)
+
+	// The access token was issued in a session on another device.
+	us := store.NewUserSession(core.DB, core.Q)
+	assert.Nil(t, us.Save("session", ID, "", "", "", time.Hour))
+	sessionToken, err := rt.Create(ID, "session", time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("refresh_token", token)
+
+	p := &principal.Principal{
+		UserID:    ID,
+		TokenID:   "jti",
+		SessionID: "session",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/logout", form)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "logged out", r.Body.Message)
+
+	// The access token is revoked.
+	revoked, err := core.Revocation.IsRevoked("jti")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// The refresh token is revoked.
+	found, err := rt.FindOneByToken(token)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	// The session is revoked with its refresh tokens.
+	revoked, err = core.Revocation.IsRevoked("session")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	found, err = rt.FindOneByToken(sessionToken)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	found, err = us.FindOneActiveByUser("session", ID)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLogoutMissingToken(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/logout", nil)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "authorization token is missing")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestLogoutAPIKey(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	p := &principal.Principal{
+		UserID: "1",
+		KeyID:  "1",
+	}
+
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/logout", nil)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "api keys must be revoked instead")
+
+	testutil.TeardownDatabase(unique)
+}
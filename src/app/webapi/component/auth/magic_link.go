@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"app/webapi/store"
+)
+
+// magicLinkWindow is the period in which the login links sent to an address
+// are counted.
+const magicLinkWindow = time.Hour
+
+// errMagicLinkLimit is returned when too many login links were sent to an
+// address.
+var errMagicLinkLimit = errors.New("too many login links were sent to the email, try again later")
+
+// MagicLink .
+// swagger:route POST /v1/auth/magic auth AuthMagicLink
+//
+// Send a single use login link to the email if it belongs to a user. Only a
+// few links are sent to an address per hour.
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   429: TooManyRequestsResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) MagicLink(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthMagicLink
+	type request struct {
+		// in: formData
+		// Required: true
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Limit the links sent to the address. Every address is counted, not just
+	// the addresses of users, so the limit does not reveal whether the
+	// account exists.
+	lt := store.NewLoginThrottle(p.DB, p.Q)
+	exists, err := lt.Find(store.ThrottleMagicLink, req.Email)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if exists && lt.RetryAfter > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(lt.RetryAfter))
+		return http.StatusTooManyRequests, errMagicLinkLimit
+	}
+
+	count, err := lt.Fail(store.ThrottleMagicLink, req.Email, magicLinkWindow)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if count >= p.Auth.MagicLinkLimit() {
+		err = lt.Block(store.ThrottleMagicLink, req.Email, magicLinkWindow)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Get the item by email.
+	exists, err = u.FindOneByField(u, "email", req.Email)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Only send the email if the user exists, but always return the same
+	// response so it does not reveal whether the account exists.
+	if exists {
+		duration := p.Auth.MagicLinkDuration()
+		ut := store.NewUserToken(p.DB, p.Q)
+		token, err := ut.Create(u.ID, store.TokenMagicLink, duration)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		body := fmt.Sprintf("A login link was requested for your account. "+
+			"Use the following link within %v minutes to log in:\n\n"+
+			"%v%v\n\n"+
+			"The link can only be used once. If you did not request it, you can ignore this email.\n",
+			int(duration.Minutes()), p.Auth.MagicLinkURL, token)
+
+		err = p.Mail.Send(u.Email, "Your login link", body)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	return p.Response.OK(w, "if the email belongs to a user, a login link was sent")
+}
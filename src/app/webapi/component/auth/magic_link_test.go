@@ -0,0 +1,140 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLink(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	mailTo := ""
+	mailBody := ""
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		mailTo = to
+		mailBody = body
+		return nil
+	}
+
+	tokenUserID := ""
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
+		tokenUserID = claims.Subject
+		return "token", nil
+	}
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/magic", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jsmith@example.com", mailTo)
+
+	// The email contains a token that can be exchanged for an access token.
+	token := regexp.MustCompile(`[A-Za-z0-9_-]{43}`).FindString(mailBody)
+	form = url.Values{}
+	form.Add("token", token)
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/magic/verify", form)
+
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "token", r.Body.Data.Token)
+	assert.NotEmpty(t, r.Body.Data.RefreshToken)
+	assert.Equal(t, ID, tokenUserID)
+
+	// The link also activates the user.
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+
+	// The link can only be used once.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/magic/verify", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "login link is invalid")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestMagicLinkNotFound(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	sent := false
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		sent = true
+		return nil
+	}
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+
+	// The response is the same whether or not the user exists.
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/magic", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "a login link was sent")
+	assert.False(t, sent)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestMagicLinkLimit(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+	core.Auth.MagicLinkPerHour = 2
+
+	u := store.NewUser(core.DB, core.Q)
+	_, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	sent := 0
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		sent++
+		return nil
+	}
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+
+	for i := 0; i < 2; i++ {
+		w := testrequest.SendForm(t, core, "POST", "/v1/auth/magic", form)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// The next link is rejected for an hour.
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/magic", form)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, 2, sent)
+
+	// An address without a user is limited the same way.
+	form.Set("email", "other@example.com")
+	for i := 0; i < 2; i++ {
+		w = testrequest.SendForm(t, core, "POST", "/v1/auth/magic", form)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/magic", form)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
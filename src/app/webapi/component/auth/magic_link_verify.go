@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// errMagicLinkInvalid is returned for every login link that cannot be used.
+var errMagicLinkInvalid = errors.New("login link is invalid")
+
+// MagicLinkVerify .
+// swagger:route POST /v1/auth/magic/verify auth AuthMagicLinkVerify
+//
+// Exchange the token from a login link for an access token. If the user
+// enabled two-factor authentication, a two-factor token is returned instead.
+//
+// Responses:
+//   200: AuthLoginResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) MagicLinkVerify(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthMagicLinkVerify
+	type request struct {
+		// in: formData
+		// Required: true
+		Token string `json:"token" validate:"required"`
+		// Cookie set to true writes the tokens to cookies for a browser
+		// client.
+		//
+		// in: formData
+		Cookie string `json:"cookie" validate:"omitempty,oneof=true false"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	useCookie, err := p.useCookie(req.Cookie)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	ut := store.NewUserToken(p.DB, p.Q)
+
+	// Get the item by token.
+	exists, err := ut.FindOneByToken(req.Token, store.TokenMagicLink)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusUnauthorized, errMagicLinkInvalid
+	}
+
+	// Mark the token as used. If another request used the token first, the
+	// token is no longer valid.
+	affected, err := ut.MarkUsed(ut.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		return http.StatusUnauthorized, errMagicLinkInvalid
+	}
+
+	// Any other links were sent before this login.
+	err = ut.MarkAllUsed(ut.UserID, store.TokenMagicLink)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	u := store.NewUser(p.DB, p.Q)
+	exists, err = u.FindOneByID(u, ut.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusUnauthorized, errMagicLinkInvalid
+	}
+
+	// The link proves the user owns the email address so it also activates
+	// the user.
+	if u.StatusID != store.StatusActive {
+		err = u.UpdateStatus(u.ID, store.StatusActive)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		u.StatusID = store.StatusActive
+	}
+
+	return p.completeLogin(w, r, u, useCookie)
+}
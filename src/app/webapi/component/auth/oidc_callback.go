@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"app/webapi/internal/cookie"
+	"app/webapi/internal/principal"
+	"app/webapi/pkg/oidc"
+	"app/webapi/pkg/securegen"
+	"app/webapi/store"
+)
+
+// errOIDCState is returned for every login that cannot be found.
+var errOIDCState = errors.New("login is invalid or expired, start the login again")
+
+// OIDCCallback .
+// swagger:route GET /v1/auth/oidc/callback auth AuthOIDCCallback
+//
+// Finish a login with an OpenID Connect provider. The provider redirects the
+// user here with a code that is exchanged for an ID token. A user is created
+// on the first login and is linked to the account at the provider. The login
+// must be finished in the browser that started it.
+//
+// Responses:
+//   200: AuthLoginResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) OIDCCallback(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthOIDCCallback
+	type request struct {
+		// in: query
+		// Required: true
+		State string `json:"state" validate:"required"`
+		// in: query
+		Code string `json:"code"`
+		// in: query
+		Error string `json:"error"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// The state must match the cookie of the browser that started the login.
+	if !cookie.ValidOIDCState(r, req.State) {
+		return http.StatusBadRequest, errOIDCState
+	}
+	p.Auth.Cookie.ClearOIDCState(w)
+
+	// Create the DB store.
+	ol := store.NewOIDCLogin(p.DB, p.Q)
+
+	// Each login can only be finished once.
+	exists, err := ol.FindOneByState(req.State)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errOIDCState
+	}
+
+	affected, err := ol.MarkUsed(ol.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		return http.StatusBadRequest, errOIDCState
+	}
+
+	if len(req.Error) > 0 {
+		return http.StatusUnauthorized, errors.New("provider rejected the login: " + req.Error)
+	} else if len(req.Code) == 0 {
+		return http.StatusBadRequest, errors.New("code is required")
+	}
+
+	provider, ok := p.providers[ol.Provider]
+	if !ok {
+		return http.StatusBadRequest, errors.New("provider does not exist")
+	}
+
+	raw, err := provider.Exchange(req.Code, ol.CodeVerifier)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	id, err := provider.Verify(raw, ol.Nonce)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	u, status, err := p.identityUser(provider, id)
+	if err != nil {
+		return status, err
+	}
+
+	// The callback is opened by the browser so it gets the tokens in cookies
+	// if they are enabled.
+	return p.completeLogin(w, r, u, p.Auth.Cookie.Enabled)
+}
+
+// identityUser returns the user that is linked to the account at the
+// provider. On the first login, the account is linked to the user with the
+// same email if the provider allows it or a new user is created.
+func (p *Endpoint) identityUser(provider *oidc.Provider, id *oidc.IDToken) (*store.User, int, error) {
+	u := store.NewUser(p.DB, p.Q)
+	ui := store.NewUserIdentity(p.DB, p.Q)
+
+	exists, err := ui.FindOneBySubject(id.Issuer, id.Subject)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	} else if exists {
+		exists, err = u.FindOneByID(u, ui.UserID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		} else if !exists {
+			return nil, http.StatusUnauthorized, errors.New("user does not exist")
+		}
+		return u, http.StatusOK, nil
+	}
+
+	if len(id.Email) == 0 {
+		return nil, http.StatusBadRequest, errors.New("provider did not share the email address")
+	}
+
+	exists, err = u.FindOneByField(u, "email", id.Email)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	} else if exists {
+		// Only link the account if the provider proved the user owns the
+		// address, otherwise anyone could take over the user.
+		if !provider.LinkByEmail || !id.EmailVerified {
+			return nil, http.StatusBadRequest, errors.New("user already exists, log in with the password")
+		}
+	} else {
+		// A deleted user keeps the email until it is purged.
+		exists, _, err = p.Q.WithDeleted().ExistsByField(u, "email", id.Email)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		} else if exists {
+			return nil, http.StatusForbidden, errors.New("user is deleted, ask an admin to restore it")
+		}
+
+		if u, err = p.createIdentityUser(id); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+
+	// The provider verified the address.
+	if id.EmailVerified && u.StatusID != store.StatusActive {
+		if err = u.UpdateStatus(u.ID, store.StatusActive); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		u.StatusID = store.StatusActive
+	}
+
+	if _, err = ui.Create(u.ID, id.Issuer, id.Subject, id.Email); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return u, http.StatusOK, nil
+}
+
+// createIdentityUser returns a new user from the ID token. The user has a
+// random password so they can only log in with the provider until they reset
+// it.
+func (p *Endpoint) createIdentityUser(id *oidc.IDToken) (*store.User, error) {
+	firstName, lastName := id.GivenName, id.FamilyName
+	if len(firstName) == 0 && len(lastName) == 0 {
+		names := strings.Fields(id.Name)
+		if len(names) > 0 {
+			firstName = names[0]
+			lastName = strings.Join(names[1:], " ")
+		}
+	}
+	if len(firstName) == 0 {
+		firstName = strings.Split(id.Email, "@")[0]
+	}
+
+	b, err := securegen.Bytes(32)
+	if err != nil {
+		return nil, err
+	}
+	password, err := p.Password.HashString(base64.RawURLEncoding.EncodeToString(b))
+	if err != nil {
+		return nil, err
+	}
+
+	u := store.NewUser(p.DB, p.Q)
+	ID, err := u.Create(firstName, lastName, id.Email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Give the user the default role.
+	role := store.NewRole(p.DB, p.Q)
+	if err = role.Assign(ID, principal.RoleUser); err != nil {
+		return nil, err
+	}
+
+	if _, err = u.FindOneByID(u, ID); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
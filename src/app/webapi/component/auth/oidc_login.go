@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"app/webapi/store"
+)
+
+// oidcLoginDuration is the time a user has to log in at the provider.
+const oidcLoginDuration = 10 * time.Minute
+
+// OIDCLogin .
+// swagger:route GET /v1/auth/oidc auth AuthOIDCLogin
+//
+// Start a login with an OpenID Connect provider by redirecting the user to
+// the provider. A cookie ties the login to the browser that started it.
+//
+// Responses:
+//   302: FoundResponse
+//   400: BadRequestResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) OIDCLogin(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthOIDCLogin
+	type request struct {
+		// in: query
+		// Required: true
+		Provider string `json:"provider" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	provider, ok := p.providers[req.Provider]
+	if !ok {
+		return http.StatusBadRequest, errors.New("provider does not exist")
+	}
+
+	// Store the state, nonce, and PKCE verifier until the user returns.
+	ol := store.NewOIDCLogin(p.DB, p.Q)
+	state, err := ol.Create(req.Provider, oidcLoginDuration)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	sum := sha256.Sum256([]byte(ol.CodeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	location, err := provider.AuthCodeURL(state, ol.Nonce, challenge)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Only finish the login in this browser so a callback URL of another
+	// login cannot sign the user in to another account.
+	p.Auth.Cookie.SetOIDCState(w, state, oidcLoginDuration)
+
+	http.Redirect(w, r, location, http.StatusFound)
+	return http.StatusFound, nil
+}
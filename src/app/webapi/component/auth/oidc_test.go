@@ -0,0 +1,228 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/oidc"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// oidcLogin starts a login with the fake provider and returns the query of
+// the callback with the cookies of the browser that started the login.
+func oidcLogin(t *testing.T, core component.Core, fake *testutil.OIDCProvider) (string, []*http.Cookie) {
+	w := testrequest.SendForm(t, core, "GET", "/v1/auth/oidc?provider=company", nil)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	callback, err := fake.Login(w.Header().Get("Location"))
+	assert.Nil(t, err)
+
+	return callback.RawQuery, w.Result().Cookies()
+}
+
+func oidcConfig(fake *testutil.OIDCProvider, linkByEmail bool) []oidc.Config {
+	return []oidc.Config{
+		{
+			Name:         "company",
+			Issuer:       fake.Issuer(),
+			ClientID:     fake.ClientID,
+			ClientSecret: fake.ClientSecret,
+			RedirectURL:  "http://localhost:8080/v1/auth/oidc/callback",
+			LinkByEmail:  linkByEmail,
+		},
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+	core.Auth.OIDC = oidcConfig(fake, false)
+
+	tokenUserID := ""
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
+		tokenUserID = claims.Subject
+		return "token", nil
+	}
+
+	query, cookies := oidcLogin(t, core, fake)
+	w := testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+
+	r := new(model.AuthLoginResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "token", r.Body.Data.Token)
+	assert.NotEmpty(t, r.Body.Data.RefreshToken)
+
+	// The user is created on the first login and is active.
+	u := store.NewUser(core.DB, core.Q)
+	found, err := u.FindOneByField(u, "email", "jsmith@example.com")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, u.ID, tokenUserID)
+	assert.Equal(t, "John", u.FirstName)
+	assert.Equal(t, "Smith", u.LastName)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+
+	ui := store.NewUserIdentity(core.DB, core.Q)
+	found, err = ui.FindOneBySubject(fake.Issuer(), fake.Subject)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, u.ID, ui.UserID)
+
+	// The callback can only be used once.
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The next login is linked by the subject even if the email changed.
+	fake.Email = "john.smith@example.com"
+	tokenUserID = ""
+	query, cookies = oidcLogin(t, core, fake)
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, u.ID, tokenUserID)
+
+	// An unknown provider.
+	w = testrequest.SendForm(t, core, "GET", "/v1/auth/oidc?provider=other", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestOIDCLoginExistingUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+	core.Auth.OIDC = oidcConfig(fake, false)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	tokenUserID := ""
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
+		tokenUserID = claims.Subject
+		return "token", nil
+	}
+
+	// The user is not linked by email unless the provider is trusted.
+	query, cookies := oidcLogin(t, core, fake)
+	w := testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The email must be verified by the provider.
+	core.Auth.OIDC = oidcConfig(fake, true)
+	fake.EmailVerified = false
+	query, cookies = oidcLogin(t, core, fake)
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The inactive user is linked and activated.
+	fake.EmailVerified = true
+	query, cookies = oidcLogin(t, core, fake)
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ID, tokenUserID)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestOIDCLoginDeletedUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+	core.Auth.OIDC = oidcConfig(fake, true)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	_, err = u.DeleteOneByID(u, ID)
+	assert.Nil(t, err)
+
+	// The email of a deleted user cannot be used by a new user.
+	query, cookies := oidcLogin(t, core, fake)
+	w := testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+
+	r := new(model.ForbiddenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "user is deleted, ask an admin to restore it", r.Body.Message)
+
+	ui := store.NewUserIdentity(core.DB, core.Q)
+	found, err := ui.FindOneBySubject(fake.Issuer(), fake.Subject)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestOIDCCallbackInvalid(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+	core.Auth.OIDC = oidcConfig(fake, false)
+
+	// An unknown state.
+	w := testrequest.SendForm(t, core, "GET", "/v1/auth/oidc/callback?state=unknown&code=code", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A callback URL of a login that was started in another browser.
+	query, cookies := oidcLogin(t, core, fake)
+	w = testrequest.SendForm(t, core, "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "login is invalid or expired")
+
+	_, other := oidcLogin(t, core, fake)
+	w = testrequest.SendFormCookies(t, core, other, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The login can still be finished in the browser that started it.
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+query, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The user cancelled the login at the provider.
+	query, cookies = oidcLogin(t, core, fake)
+	v, err := url.ParseQuery(query)
+	assert.Nil(t, err)
+	v.Del("code")
+	v.Set("error", "access_denied")
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+v.Encode(), nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// A code that the provider did not issue.
+	query, cookies = oidcLogin(t, core, fake)
+	v, err = url.ParseQuery(query)
+	assert.Nil(t, err)
+	v.Set("code", "other")
+	w = testrequest.SendFormCookies(t, core, cookies, "", "GET", "/v1/auth/oidc/callback?"+v.Encode(), nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
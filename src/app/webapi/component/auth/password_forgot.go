@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// PasswordForgot .
+// swagger:route POST /v1/auth/password/forgot auth AuthPasswordForgot
+//
+// Send a password reset token to the email if it belongs to a user.
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) PasswordForgot(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthPasswordForgot
+	type request struct {
+		// in: formData
+		// Required: true
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Get the item by email.
+	exists, err := u.FindOneByField(u, "email", req.Email)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Only send the email if the user exists, but always return the same
+	// response so it does not reveal whether the account exists.
+	if exists {
+		duration := p.Auth.PasswordResetDuration()
+		ut := store.NewUserToken(p.DB, p.Q)
+		token, err := ut.Create(u.ID, store.TokenPasswordReset, duration)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		body := fmt.Sprintf("A password reset was requested for your account. "+
+			"Use the following token within %v minutes to choose a new password:\n\n"+
+			"%v%v\n\n"+
+			"If you did not request a password reset, you can ignore this email.\n",
+			int(duration.Minutes()), p.Auth.PasswordResetURL, token)
+
+		err = p.Mail.Send(u.Email, "Reset your password", body)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	return p.Response.OK(w, "if the email belongs to a user, a password reset email was sent")
+}
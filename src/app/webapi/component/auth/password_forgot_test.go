@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordForgot(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	mailTo := ""
+	mailBody := ""
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		mailTo = to
+		mailBody = body
+		return nil
+	}
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/password/forgot", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jsmith@example.com", mailTo)
+
+	// The email contains a token that can reset the password.
+	token := regexp.MustCompile(`[A-Za-z0-9_-]{43}`).FindString(mailBody)
+	ut := store.NewUserToken(core.DB, core.Q)
+	found, err := ut.FindOneByToken(token, store.TokenPasswordReset)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, ID, ut.UserID)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPasswordForgotNotFound(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	sent := false
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		sent = true
+		return nil
+	}
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+
+	// The response is the same whether or not the user exists.
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/password/forgot", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "password reset email was sent")
+	assert.False(t, sent)
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"app/webapi/store"
+)
+
+// errResetInvalid is returned for every password reset token that cannot be
+// used.
+var errResetInvalid = errors.New("password reset token is invalid")
+
+// PasswordReset .
+// swagger:route POST /v1/auth/password/reset auth AuthPasswordReset
+//
+// Exchange a password reset token for a new password. Every token of the user
+// is revoked.
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) PasswordReset(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthPasswordReset
+	type request struct {
+		// in: formData
+		// Required: true
+		Token string `json:"token" validate:"required"`
+		// in: formData
+		// Required: true
+		Password string `json:"password" validate:"required,password"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	ut := store.NewUserToken(p.DB, p.Q)
+
+	// Get the item by token.
+	exists, err := ut.FindOneByToken(req.Token, store.TokenPasswordReset)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errResetInvalid
+	}
+
+	// Ensure the password does not contain the name or email of the user.
+	// The token is still valid so the user can choose another password.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err = u.FindOneByID(u, ut.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errResetInvalid
+	} else if err = p.Bind.ValidatePassword(req.Password, u.FirstName, u.LastName, u.Email); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Mark the token as used. If another request used the token first, the
+	// token is no longer valid.
+	affected, err := ut.MarkUsed(ut.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		return http.StatusBadRequest, errResetInvalid
+	}
+
+	// Encrypt the password.
+	hash, err := p.Password.HashString(req.Password)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Change the password.
+	err = u.UpdatePassword(ut.UserID, hash)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Any other reset tokens were sent before the password changed.
+	err = ut.MarkAllUsed(ut.UserID, store.TokenPasswordReset)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Revoke the sessions and the access tokens of the user.
+	err = p.revokeSessions(ut.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = p.Revocation.RevokeUser(ut.UserID, time.Now())
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "password reset")
+}
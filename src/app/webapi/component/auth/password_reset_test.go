@@ -0,0 +1,134 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordReset(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	refresh, err := rt.Create(ID, "family", time.Hour)
+	assert.Nil(t, err)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+	ort := store.NewOAuthRefreshToken(core.DB, core.Q)
+	oauthRefresh, err := ort.Create(clientID, ID, "grant", "user:read", time.Hour)
+	assert.Nil(t, err)
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenPasswordReset, time.Hour)
+	assert.Nil(t, err)
+	other, err := ut.Create(ID, store.TokenPasswordReset, time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("token", token)
+	form.Add("password", "new-password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/password/reset", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The password is changed.
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, core.Password.MatchString(u.Password, "new-password"))
+
+	// The refresh tokens and access tokens are revoked.
+	found, err = rt.FindOneByToken(refresh)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	found, err = ort.FindOneByToken(oauthRefresh)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, ort.Used())
+
+	revoked, err := core.Revocation.IsUserRevoked(ID, time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// The token can only be used once.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/password/reset", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password reset token is invalid")
+
+	// Other reset tokens can no longer be used.
+	form.Set("token", other)
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/password/reset", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenPasswordReset, 1*time.Second)
+	assert.Nil(t, err)
+
+	time.Sleep(2 * time.Second)
+
+	form := url.Values{}
+	form.Add("token", token)
+	form.Add("password", "new-password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/password/reset", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password reset token is invalid")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPasswordResetWeakPassword(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenPasswordReset, time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("token", token)
+	form.Add("password", "jsmith-password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/password/reset", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "password must not contain your name or email address")
+
+	// The token can still be used with another password.
+	form.Set("password", "new-password")
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/password/reset", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
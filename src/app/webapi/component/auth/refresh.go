@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/internal/cookie"
+	"app/webapi/model"
+	"app/webapi/store"
+)
+
+// errRefreshInvalid is returned for every refresh token that cannot be
+// exchanged.
+var errRefreshInvalid = errors.New("refresh token is invalid")
+
+// errCSRFInvalid is returned when the CSRF header does not match the cookie.
+var errCSRFInvalid = errors.New("csrf token is invalid")
+
+// Refresh .
+// swagger:route POST /v1/auth/refresh auth AuthRefresh
+//
+// Exchange a refresh token for a new access token and refresh token. A browser
+// client sends the refresh token in a cookie with the CSRF token in the
+// X-CSRF-Token header and gets the new tokens in cookies.
+//
+// Responses:
+//   200: AuthRefreshResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Refresh(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthRefresh
+	type request struct {
+		// in: formData
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Read the refresh token from the cookie if it is not in the form.
+	useCookie := false
+	if len(req.RefreshToken) == 0 && p.Auth.Cookie.Enabled {
+		req.RefreshToken = cookie.RefreshToken(r)
+		if len(req.RefreshToken) > 0 {
+			if !cookie.ValidCSRF(r) {
+				return http.StatusForbidden, errCSRFInvalid
+			}
+			useCookie = true
+		}
+	}
+
+	if len(req.RefreshToken) == 0 {
+		return http.StatusBadRequest, errors.New("refresh_token is required")
+	}
+
+	// Create the DB store.
+	rt := store.NewRefreshToken(p.DB, p.Q)
+
+	// Get the item by token.
+	exists, err := rt.FindOneByToken(req.RefreshToken)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusUnauthorized, errRefreshInvalid
+	}
+
+	// A token that was already exchanged has been stolen or replayed so
+	// revoke the session with every token in the family.
+	if rt.Used() {
+		err = p.revokeSession(rt.FamilyID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusUnauthorized, errRefreshInvalid
+	}
+
+	// The user may have been deleted or deactivated since the token was
+	// issued.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err = u.FindOneByID(u, rt.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists || u.StatusID != store.StatusActive {
+		return http.StatusUnauthorized, errRefreshInvalid
+	}
+
+	// Mark the token as used. If another request exchanged the token first,
+	// treat it as reuse as well.
+	affected, err := rt.MarkUsed(rt.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		err = p.revokeSession(rt.FamilyID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusUnauthorized, errRefreshInvalid
+	}
+
+	// Generate the tokens in the same family.
+	t, refresh, err := p.issueTokens(r, rt.UserID, rt.FamilyID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.AuthRefreshResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.ExpiresIn = int(p.Auth.AccessTokenDuration().Seconds())
+
+	if useCookie {
+		resp.Body.Data.CSRFToken, err = p.Auth.Cookie.SetTokens(w, t, p.Auth.AccessTokenDuration(),
+			refresh, p.Auth.RefreshTokenDuration())
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	} else {
+		resp.Body.Data.Token = t
+		resp.Body.Data.RefreshToken = refresh
+	}
+
+	return p.Response.JSON(w, resp.Body)
+}
@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefresh(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	token, err := rt.Create(ID, "family", time.Hour)
+	assert.Nil(t, err)
+
+	tokenUserID := ""
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
+		tokenUserID = claims.Subject
+		return "token", nil
+	}
+
+	form := url.Values{}
+	form.Add("refresh_token", token)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/refresh", form)
+
+	r := new(model.AuthRefreshResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "token", r.Body.Data.Token)
+	assert.NotEmpty(t, r.Body.Data.RefreshToken)
+	assert.NotEqual(t, token, r.Body.Data.RefreshToken)
+	assert.Equal(t, ID, tokenUserID)
+
+	// The new token must be in the same family.
+	found, err := rt.FindOneByToken(r.Body.Data.RefreshToken)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "family", rt.FamilyID)
+	assert.False(t, rt.Used())
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestRefreshReuse(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	token, err := rt.Create(ID, "family", time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("refresh_token", token)
+
+	// Rotate the token.
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/refresh", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	r := new(model.AuthRefreshResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	// Reuse the old token.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/refresh", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "refresh token is invalid")
+
+	// The whole family is now revoked.
+	form = url.Values{}
+	form.Add("refresh_token", r.Body.Data.RefreshToken)
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/refresh", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The session and the access tokens issued in it are revoked.
+	revoked, err := core.Revocation.IsRevoked("family")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	us := store.NewUserSession(core.DB, core.Q)
+	found, err := us.FindOneActiveByUser("family", ID)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestRefreshInvalid(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	expired, err := rt.Create(ID, "family", -time.Hour)
+	assert.Nil(t, err)
+
+	// The token of a deleted user is not valid.
+	deletedID, err := u.Create("Jane", "Doe", "jdoe@example.com", "password")
+	assert.Nil(t, err)
+	deleted, err := rt.Create(deletedID, "family2", time.Hour)
+	assert.Nil(t, err)
+	_, err = u.DeleteOneByID(u, deletedID)
+	assert.Nil(t, err)
+
+	// The token of an inactive user is not valid.
+	inactiveID, err := u.Create("Jim", "Doe", "jim@example.com", "password")
+	assert.Nil(t, err)
+	inactive, err := rt.Create(inactiveID, "family3", time.Hour)
+	assert.Nil(t, err)
+
+	for _, v := range []string{
+		"unknown",
+		expired,
+		deleted,
+		inactive,
+	} {
+		form := url.Values{}
+		form.Add("refresh_token", v)
+
+		w := testrequest.SendForm(t, core, "POST", "/v1/auth/refresh", form)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "refresh token is invalid")
+	}
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/model"
+	"app/webapi/pkg/totp"
+	"app/webapi/store"
+)
+
+// errCodeInvalid is returned when a two-factor code is not valid.
+var errCodeInvalid = errors.New("code is invalid")
+
+// TwoFactorConfirm .
+// swagger:route POST /v1/auth/2fa/confirm auth AuthTwoFactorConfirm
+//
+// Enable two-factor authentication with a code from the enrolled secret. The
+// response contains the recovery codes, which are only shown once.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: AuthTwoFactorConfirmResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) TwoFactorConfirm(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthTwoFactorConfirm
+	type request struct {
+		// in: formData
+		// Required: true
+		Code string `json:"code" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	caller, status, err := userCaller(r)
+	if err != nil {
+		return status, err
+	}
+
+	// Get the enrolled secret.
+	tf := store.NewUserTOTP(p.DB, p.Q)
+	exists, err := tf.FindOneByID(tf, caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("two-factor authentication is not enrolled")
+	} else if tf.Enabled() {
+		return http.StatusBadRequest, errors.New("two-factor authentication is already enabled")
+	}
+
+	// Ensure the code matches.
+	step, ok, err := totp.New().Match(tf.Secret, req.Code)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !ok {
+		return http.StatusBadRequest, errCodeInvalid
+	}
+
+	affected, err := tf.Confirm(caller.UserID, step)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		return http.StatusBadRequest, errors.New("two-factor authentication is already enabled")
+	}
+
+	// Replace any previous recovery codes.
+	codes, hashes, err := p.newRecoveryCodes()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = store.NewRecoveryCode(p.DB, p.Q).Replace(caller.UserID, hashes)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.AuthTwoFactorConfirmResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.RecoveryCodes = codes
+	return p.Response.JSON(w, resp.Body)
+}
@@ -0,0 +1,83 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/totp"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorConfirm(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	tf := store.NewUserTOTP(core.DB, core.Q)
+	assert.Nil(t, tf.Save(ID, secret))
+
+	p := &principal.Principal{UserID: ID}
+
+	// A wrong code is rejected.
+	form := url.Values{}
+	form.Add("code", "abcdef")
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/confirm", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "code is invalid")
+
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+	form.Set("code", code)
+	w = testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/confirm", form)
+
+	r := new(model.AuthTwoFactorConfirmResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 10, len(r.Body.Data.RecoveryCodes))
+	assert.Equal(t, 11, len(r.Body.Data.RecoveryCodes[0]))
+
+	// The second factor is enabled.
+	found, err := tf.FindOneByID(tf, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, tf.Enabled())
+
+	// Only the hashes of the recovery codes are stored.
+	rc := store.NewRecoveryCode(core.DB, core.Q)
+	group, err := rc.FindUnused(ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(*group))
+	code0 := strings.Replace(r.Body.Data.RecoveryCodes[0], "-", "", -1)
+	matches := 0
+	for _, v := range *group {
+		assert.NotEqual(t, code0, v.CodeHash)
+		if core.Password.MatchString(v.CodeHash, code0) {
+			matches++
+		}
+	}
+	assert.Equal(t, 1, matches)
+
+	// The second factor cannot be confirmed twice.
+	w = testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/confirm", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/internal/throttle"
+	"app/webapi/store"
+)
+
+// TwoFactorDisable .
+// swagger:route POST /v1/auth/2fa/disable auth AuthTwoFactorDisable
+//
+// Disable two-factor authentication with a code or a recovery code.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   423: LockedResponse
+//   429: TooManyRequestsResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) TwoFactorDisable(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthTwoFactorDisable
+	type request struct {
+		// in: formData
+		// Required: true
+		Code string `json:"code" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	caller, status, err := userCaller(r)
+	if err != nil {
+		return status, err
+	}
+
+	// Get the user.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err := u.FindOneByID(u, caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user not found")
+	}
+
+	// Reject the guess early while the account or IP address is blocked.
+	// Wrong codes count against the logins of the account so a stolen
+	// access token cannot be used to guess the code.
+	ip := throttle.ClientIP(r)
+	lt := throttle.New(p.DB, p.Q, p.Auth)
+	if status, err := lt.Blocked(w, u.Email, ip); err != nil {
+		return status, err
+	}
+
+	// Get the secret.
+	tf := store.NewUserTOTP(p.DB, p.Q)
+	exists, err = tf.FindOneByID(tf, caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists || !tf.Enabled() {
+		return http.StatusBadRequest, errors.New("two-factor authentication is not enabled")
+	}
+
+	// Ensure the code matches.
+	ok, err := p.checkSecondFactor(tf, req.Code)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !ok {
+		if err = lt.Failed(u.ID, u.Email, ip); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusBadRequest, errCodeInvalid
+	}
+
+	// Remove the secret and the recovery codes.
+	_, err = tf.DeleteOneByID(tf, caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = store.NewRecoveryCode(p.DB, p.Q).DeleteByUserID(caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "two-factor authentication disabled")
+}
@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/totp"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorDisable(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	tf := store.NewUserTOTP(core.DB, core.Q)
+	assert.Nil(t, tf.Save(ID, secret))
+	_, err = tf.Confirm(ID, 0)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{UserID: ID}
+
+	// A wrong code is rejected.
+	form := url.Values{}
+	form.Add("code", "abcdef")
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/disable", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+	form.Set("code", code)
+	w = testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/disable", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The secret is removed.
+	found, err := tf.FindOneByID(tf, ID)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	// The second factor cannot be disabled twice.
+	w = testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/disable", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "not enabled")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTwoFactorDisableThrottle(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginBackoffAfter = 2
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	tf := store.NewUserTOTP(core.DB, core.Q)
+	assert.Nil(t, tf.Save(ID, secret))
+	_, err = tf.Confirm(ID, 0)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{UserID: ID}
+
+	form := url.Values{}
+	form.Add("code", "abcdef")
+	for i := 0; i < 2; i++ {
+		w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/disable", form)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	// The wrong codes count against the logins of the account so the next
+	// guess is delayed, even with the correct code.
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+	form.Set("code", code)
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/disable", form)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// The second factor is still enabled.
+	found, err := tf.FindOneByID(tf, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, tf.Enabled())
+
+	testutil.TeardownDatabase(unique)
+}
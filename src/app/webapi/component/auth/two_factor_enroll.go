@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/model"
+	"app/webapi/pkg/totp"
+	"app/webapi/store"
+)
+
+// TwoFactorEnroll .
+// swagger:route POST /v1/auth/2fa/enroll auth AuthTwoFactorEnroll
+//
+// Create a TOTP secret for the caller. Two-factor authentication is enabled
+// once the secret is confirmed with a code.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: AuthTwoFactorEnrollResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) TwoFactorEnroll(w http.ResponseWriter, r *http.Request) (int, error) {
+	caller, status, err := userCaller(r)
+	if err != nil {
+		return status, err
+	}
+
+	// Get the user.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err := u.FindOneByID(u, caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user not found")
+	}
+
+	// A confirmed secret must be disabled before enrolling again.
+	tf := store.NewUserTOTP(p.DB, p.Q)
+	exists, err = tf.FindOneByID(tf, caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if exists && tf.Enabled() {
+		return http.StatusBadRequest, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = tf.Save(caller.UserID, secret)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.AuthTwoFactorEnrollResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.Secret = secret
+	resp.Body.Data.URI = totp.New().URI(p.Auth.Issuer(), u.Email, secret)
+	return p.Response.JSON(w, resp.Body)
+}
@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorEnroll(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	p := &principal.Principal{UserID: ID}
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/enroll", nil)
+
+	r := new(model.AuthTwoFactorEnrollResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 32, len(r.Body.Data.Secret))
+	assert.True(t, strings.HasPrefix(r.Body.Data.URI, "otpauth://totp/webapi:jsmith@example.com?"))
+	assert.Contains(t, r.Body.Data.URI, "secret="+r.Body.Data.Secret)
+
+	// The secret is not enabled until it is confirmed.
+	tf := store.NewUserTOTP(core.DB, core.Q)
+	found, err := tf.FindOneByID(tf, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, r.Body.Data.Secret, tf.Secret)
+	assert.False(t, tf.Enabled())
+
+	// Enrolling again replaces the unconfirmed secret.
+	w = testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/enroll", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	found, err = tf.FindOneByID(tf, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.NotEqual(t, r.Body.Data.Secret, tf.Secret)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTwoFactorEnrollEnabled(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	tf := store.NewUserTOTP(core.DB, core.Q)
+	assert.Nil(t, tf.Save(ID, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"))
+	_, err = tf.Confirm(ID, 0)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{UserID: ID}
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/enroll", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "already enabled")
+
+	// API keys cannot manage the second factor.
+	p = &principal.Principal{UserID: ID, KeyID: "1"}
+	w = testrequest.SendFormAs(t, core, p, "POST", "/v1/auth/2fa/enroll", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
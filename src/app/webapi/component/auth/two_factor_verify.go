@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/internal/throttle"
+	"app/webapi/store"
+)
+
+// maxTwoFactorAttempts is the number of wrong codes allowed before the
+// two-factor token can no longer be used.
+const maxTwoFactorAttempts = 5
+
+// errTwoFactorInvalid is returned for every two-factor token that cannot be
+// exchanged.
+var errTwoFactorInvalid = errors.New("two-factor token is invalid")
+
+// TwoFactorVerify .
+// swagger:route POST /v1/auth/2fa/verify auth AuthTwoFactorVerify
+//
+// Exchange the two-factor token from the login and a code or a recovery code
+// for an access token.
+//
+// Responses:
+//   200: AuthLoginResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   423: LockedResponse
+//   429: TooManyRequestsResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) TwoFactorVerify(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthTwoFactorVerify
+	type request struct {
+		// in: formData
+		// Required: true
+		TwoFactorToken string `json:"two_factor_token" validate:"required"`
+		// in: formData
+		// Required: true
+		Code string `json:"code" validate:"required"`
+		// Cookie set to true writes the tokens to cookies for a browser
+		// client.
+		//
+		// in: formData
+		Cookie string `json:"cookie" validate:"omitempty,oneof=true false"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	useCookie, err := p.useCookie(req.Cookie)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Get the item by token.
+	ut := store.NewUserToken(p.DB, p.Q)
+	exists, err := ut.FindOneByToken(req.TwoFactorToken, store.TokenTwoFactor)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusUnauthorized, errTwoFactorInvalid
+	}
+
+	// Get the user. Wrong codes count against the logins of the account.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err = u.FindOneByID(u, ut.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusUnauthorized, errTwoFactorInvalid
+	}
+
+	ip := throttle.ClientIP(r)
+	lt := throttle.New(p.DB, p.Q, p.Auth)
+	if status, err := lt.Blocked(w, u.Email, ip); err != nil {
+		return status, err
+	}
+
+	// Get the secret.
+	tf := store.NewUserTOTP(p.DB, p.Q)
+	exists, err = tf.FindOneByID(tf, ut.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists || !tf.Enabled() {
+		return http.StatusUnauthorized, errTwoFactorInvalid
+	}
+
+	// Ensure the code matches. Each wrong code counts against the token.
+	ok, err := p.checkSecondFactor(tf, req.Code)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !ok {
+		err = ut.AddAttempt(ut.ID, maxTwoFactorAttempts)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if err = lt.Failed(u.ID, u.Email, ip); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusUnauthorized, errCodeInvalid
+	}
+
+	// Mark the token as used.
+	affected, err := ut.MarkUsed(ut.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		return http.StatusUnauthorized, errTwoFactorInvalid
+	}
+
+	// Forget the failed logins of the account.
+	if err = lt.Succeeded(u.Email); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.writeLogin(w, r, ut.UserID, useCookie)
+}
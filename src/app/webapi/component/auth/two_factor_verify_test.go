@@ -0,0 +1,233 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/totp"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// loginTwoFactor creates an active user with two-factor authentication and
+// returns the two-factor token from the login.
+func loginTwoFactor(t *testing.T, core component.Core, secret string) (string, string) {
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+
+	tf := store.NewUserTOTP(core.DB, core.Q)
+	assert.Nil(t, tf.Save(ID, secret))
+	_, err = tf.Confirm(ID, 0)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("email", "jsmith@example.com")
+	form.Add("password", "password")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", form)
+
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	// Only the two-factor token is returned.
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, r.Body.Data.Token)
+	assert.Empty(t, r.Body.Data.RefreshToken)
+	assert.NotEmpty(t, r.Body.Data.TwoFactorToken)
+
+	return ID, r.Body.Data.TwoFactorToken
+}
+
+func TestTwoFactorVerify(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	tokenUserID := ""
+	m.Token.GenerateFunc = func(claims webtoken.Claims, duration time.Duration) (string, error) {
+		tokenUserID = claims.Subject
+		return "token", nil
+	}
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	ID, token := loginTwoFactor(t, core, secret)
+	assert.Empty(t, tokenUserID)
+
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("two_factor_token", token)
+	form.Add("code", code)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "token", r.Body.Data.Token)
+	assert.NotEmpty(t, r.Body.Data.RefreshToken)
+	assert.Equal(t, ID, tokenUserID)
+
+	// The two-factor token can only be used once.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "two-factor token is invalid")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTwoFactorVerifyReplay(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	_, token := loginTwoFactor(t, core, secret)
+
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("two_factor_token", token)
+	form.Add("code", code)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A code cannot be used for a second login.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/login", url.Values{
+		"email":    {"jsmith@example.com"},
+		"password": {"password"},
+	})
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	form.Set("two_factor_token", r.Body.Data.TwoFactorToken)
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "code is invalid")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTwoFactorVerifyRecoveryCode(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	ID, token := loginTwoFactor(t, core, secret)
+
+	hash, err := core.Password.HashString("abcdefghij")
+	assert.Nil(t, err)
+	rc := store.NewRecoveryCode(core.DB, core.Q)
+	assert.Nil(t, rc.Replace(ID, []string{hash}))
+
+	form := url.Values{}
+	form.Add("two_factor_token", token)
+	form.Add("code", "ABCDE-FGHIJ")
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The recovery code is used.
+	group, err := rc.FindUnused(ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(*group))
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTwoFactorVerifyAttempts(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginBackoffAfter = 10
+	core.Auth.LoginLockoutAfter = 10
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	_, token := loginTwoFactor(t, core, secret)
+
+	form := url.Values{}
+	form.Add("two_factor_token", token)
+	form.Add("code", "abcdef")
+
+	for i := 0; i < 5; i++ {
+		w := testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "code is invalid")
+	}
+
+	// The token cannot be used after too many wrong codes.
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+	form.Set("code", code)
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "two-factor token is invalid")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTwoFactorVerifyLockout(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginBackoffAfter = 10
+	core.Auth.LoginLockoutAfter = 3
+
+	secret, err := totp.NewSecret()
+	assert.Nil(t, err)
+	_, token := loginTwoFactor(t, core, secret)
+
+	form := url.Values{}
+	form.Add("two_factor_token", token)
+	form.Add("code", "abcdef")
+
+	for i := 0; i < 2; i++ {
+		w := testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// The correct password does not forget the wrong codes.
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/login", url.Values{
+		"email":    {"jsmith@example.com"},
+		"password": {"password"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	r := new(model.AuthLoginResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	form.Set("two_factor_token", r.Body.Data.TwoFactorToken)
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The account is locked, even with the correct code.
+	code, err := totp.New().Code(secret, time.Now())
+	assert.Nil(t, err)
+	form.Set("code", code)
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/2fa/verify", form)
+	assert.Equal(t, http.StatusLocked, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	testutil.TeardownDatabase(unique)
+}
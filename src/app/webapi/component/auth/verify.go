@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// errVerifyInvalid is returned for every verification token that cannot be
+// used.
+var errVerifyInvalid = errors.New("verification token is invalid")
+
+// Verify .
+// swagger:route POST /v1/auth/verify auth AuthVerify
+//
+// Exchange an email verification token to activate a user. A token sent to
+// a new email of the user changes the email to the new address.
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Verify(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters AuthVerify
+	type request struct {
+		// in: formData
+		// Required: true
+		Token string `json:"token" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	ut := store.NewUserToken(p.DB, p.Q)
+
+	// Get the item by token, either for a new user or for a new email.
+	exists, err := ut.FindOneByToken(req.Token, store.TokenEmailVerify)
+	if err == nil && !exists {
+		exists, err = ut.FindOneByToken(req.Token, store.TokenEmailChange)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errVerifyInvalid
+	}
+
+	// A deleted user cannot be activated by an old token.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err = u.FindOneByID(u, ut.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errVerifyInvalid
+	}
+
+	// Ensure no other user took the new email since it was sent.
+	if ut.Purpose == store.TokenEmailChange {
+		if u.PendingEmail == nil {
+			return http.StatusBadRequest, errVerifyInvalid
+		}
+
+		exists, ID, err := p.Q.WithDeleted().ExistsByField(u, "email", *u.PendingEmail)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if exists && ID != u.ID {
+			return http.StatusBadRequest, errors.New("email is already in use")
+		}
+	}
+
+	// Mark the token as used.
+	affected, err := ut.MarkUsed(ut.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		return http.StatusBadRequest, errVerifyInvalid
+	}
+
+	// Change the email to the verified address.
+	if ut.Purpose == store.TokenEmailChange {
+		affected, err = u.ConfirmPendingEmail(u.ID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if affected < 1 {
+			return http.StatusBadRequest, errVerifyInvalid
+		}
+	}
+
+	// Activate the user.
+	err = u.UpdateStatus(u.ID, store.StatusActive)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "email verified")
+}
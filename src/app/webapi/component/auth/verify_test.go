@@ -0,0 +1,191 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenEmailVerify, time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("token", token)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The user is active.
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+
+	// The token can only be used once.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "verification token is invalid")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestVerifyLink(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenEmailVerify, time.Hour)
+	assert.Nil(t, err)
+
+	// The token in a link is accepted.
+	w := testrequest.SendForm(t, core, "GET", "/v1/auth/verify?token="+token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestVerifyWrongPurpose(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	// A password reset token cannot verify an email.
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenPasswordReset, time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("token", token)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestVerifyDeletedUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenEmailVerify, time.Hour)
+	assert.Nil(t, err)
+
+	_, err = u.DeleteOneByID(u, ID)
+	assert.Nil(t, err)
+
+	// The token cannot activate a deleted user.
+	form := url.Values{}
+	form.Add("token", token)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "verification token is invalid")
+
+	found, err := core.Q.WithDeleted().FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, store.StatusInactive, u.StatusID)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestVerifyEmailChange(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+	assert.Nil(t, u.UpdatePendingEmail(ID, "jsmith2@example.com"))
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenEmailChange, time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("token", token)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The email is changed to the verified address.
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "jsmith2@example.com", u.Email)
+	assert.Nil(t, u.PendingEmail)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+
+	// The token can only be used once.
+	w = testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestVerifyEmailChangeInUse(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdatePendingEmail(ID, "jane@example.com"))
+
+	ut := store.NewUserToken(core.DB, core.Q)
+	token, err := ut.Create(ID, store.TokenEmailChange, time.Hour)
+	assert.Nil(t, err)
+
+	// Another user took the email after the token was sent.
+	_, err = u.Create("Jane", "Smith", "jane@example.com", "password")
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("token", token)
+
+	w := testrequest.SendForm(t, core, "POST", "/v1/auth/verify", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "email is already in use")
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "jsmith@example.com", u.Email)
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,122 @@
+package component
+
+import (
+	"time"
+
+	"app/webapi/internal/cookie"
+	"app/webapi/pkg/oidc"
+)
+
+// AuthConfig contains the authentication settings for the components.
+type AuthConfig struct {
+	AnonymousToken     bool `json:"AnonymousToken"`     // Allow GET /v1/auth to issue a token without credentials.
+	AccessTokenMinutes int  `json:"AccessTokenMinutes"` // Lifetime of an access token, defaults to 15.
+	RefreshTokenHours  int  `json:"RefreshTokenHours"`  // Lifetime of a refresh token, defaults to 720.
+
+	PasswordResetMinutes int    `json:"PasswordResetMinutes"` // Lifetime of a password reset token, defaults to 60.
+	PasswordResetURL     string `json:"PasswordResetURL"`     // Link in the password reset email, the token is appended.
+	EmailVerifyHours     int    `json:"EmailVerifyHours"`     // Lifetime of an email verification token, defaults to 48.
+	EmailVerifyURL       string `json:"EmailVerifyURL"`       // Link in the verification email, the token is appended.
+	TwoFactorIssuer      string `json:"TwoFactorIssuer"`      // Name shown in authenticator apps, defaults to webapi.
+	MagicLinkMinutes     int    `json:"MagicLinkMinutes"`     // Lifetime of a login link, defaults to 15.
+	MagicLinkURL         string `json:"MagicLinkURL"`         // Link in the login link email, the token is appended.
+	MagicLinkPerHour     int    `json:"MagicLinkPerHour"`     // Login links sent to an address per hour, defaults to 3.
+
+	LoginBackoffAfter   int `json:"LoginBackoffAfter"`   // Failed logins of an account before each retry is delayed, defaults to 3.
+	LoginIPBackoffAfter int `json:"LoginIPBackoffAfter"` // Failed logins from an IP address before each retry is delayed, defaults to 20.
+	LoginLockoutAfter   int `json:"LoginLockoutAfter"`   // Failed logins of an account before it is locked, defaults to 10.
+	LoginLockoutMinutes int `json:"LoginLockoutMinutes"` // Lifetime of a lockout and of the failed login count, defaults to 15.
+
+	OIDC   []oidc.Config `json:"OIDC"`   // OpenID Connect providers that users can log in with.
+	Cookie cookie.Config `json:"Cookie"` // Tokens in cookies for browser clients.
+}
+
+// AccessTokenDuration returns the lifetime of an access token.
+func (c AuthConfig) AccessTokenDuration() time.Duration {
+	if c.AccessTokenMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.AccessTokenMinutes) * time.Minute
+}
+
+// RefreshTokenDuration returns the lifetime of a refresh token.
+func (c AuthConfig) RefreshTokenDuration() time.Duration {
+	if c.RefreshTokenHours <= 0 {
+		return 720 * time.Hour
+	}
+	return time.Duration(c.RefreshTokenHours) * time.Hour
+}
+
+// PasswordResetDuration returns the lifetime of a password reset token.
+func (c AuthConfig) PasswordResetDuration() time.Duration {
+	if c.PasswordResetMinutes <= 0 {
+		return 60 * time.Minute
+	}
+	return time.Duration(c.PasswordResetMinutes) * time.Minute
+}
+
+// EmailVerifyDuration returns the lifetime of an email verification token.
+func (c AuthConfig) EmailVerifyDuration() time.Duration {
+	if c.EmailVerifyHours <= 0 {
+		return 48 * time.Hour
+	}
+	return time.Duration(c.EmailVerifyHours) * time.Hour
+}
+
+// Issuer returns the name shown in authenticator apps.
+func (c AuthConfig) Issuer() string {
+	if len(c.TwoFactorIssuer) == 0 {
+		return "webapi"
+	}
+	return c.TwoFactorIssuer
+}
+
+// MagicLinkDuration returns the lifetime of a login link.
+func (c AuthConfig) MagicLinkDuration() time.Duration {
+	if c.MagicLinkMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.MagicLinkMinutes) * time.Minute
+}
+
+// MagicLinkLimit returns the login links sent to an address per hour.
+func (c AuthConfig) MagicLinkLimit() int {
+	if c.MagicLinkPerHour <= 0 {
+		return 3
+	}
+	return c.MagicLinkPerHour
+}
+
+// BackoffAfter returns the failed logins of an account before each retry is
+// delayed.
+func (c AuthConfig) BackoffAfter() int {
+	if c.LoginBackoffAfter <= 0 {
+		return 3
+	}
+	return c.LoginBackoffAfter
+}
+
+// IPBackoffAfter returns the failed logins from an IP address before each
+// retry is delayed.
+func (c AuthConfig) IPBackoffAfter() int {
+	if c.LoginIPBackoffAfter <= 0 {
+		return 20
+	}
+	return c.LoginIPBackoffAfter
+}
+
+// LockoutAfter returns the failed logins of an account before it is locked.
+func (c AuthConfig) LockoutAfter() int {
+	if c.LoginLockoutAfter <= 0 {
+		return 10
+	}
+	return c.LoginLockoutAfter
+}
+
+// LockoutDuration returns the lifetime of a lockout.
+func (c AuthConfig) LockoutDuration() time.Duration {
+	if c.LoginLockoutMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.LoginLockoutMinutes) * time.Minute
+}
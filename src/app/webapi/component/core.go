@@ -1,25 +1,33 @@
 package component
 
 // NewCore returns the standard component dependencies.
-func NewCore(l ILogger, d IDatabase, q IQuery, b IBind, resp IResponse, t IToken, p IPassword) Core {
+func NewCore(l ILogger, d IDatabase, q IQuery, b IBind, resp IResponse, t IToken, p IPassword, rev IRevocation, acc IAccess, m IMailer, a AuthConfig) Core {
 	return Core{
-		Log:      l,
-		DB:       d,
-		Q:        q,
-		Bind:     b,
-		Response: resp,
-		Token:    t,
-		Password: p,
+		Log:        l,
+		DB:         d,
+		Q:          q,
+		Bind:       b,
+		Response:   resp,
+		Token:      t,
+		Password:   p,
+		Revocation: rev,
+		Access:     acc,
+		Mail:       m,
+		Auth:       a,
 	}
 }
 
 // Core contains all the dependencies for the components.
 type Core struct {
-	Log      ILogger
-	DB       IDatabase
-	Q        IQuery
-	Bind     IBind
-	Response IResponse
-	Token    IToken
-	Password IPassword
+	Log        ILogger
+	DB         IDatabase
+	Q          IQuery
+	Bind       IBind
+	Response   IResponse
+	Token      IToken
+	Password   IPassword
+	Revocation IRevocation
+	Access     IAccess
+	Mail       IMailer
+	Auth       AuthConfig
 }
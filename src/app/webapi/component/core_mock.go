@@ -7,6 +7,8 @@ import (
 	"app/webapi/pkg/database"
 	"app/webapi/pkg/passhash"
 	"app/webapi/pkg/query"
+	"app/webapi/pkg/rbac"
+	"app/webapi/pkg/revocation"
 )
 
 // NewCoreMock returns all mocked dependencies.
@@ -17,27 +19,41 @@ func NewCoreMock(db *database.DBW) (Core, *CoreMock) {
 	resp := response.New()
 	binder := bind.New()
 	p := passhash.New()
+	rev := revocation.New(db)
+	acc := rbac.New(db)
+	mm := new(testutil.MockMailer)
+	a := AuthConfig{
+		AnonymousToken: true,
+	}
 
-	core := NewCore(ml, db, mq, binder, resp, mt, p)
+	core := NewCore(ml, db, mq, binder, resp, mt, p, rev, acc, mm, a)
 	m := &CoreMock{
-		Log:      ml,
-		DB:       db,
-		Q:        mq,
-		Bind:     binder,
-		Response: resp,
-		Token:    mt,
-		Password: p,
+		Log:        ml,
+		DB:         db,
+		Q:          mq,
+		Bind:       binder,
+		Response:   resp,
+		Token:      mt,
+		Password:   p,
+		Revocation: rev,
+		Access:     acc,
+		Mail:       mm,
+		Auth:       a,
 	}
 	return core, m
 }
 
 // CoreMock contains all the mocked dependencies.
 type CoreMock struct {
-	Log      *testutil.MockLogger
-	DB       IDatabase
-	Q        IQuery
-	Bind     IBind
-	Response IResponse
-	Token    *testutil.MockToken
-	Password IPassword
+	Log        *testutil.MockLogger
+	DB         IDatabase
+	Q          IQuery
+	Bind       IBind
+	Response   IResponse
+	Token      *testutil.MockToken
+	Password   IPassword
+	Revocation IRevocation
+	Access     IAccess
+	Mail       *testutil.MockMailer
+	Auth       AuthConfig
 }
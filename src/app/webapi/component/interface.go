@@ -7,6 +7,7 @@ import (
 
 	"app/webapi/pkg/query"
 	"app/webapi/pkg/router"
+	"app/webapi/pkg/webtoken"
 )
 
 // IDatabase provides data query capabilities.
@@ -16,14 +17,10 @@ type IDatabase interface {
 	Select(dest interface{}, query string, args ...interface{}) error
 }
 
-// IQuery provides default queries.
+// IQuery provides default queries. It is defined by the query package so
+// WithDeleted can return the same interface.
 type IQuery interface {
-	FindOneByID(dest query.IRecord, ID string) (found bool, err error)
-	FindAll(dest query.IRecord) (total int, err error)
-	ExistsByID(db query.IRecord, s string) (found bool, err error)
-	ExistsByField(db query.IRecord, field string, value string) (found bool, ID string, err error)
-	DeleteOneByID(dest query.IRecord, ID string) (affected int, err error)
-	DeleteAll(dest query.IRecord) (affected int, err error)
+	query.IQuery
 }
 
 // ILogger provides logging capabilities.
@@ -47,6 +44,7 @@ type IRouter interface {
 type IBind interface {
 	FormUnmarshal(i interface{}, r *http.Request) (err error)
 	Validate(s interface{}) error
+	ValidatePassword(password string, personal ...string) error
 }
 
 // IResponse provides outputs for data.
@@ -58,11 +56,32 @@ type IResponse interface {
 
 // IToken provides outputs for the JWT.
 type IToken interface {
-	Generate(userID string, duration time.Duration) (string, error)
+	Generate(claims webtoken.Claims, duration time.Duration) (string, error)
+	Verify(s string) (*webtoken.Claims, error)
+	JWKS() []webtoken.JSONWebKey
+}
+
+// IRevocation provides token revocation.
+type IRevocation interface {
+	Revoke(tokenID string, expiresAt time.Time) error
+	IsRevoked(tokenID string) (bool, error)
+	RevokeUser(userID string, revokedAt time.Time) error
+	IsUserRevoked(userID string, issuedAt time.Time) (bool, error)
+}
+
+// IAccess provides permission checks for roles.
+type IAccess interface {
+	Allowed(roles []string, permission string) (bool, error)
+}
+
+// IMailer provides email delivery.
+type IMailer interface {
+	Send(to, subject, body string) error
 }
 
 // IPassword provides password hashing.
 type IPassword interface {
 	HashString(password string) (string, error)
 	MatchString(hash, password string) bool
+	NeedsRehash(hash string) bool
 }
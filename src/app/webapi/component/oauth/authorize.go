@@ -0,0 +1,165 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"app/webapi/internal/principal"
+	"app/webapi/model"
+	"app/webapi/store"
+)
+
+// codeDuration is the lifetime of an authorization code.
+const codeDuration = 10 * time.Minute
+
+// AuthorizeShow .
+// swagger:route GET /oauth/authorize oauth OAuthAuthorizeShow
+//
+// Check an authorization request and return the details to show the user on
+// the consent page.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OAuthAuthorizeResponse
+//   400: OAuthErrorResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) AuthorizeShow(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters OAuthAuthorizeShow
+	type request struct {
+		// in: query
+		// Required: true
+		ResponseType string `json:"response_type"`
+		// in: query
+		// Required: true
+		ClientID string `json:"client_id"`
+		// in: query
+		RedirectURI string `json:"redirect_uri"`
+		// in: query
+		Scope string `json:"scope"`
+		// in: query
+		State string `json:"state"`
+		// in: query
+		CodeChallenge string `json:"code_challenge"`
+		// in: query
+		CodeChallengeMethod string `json:"code_challenge_method"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, newError(errInvalidRequest, err.Error())
+	}
+
+	c, redirectURI, scopes, status, err := p.checkAuthorization(r, authorization{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	})
+	if err != nil {
+		return status, err
+	}
+
+	resp := new(model.OAuthAuthorizeResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.ClientID = c.ID
+	resp.Body.Data.ClientName = c.Name
+	resp.Body.Data.RedirectURI = redirectURI
+	resp.Body.Data.Scope = scopes
+	resp.Body.Data.State = req.State
+	return p.Response.JSON(w, resp.Body)
+}
+
+// Authorize .
+// swagger:route POST /oauth/authorize oauth OAuthAuthorize
+//
+// Approve or deny an authorization request and return the redirect URI with
+// the authorization code or the error.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OAuthAuthorizeDecisionResponse
+//   400: OAuthErrorResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Authorize(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters OAuthAuthorize
+	type request struct {
+		// in: formData
+		// Required: true
+		ResponseType string `json:"response_type"`
+		// in: formData
+		// Required: true
+		ClientID string `json:"client_id"`
+		// in: formData
+		RedirectURI string `json:"redirect_uri"`
+		// in: formData
+		Scope string `json:"scope"`
+		// in: formData
+		State string `json:"state"`
+		// in: formData
+		CodeChallenge string `json:"code_challenge"`
+		// in: formData
+		CodeChallengeMethod string `json:"code_challenge_method"`
+		// in: formData
+		// Required: true
+		Approve string `json:"approve" validate:"required,oneof=true false"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, newError(errInvalidRequest, err.Error())
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, newError(errInvalidRequest, "approve must be true or false")
+	}
+
+	c, redirectURI, scopes, status, err := p.checkAuthorization(r, authorization{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	})
+	if err != nil {
+		return status, err
+	}
+
+	params := url.Values{}
+	params.Set("state", req.State)
+
+	if req.Approve == "true" {
+		caller, _ := principal.FromRequest(r)
+		oc := store.NewOAuthCode(p.DB, p.Q)
+		code, err := oc.Create(c.ID, caller.UserID, req.RedirectURI,
+			strings.Join(scopes, " "), req.CodeChallenge, codeDuration)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		params.Set("code", code)
+	} else {
+		params.Set("error", errAccessDenied)
+	}
+
+	location, err := redirectTo(redirectURI, params)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.OAuthAuthorizeDecisionResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.RedirectTo = location
+	return p.Response.JSON(w, resp.Body)
+}
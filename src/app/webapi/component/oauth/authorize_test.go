@@ -0,0 +1,163 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// challenge is the S256 PKCE challenge of verifier from RFC 7636.
+const (
+	verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// authorizeCode returns a code that the user approved for the client.
+func authorizeCode(t *testing.T, core component.Core, userID, clientID string) string {
+	form := url.Values{}
+	form.Set("response_type", "code")
+	form.Set("client_id", clientID)
+	form.Set("redirect_uri", "https://app.example.com/callback")
+	form.Set("scope", "user:read")
+	form.Set("state", "xyz")
+	form.Set("code_challenge", challenge)
+	form.Set("code_challenge_method", "S256")
+	form.Set("approve", "true")
+
+	user := &principal.Principal{
+		UserID: userID,
+		Roles:  []string{principal.RoleUser},
+	}
+
+	w := testrequest.SendFormAs(t, core, user, "POST", "/oauth/authorize", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	r := new(model.OAuthAuthorizeDecisionResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	u, err := url.Parse(r.Body.Data.RedirectTo)
+	assert.Nil(t, err)
+	assert.Equal(t, "xyz", u.Query().Get("state"))
+
+	return u.Query().Get("code")
+}
+
+func TestAuthorizeShow(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Mobile", "user:read user:delete_all",
+		"https://app.example.com/callback", false, "")
+	assert.Nil(t, err)
+
+	user := &principal.Principal{
+		UserID: "user",
+		Roles:  []string{principal.RoleUser},
+	}
+
+	target := "/oauth/authorize?response_type=code&client_id=" + clientID +
+		"&scope=user:read&state=xyz&code_challenge=" + challenge +
+		"&code_challenge_method=S256"
+
+	w := testrequest.SendFormAs(t, core, user, "GET", target, nil)
+
+	r := new(model.OAuthAuthorizeResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Mobile", r.Body.Data.ClientName)
+	assert.Equal(t, "https://app.example.com/callback", r.Body.Data.RedirectURI)
+	assert.Equal(t, []string{"user:read"}, r.Body.Data.Scope)
+	assert.Equal(t, "xyz", r.Body.Data.State)
+
+	for _, v := range []struct {
+		query string
+		code  string
+	}{
+		// An unknown client.
+		{"response_type=code&client_id=unknown", "invalid_request"},
+		// A redirect URI that is not registered.
+		{"response_type=code&redirect_uri=https://evil.example.com&client_id=" + clientID, "invalid_request"},
+		// A public client without PKCE.
+		{"response_type=code&client_id=" + clientID, "invalid_request"},
+		// A plain PKCE challenge.
+		{"response_type=code&code_challenge_method=plain&code_challenge=" + challenge + "&client_id=" + clientID, "invalid_request"},
+		// The implicit grant.
+		{"response_type=token&client_id=" + clientID, "unsupported_response_type"},
+		// A scope the user does not hold.
+		{"response_type=code&scope=user:delete_all&code_challenge_method=S256&code_challenge=" + challenge + "&client_id=" + clientID, "invalid_scope"},
+		// A scope the client cannot request.
+		{"response_type=code&scope=role:assign&code_challenge_method=S256&code_challenge=" + challenge + "&client_id=" + clientID, "invalid_scope"},
+	} {
+		w = testrequest.SendFormAs(t, core, user, "GET", "/oauth/authorize?"+v.query, nil)
+
+		re := new(model.OAuthErrorResponse)
+		err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code, v.query)
+		assert.Equal(t, v.code, re.Body.Error, v.query)
+	}
+
+	// An OAuth client cannot authorize another client.
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID:   "user",
+		ClientID: "other",
+		Scopes:   []string{"user:read"},
+	}, "GET", target, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestAuthorizeDeny(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Partner", "user:read",
+		"https://app.example.com/callback?app=1", true, "")
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Set("response_type", "code")
+	form.Set("client_id", clientID)
+	form.Set("state", "xyz")
+	form.Set("approve", "false")
+
+	user := &principal.Principal{
+		UserID: "user",
+		Roles:  []string{principal.RoleUser},
+	}
+
+	w := testrequest.SendFormAs(t, core, user, "POST", "/oauth/authorize", form)
+
+	r := new(model.OAuthAuthorizeDecisionResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The only redirect URI is used and its query is kept.
+	u, err := url.Parse(r.Body.Data.RedirectTo)
+	assert.Nil(t, err)
+	assert.Equal(t, "app.example.com", u.Host)
+	assert.Equal(t, "1", u.Query().Get("app"))
+	assert.Equal(t, "access_denied", u.Query().Get("error"))
+	assert.Equal(t, "xyz", u.Query().Get("state"))
+	assert.Empty(t, u.Query().Get("code"))
+
+	testutil.TeardownDatabase(unique)
+}
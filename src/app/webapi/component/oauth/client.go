@@ -0,0 +1,141 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"app/webapi/internal/principal"
+	"app/webapi/model"
+	"app/webapi/store"
+)
+
+// ClientCreate .
+// swagger:route POST /v1/oauth/client oauth OAuthClientCreate
+//
+// Register an OAuth client. The secret of a confidential client is only
+// returned once.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OAuthClientCreateResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) ClientCreate(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters OAuthClientCreate
+	type request struct {
+		// in: formData
+		// Required: true
+		Name string `json:"name" validate:"required,max=100"`
+		// in: formData
+		// Required: true
+		ClientType string `json:"client_type" validate:"required,oneof=confidential public"`
+		// Scopes the client can request separated by spaces.
+		// in: formData
+		Scope string `json:"scope" validate:"max=1000"`
+		// Redirect URIs separated by spaces.
+		// in: formData
+		RedirectURI string `json:"redirect_uri"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// A public client can only use the authorization code grant so it needs
+	// a redirect URI.
+	uris := strings.Fields(req.RedirectURI)
+	if len(uris) == 0 && req.ClientType == "public" {
+		return http.StatusBadRequest, errors.New("redirect_uri is required for a public client")
+	}
+	for _, v := range uris {
+		u, err := url.Parse(v)
+		if err != nil || !u.IsAbs() || len(u.Fragment) > 0 {
+			return http.StatusBadRequest, errors.New("redirect_uri must be an absolute URI without a fragment")
+		}
+	}
+
+	// Create the DB store.
+	c := store.NewOAuthClient(p.DB, p.Q)
+
+	// Create the item.
+	caller, _ := principal.FromRequest(r)
+	ID, secret, err := c.Create(req.Name, req.Scope, req.RedirectURI,
+		req.ClientType == "confidential", caller.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.OAuthClientCreateResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data.ClientID = ID
+	resp.Body.Data.ClientSecret = secret
+	return p.Response.JSON(w, resp.Body)
+}
+
+// ClientDestroy .
+// swagger:route DELETE /v1/oauth/client/{client_id} oauth OAuthClientDestroy
+//
+// Delete an OAuth client with its authorization codes and refresh tokens.
+// The access tokens issued to the client are revoked.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) ClientDestroy(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters OAuthClientDestroy
+	type request struct {
+		// in: path
+		// x-example: CLIENTID
+		ClientID string `json:"client_id" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	c := store.NewOAuthClient(p.DB, p.Q)
+
+	// Delete the item.
+	count, err := c.DeleteOneByID(c, req.ClientID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if count < 1 {
+		return http.StatusBadRequest, errors.New("client does not exist")
+	}
+
+	// Revoke the access tokens issued to the client and the sessions of the
+	// users with the client.
+	err = p.Revocation.Revoke(req.ClientID, time.Now().Add(p.Auth.AccessTokenDuration()))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = store.NewUserSession(p.DB, p.Q).RevokeClient(req.ClientID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "client deleted")
+}
@@ -0,0 +1,128 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCreate(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	form := url.Values{}
+	form.Set("name", "Partner")
+	form.Set("client_type", "confidential")
+	form.Set("scope", "user:read role:read")
+	form.Set("redirect_uri", "https://partner.example.com/callback")
+
+	w := testrequest.SendFormAs(t, core, admin, "POST", "/v1/oauth/client", form)
+
+	r := new(model.OAuthClientCreateResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, r.Body.Data.ClientID)
+	assert.NotEmpty(t, r.Body.Data.ClientSecret)
+
+	// Only the hash of the secret is stored.
+	c := store.NewOAuthClient(core.DB, core.Q)
+	found, err := c.FindOneByID(c, r.Body.Data.ClientID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Partner", c.Name)
+	assert.NotEqual(t, r.Body.Data.ClientSecret, c.SecretHash)
+	assert.True(t, c.MatchSecret(r.Body.Data.ClientSecret))
+	assert.Equal(t, []string{"user:read", "role:read"}, c.Scopes())
+	assert.Equal(t, "admin", *c.CreatedBy)
+
+	// A public client has no secret.
+	form.Set("client_type", "public")
+	w = testrequest.SendFormAs(t, core, admin, "POST", "/v1/oauth/client", form)
+	r = new(model.OAuthClientCreateResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, r.Body.Data.ClientSecret)
+
+	// A public client needs a redirect URI.
+	form.Del("redirect_uri")
+	w = testrequest.SendFormAs(t, core, admin, "POST", "/v1/oauth/client", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A redirect URI must be absolute.
+	form.Set("redirect_uri", "/callback")
+	w = testrequest.SendFormAs(t, core, admin, "POST", "/v1/oauth/client", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A user cannot register a client.
+	user := &principal.Principal{
+		UserID: "user",
+		Roles:  []string{principal.RoleUser},
+	}
+	form.Set("redirect_uri", "https://partner.example.com/callback")
+	w = testrequest.SendFormAs(t, core, user, "POST", "/v1/oauth/client", form)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestClientDestroy(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	ID, _, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	us := store.NewUserSession(core.DB, core.Q)
+	err = us.Save("family", userID, ID, "", "", time.Hour)
+	assert.Nil(t, err)
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	w := testrequest.SendFormAs(t, core, admin, "DELETE", "/v1/oauth/client/"+ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, err := c.FindOneByID(c, ID)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	// The access tokens issued to the client are revoked.
+	revoked, err := core.Revocation.IsRevoked(ID)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// The sessions of the users with the client are revoked.
+	found, err = us.FindOneActiveByUser("family", userID)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	w = testrequest.SendFormAs(t, core, admin, "DELETE", "/v1/oauth/client/"+ID, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
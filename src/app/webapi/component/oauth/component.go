@@ -0,0 +1,26 @@
+package oauth
+
+import (
+	"app/webapi/component"
+)
+
+// New returns a new instance of the endpoint.
+func New(bc component.Core) *Endpoint {
+	return &Endpoint{
+		Core: bc,
+	}
+}
+
+// Endpoint contains the dependencies.
+type Endpoint struct {
+	component.Core
+}
+
+// Routes will set up the endpoints.
+func (p *Endpoint) Routes(router component.IRouter) {
+	router.Get("/oauth/authorize", p.AuthorizeShow)
+	router.Post("/oauth/authorize", p.Authorize)
+	router.Post("/oauth/token", p.Exchange)
+	router.Post("/v1/oauth/client", p.Require(component.PermissionOAuthClient, p.ClientCreate))
+	router.Delete("/v1/oauth/client/:client_id", p.Require(component.PermissionOAuthClient, p.ClientDestroy))
+}
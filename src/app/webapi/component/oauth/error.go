@@ -0,0 +1,46 @@
+package oauth
+
+import (
+	"app/webapi/model"
+)
+
+// Error codes from RFC 6749 and RFC 7636.
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errUnauthorizedClient      = "unauthorized_client"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errInvalidScope            = "invalid_scope"
+	errAccessDenied            = "access_denied"
+)
+
+// oauthError is an RFC 6749 error. The body of the error response contains
+// the code and the description instead of the status and message of the other
+// endpoints.
+type oauthError struct {
+	code        string
+	description string
+}
+
+// newError returns an error with the code and description.
+func newError(code, description string) *oauthError {
+	return &oauthError{
+		code:        code,
+		description: description,
+	}
+}
+
+// Error returns the code and description.
+func (e *oauthError) Error() string {
+	return e.code + ": " + e.description
+}
+
+// ResponseBody returns the body of the error response.
+func (e *oauthError) ResponseBody() interface{} {
+	resp := new(model.OAuthErrorResponse)
+	resp.Body.Error = e.code
+	resp.Body.ErrorDescription = e.description
+	return resp.Body
+}
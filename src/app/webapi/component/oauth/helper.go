@@ -0,0 +1,236 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"app/webapi/internal/principal"
+	"app/webapi/internal/throttle"
+	"app/webapi/model"
+	"app/webapi/pkg/securegen"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+)
+
+// authorization contains the parameters of an authorization request.
+type authorization struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// checkAuthorization returns the client, the redirect URI, and the scopes of
+// an authorization request. The caller must be a user that holds every scope.
+func (p *Endpoint) checkAuthorization(r *http.Request, a authorization) (*store.OAuthClient, string, []string, int, error) {
+	caller, ok := principal.FromRequest(r)
+	if !ok {
+		return nil, "", nil, http.StatusUnauthorized, errors.New("authorization token is missing")
+	} else if len(caller.KeyID) > 0 || len(caller.ClientID) > 0 || len(caller.UserID) == 0 {
+		return nil, "", nil, http.StatusForbidden, errors.New("only a user can authorize a client")
+	}
+
+	// Create the DB store.
+	c := store.NewOAuthClient(p.DB, p.Q)
+
+	// The client and the redirect URI are checked first because the other
+	// errors are shown to the client.
+	exists, err := c.FindOneByID(c, a.ClientID)
+	if err != nil {
+		return nil, "", nil, http.StatusInternalServerError, err
+	} else if !exists {
+		return nil, "", nil, http.StatusBadRequest, newError(errInvalidRequest, "client_id is invalid")
+	}
+
+	redirectURI := a.RedirectURI
+	if len(redirectURI) == 0 && len(c.RedirectURIList()) == 1 {
+		redirectURI = c.RedirectURIList()[0]
+	} else if !c.AllowsRedirectURI(redirectURI) {
+		return nil, "", nil, http.StatusBadRequest, newError(errInvalidRequest, "redirect_uri is not registered")
+	}
+
+	if a.ResponseType != "code" {
+		return nil, "", nil, http.StatusBadRequest, newError(errUnsupportedResponseType, "response_type must be code")
+	}
+
+	// Public clients cannot keep a secret so they must use PKCE.
+	if len(a.CodeChallenge) > 0 {
+		if a.CodeChallengeMethod != "S256" {
+			return nil, "", nil, http.StatusBadRequest, newError(errInvalidRequest, "code_challenge_method must be S256")
+		} else if len(a.CodeChallenge) < 43 || len(a.CodeChallenge) > 128 {
+			return nil, "", nil, http.StatusBadRequest, newError(errInvalidRequest, "code_challenge is invalid")
+		}
+	} else if !c.Confidential() {
+		return nil, "", nil, http.StatusBadRequest, newError(errInvalidRequest, "code_challenge is required")
+	}
+
+	scopes, oerr := requestedScopes(c, a.Scope)
+	if oerr != nil {
+		return nil, "", nil, http.StatusBadRequest, oerr
+	}
+
+	// The user can only grant the permissions the user holds.
+	for _, s := range scopes {
+		if caller.HasScope(s) {
+			continue
+		}
+		allowed, err := p.Access.Allowed(caller.Roles, s)
+		if err != nil {
+			return nil, "", nil, http.StatusInternalServerError, err
+		} else if !allowed {
+			return nil, "", nil, http.StatusBadRequest, newError(errInvalidScope, "scope "+s+" is not granted to the user")
+		}
+	}
+
+	return c, redirectURI, scopes, http.StatusOK, nil
+}
+
+// requestedScopes returns the scopes separated by spaces or every scope of
+// the client if none are requested.
+func requestedScopes(c *store.OAuthClient, scope string) ([]string, *oauthError) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = c.Scopes()
+	}
+
+	for _, s := range scopes {
+		if !c.AllowsScope(s) {
+			return nil, newError(errInvalidScope, "scope "+s+" is not allowed for the client")
+		}
+	}
+
+	return scopes, nil
+}
+
+// redirectTo returns the redirect URI with the parameters added to the query.
+func redirectTo(redirectURI string, params url.Values) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && len(v[0]) > 0 {
+			q.Set(k, v[0])
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// authenticateClient returns the client from the HTTP basic credentials or
+// from the form. A public client only sends the client ID.
+func (p *Endpoint) authenticateClient(w http.ResponseWriter, r *http.Request, clientID, clientSecret string) (*store.OAuthClient, int, error) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		// The credentials are form encoded before they are encoded in the
+		// header.
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = clientID, clientSecret
+	}
+
+	invalid := func() (*store.OAuthClient, int, error) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		return nil, http.StatusUnauthorized, newError(errInvalidClient, "client authentication failed")
+	}
+
+	if len(id) == 0 {
+		return invalid()
+	}
+
+	// Create the DB store.
+	c := store.NewOAuthClient(p.DB, p.Q)
+
+	exists, err := c.FindOneByID(c, id)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	} else if !exists {
+		return invalid()
+	}
+
+	if c.Confidential() {
+		if !c.MatchSecret(secret) {
+			return invalid()
+		}
+	} else if len(secret) > 0 {
+		return invalid()
+	}
+
+	return c, http.StatusOK, nil
+}
+
+// verifyChallenge returns true if the S256 hash of the verifier matches the
+// PKCE challenge.
+func verifyChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// respondTokens will write a new access token for the client and, if the
+// client acts for a user, a new refresh token in the family. The refresh
+// token keeps the refresh scope even if the access token has fewer scopes.
+// The family is recorded as a session of the user so the user can see and
+// revoke the grant.
+func (p *Endpoint) respondTokens(w http.ResponseWriter, r *http.Request, clientID, userID, familyID string,
+	scopes []string, refreshScope string) (int, error) {
+	subject := userID
+	if len(subject) == 0 {
+		subject = clientID
+	}
+
+	// Set the token ID so the token can be revoked.
+	tokenID, err := securegen.UUID()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	t, err := p.Token.Generate(webtoken.Claims{
+		ID:        tokenID,
+		Subject:   subject,
+		ClientID:  clientID,
+		Scopes:    scopes,
+		SessionID: familyID,
+	}, p.Auth.AccessTokenDuration())
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	resp := new(model.OAuthTokenResponse)
+	resp.Body.AccessToken = t
+	resp.Body.TokenType = "Bearer"
+	resp.Body.ExpiresIn = int(p.Auth.AccessTokenDuration().Seconds())
+	resp.Body.Scope = strings.Join(scopes, " ")
+
+	if len(userID) > 0 {
+		rt := store.NewOAuthRefreshToken(p.DB, p.Q)
+		resp.Body.RefreshToken, err = rt.Create(clientID, userID, familyID,
+			refreshScope, p.Auth.RefreshTokenDuration())
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		us := store.NewUserSession(p.DB, p.Q)
+		err = us.Save(familyID, userID, clientID, r.UserAgent(), throttle.ClientIP(r), p.Auth.RefreshTokenDuration())
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	return p.Response.JSON(w, resp.Body)
+}
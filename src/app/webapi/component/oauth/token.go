@@ -0,0 +1,205 @@
+package oauth
+
+import (
+	"net/http"
+	"strings"
+
+	"app/webapi/store"
+)
+
+// Exchange .
+// swagger:route POST /oauth/token oauth OAuthToken
+//
+// Exchange a grant for an access token. The client_credentials,
+// authorization_code, and refresh_token grants are supported. A confidential
+// client authenticates with HTTP basic authentication or the client_id and
+// client_secret fields.
+//
+// Responses:
+//   200: OAuthTokenResponse
+//   400: OAuthErrorResponse
+//   401: OAuthErrorResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Exchange(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters OAuthToken
+	type request struct {
+		// in: formData
+		// Required: true
+		GrantType string `json:"grant_type" validate:"required"`
+		// in: formData
+		Code string `json:"code"`
+		// in: formData
+		RedirectURI string `json:"redirect_uri"`
+		// in: formData
+		CodeVerifier string `json:"code_verifier"`
+		// in: formData
+		RefreshToken string `json:"refresh_token"`
+		// in: formData
+		Scope string `json:"scope"`
+		// in: formData
+		ClientID string `json:"client_id"`
+		// in: formData
+		ClientSecret string `json:"client_secret"`
+	}
+
+	// The tokens must not be cached.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, newError(errInvalidRequest, err.Error())
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, newError(errInvalidRequest, "grant_type is required")
+	}
+
+	c, status, err := p.authenticateClient(w, r, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return status, err
+	}
+
+	switch req.GrantType {
+	case "client_credentials":
+		return p.clientCredentials(w, r, c, req.Scope)
+	case "authorization_code":
+		return p.authorizationCode(w, r, c, req.Code, req.RedirectURI, req.CodeVerifier)
+	case "refresh_token":
+		return p.refreshToken(w, r, c, req.RefreshToken, req.Scope)
+	}
+
+	return http.StatusBadRequest, newError(errUnsupportedGrantType, "grant_type is not supported")
+}
+
+// clientCredentials will issue an access token to a confidential client that
+// acts for itself.
+func (p *Endpoint) clientCredentials(w http.ResponseWriter, r *http.Request, c *store.OAuthClient, scope string) (int, error) {
+	if !c.Confidential() {
+		return http.StatusBadRequest, newError(errUnauthorizedClient, "a public client cannot use the client_credentials grant")
+	}
+
+	scopes, oerr := requestedScopes(c, scope)
+	if oerr != nil {
+		return http.StatusBadRequest, oerr
+	}
+
+	return p.respondTokens(w, r, c.ID, "", "", scopes, "")
+}
+
+// authorizationCode will exchange an authorization code for tokens. The
+// tokens from a code that is used twice are revoked because the code was
+// stolen or replayed.
+func (p *Endpoint) authorizationCode(w http.ResponseWriter, r *http.Request, c *store.OAuthClient, code, redirectURI, verifier string) (int, error) {
+	if len(code) == 0 {
+		return http.StatusBadRequest, newError(errInvalidRequest, "code is required")
+	}
+
+	// Create the DB store.
+	oc := store.NewOAuthCode(p.DB, p.Q)
+
+	exists, err := oc.FindOneByCode(code)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists || oc.ClientID != c.ID {
+		return http.StatusBadRequest, newError(errInvalidGrant, "code is invalid")
+	}
+
+	rt := store.NewOAuthRefreshToken(p.DB, p.Q)
+
+	// The family of the refresh tokens is the ID of the code.
+	if oc.UsedAt != nil {
+		if err = rt.RevokeFamily(oc.ID); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusBadRequest, newError(errInvalidGrant, "code is invalid")
+	}
+
+	if oc.RedirectURI != redirectURI {
+		return http.StatusBadRequest, newError(errInvalidGrant, "redirect_uri does not match")
+	} else if len(oc.CodeChallenge) > 0 && !verifyChallenge(oc.CodeChallenge, verifier) {
+		return http.StatusBadRequest, newError(errInvalidGrant, "code_verifier is invalid")
+	}
+
+	// If another request exchanged the code first, treat it as reuse.
+	affected, err := oc.MarkUsed(oc.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		if err = rt.RevokeFamily(oc.ID); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusBadRequest, newError(errInvalidGrant, "code is invalid")
+	}
+
+	return p.respondTokens(w, r, c.ID, oc.UserID, oc.ID, strings.Fields(oc.Scope), oc.Scope)
+}
+
+// refreshToken will exchange a refresh token for new tokens in the same
+// family. The scope can be narrowed but not widened. A user that was deleted
+// or deactivated cannot be refreshed.
+func (p *Endpoint) refreshToken(w http.ResponseWriter, r *http.Request, c *store.OAuthClient, token, scope string) (int, error) {
+	if len(token) == 0 {
+		return http.StatusBadRequest, newError(errInvalidRequest, "refresh_token is required")
+	}
+
+	// Create the DB store.
+	rt := store.NewOAuthRefreshToken(p.DB, p.Q)
+
+	exists, err := rt.FindOneByToken(token)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists || rt.ClientID != c.ID {
+		return http.StatusBadRequest, newError(errInvalidGrant, "refresh_token is invalid")
+	}
+
+	// A token that was already exchanged has been stolen or replayed so
+	// revoke every token in the family.
+	if rt.Used() {
+		if err = rt.RevokeFamily(rt.FamilyID); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusBadRequest, newError(errInvalidGrant, "refresh_token is invalid")
+	}
+
+	// The user must still exist and be active.
+	u := store.NewUser(p.DB, p.Q)
+	exists, err = u.FindOneByID(u, rt.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists || u.StatusID != store.StatusActive {
+		return http.StatusBadRequest, newError(errInvalidGrant, "refresh_token is invalid")
+	}
+
+	scopes := strings.Fields(rt.Scope)
+	if len(strings.Fields(scope)) > 0 {
+		scopes = strings.Fields(scope)
+		for _, s := range scopes {
+			if !hasScope(rt.Scope, s) {
+				return http.StatusBadRequest, newError(errInvalidScope, "scope "+s+" was not granted")
+			}
+		}
+	}
+
+	// If another request exchanged the token first, treat it as reuse.
+	affected, err := rt.MarkUsed(rt.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if affected < 1 {
+		if err = rt.RevokeFamily(rt.FamilyID); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusBadRequest, newError(errInvalidGrant, "refresh_token is invalid")
+	}
+
+	return p.respondTokens(w, r, c.ID, rt.UserID, rt.FamilyID, scopes, rt.Scope)
+}
+
+// hasScope returns true if the scope is in the scopes separated by spaces.
+func hasScope(scopes, scope string) bool {
+	for _, v := range strings.Fields(scopes) {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/pkg/webtoken"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenClientCredentials(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, secret, err := c.Create("Partner", "user:read role:read", "", true, "")
+	assert.Nil(t, err)
+
+	var claims webtoken.Claims
+	m.Token.GenerateFunc = func(c webtoken.Claims, duration time.Duration) (string, error) {
+		claims = c
+		return "token", nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", "user:read")
+
+	w := testrequest.SendFormBasicAuth(t, core, clientID, secret, "POST", "/oauth/token", form)
+
+	r := new(model.OAuthTokenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "token", r.Body.AccessToken)
+	assert.Equal(t, "Bearer", r.Body.TokenType)
+	assert.Equal(t, "user:read", r.Body.Scope)
+	assert.Empty(t, r.Body.RefreshToken)
+	assert.Equal(t, clientID, claims.Subject)
+	assert.Equal(t, clientID, claims.ClientID)
+	assert.Equal(t, []string{"user:read"}, claims.Scopes)
+	assert.Empty(t, claims.Roles)
+
+	// The credentials can be in the form as well.
+	form.Set("client_id", clientID)
+	form.Set("client_secret", secret)
+	w = testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A scope that is not allowed for the client.
+	form.Set("scope", "role:assign")
+	w = testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+	re := new(model.OAuthErrorResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_scope", re.Body.Error)
+
+	// A grant that is not supported.
+	form.Set("grant_type", "password")
+	w = testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+	re = new(model.OAuthErrorResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "unsupported_grant_type", re.Body.Error)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTokenInvalidClient(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+	publicID, _, err := c.Create("Mobile", "user:read", "https://app.example.com/callback", false, "")
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+
+	for _, v := range []struct {
+		id     string
+		secret string
+	}{
+		{clientID, "wrong"},
+		{clientID, ""},
+		{"unknown", "secret"},
+		{publicID, "secret"},
+	} {
+		w := testrequest.SendFormBasicAuth(t, core, v.id, v.secret, "POST", "/oauth/token", form)
+
+		re := new(model.OAuthErrorResponse)
+		err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "invalid_client", re.Body.Error)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	}
+
+	// A public client cannot use the client credentials grant.
+	form.Set("client_id", publicID)
+	w := testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+	re := new(model.OAuthErrorResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "unauthorized_client", re.Body.Error)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTokenAuthorizationCode(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Mobile", "user:read",
+		"https://app.example.com/callback", false, "")
+	assert.Nil(t, err)
+
+	var claims webtoken.Claims
+	m.Token.GenerateFunc = func(c webtoken.Claims, duration time.Duration) (string, error) {
+		claims = c
+		return "token", nil
+	}
+
+	code := authorizeCode(t, core, userID, clientID)
+	assert.NotEmpty(t, code)
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", clientID)
+	form.Set("code", code)
+	form.Set("redirect_uri", "https://app.example.com/callback")
+
+	// The code verifier is required.
+	w := testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+	re := new(model.OAuthErrorResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_grant", re.Body.Error)
+
+	form.Set("code_verifier", verifier)
+	w = testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+
+	r := new(model.OAuthTokenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "token", r.Body.AccessToken)
+	assert.Equal(t, "user:read", r.Body.Scope)
+	assert.NotEmpty(t, r.Body.RefreshToken)
+	assert.Equal(t, userID, claims.Subject)
+	assert.Equal(t, clientID, claims.ClientID)
+	assert.Equal(t, []string{"user:read"}, claims.Scopes)
+
+	// A code that is used twice revokes the refresh tokens.
+	w = testrequest.SendForm(t, core, "POST", "/oauth/token", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	rt := store.NewOAuthRefreshToken(core.DB, core.Q)
+	found, err := rt.FindOneByToken(r.Body.RefreshToken)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTokenRefresh(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(userID, store.StatusActive))
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, secret, err := c.Create("Partner", "user:read user:update", "", true, "")
+	assert.Nil(t, err)
+	otherID, otherSecret, err := c.Create("Other", "user:read", "", true, "")
+	assert.Nil(t, err)
+
+	rt := store.NewOAuthRefreshToken(core.DB, core.Q)
+	token, err := rt.Create(clientID, userID, "family", "user:read user:update", time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", token)
+
+	// The token belongs to another client.
+	w := testrequest.SendFormBasicAuth(t, core, otherID, otherSecret, "POST", "/oauth/token", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The scope can be narrowed.
+	form.Set("scope", "user:read")
+	w = testrequest.SendFormBasicAuth(t, core, clientID, secret, "POST", "/oauth/token", form)
+
+	r := new(model.OAuthTokenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user:read", r.Body.Scope)
+	assert.NotEmpty(t, r.Body.RefreshToken)
+	assert.NotEqual(t, token, r.Body.RefreshToken)
+
+	// The new token is in the same family with the original scope.
+	found, err := rt.FindOneByToken(r.Body.RefreshToken)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "family", rt.FamilyID)
+	assert.Equal(t, "user:read user:update", rt.Scope)
+
+	// The family is a session of the user for the client.
+	us := store.NewUserSession(core.DB, core.Q)
+	found, err = us.FindOneActiveByUser("family", userID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, clientID, us.ClientID)
+
+	// A reused token revokes the family.
+	w = testrequest.SendFormBasicAuth(t, core, clientID, secret, "POST", "/oauth/token", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	found, err = rt.FindOneByToken(r.Body.RefreshToken)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestTokenRefreshUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, secret, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+
+	rt := store.NewOAuthRefreshToken(core.DB, core.Q)
+	token, err := rt.Create(clientID, userID, "family", "user:read", time.Hour)
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", token)
+
+	// The user is not active.
+	w := testrequest.SendFormBasicAuth(t, core, clientID, secret, "POST", "/oauth/token", form)
+	re := new(model.OAuthErrorResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_grant", re.Body.Error)
+
+	// The user was deleted.
+	assert.Nil(t, u.UpdateStatus(userID, store.StatusActive))
+	_, err = u.DeleteOneByID(u, userID)
+	assert.Nil(t, err)
+
+	w = testrequest.SendFormBasicAuth(t, core, clientID, secret, "POST", "/oauth/token", form)
+	err = json.Unmarshal(w.Body.Bytes(), &re.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_grant", re.Body.Error)
+
+	testutil.TeardownDatabase(unique)
+}
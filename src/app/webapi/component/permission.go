@@ -0,0 +1,48 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"app/webapi/internal/principal"
+	"app/webapi/pkg/router"
+)
+
+// Permissions that can be required by a route. The permissions of each role
+// are stored in the role_permission table.
+const (
+	PermissionUserRead      = "user:read"
+	PermissionUserUpdate    = "user:update"
+	PermissionUserDelete    = "user:delete"
+	PermissionUserDeleteAll = "user:delete_all"
+	PermissionUserUnlock    = "user:unlock"
+	PermissionRoleRead      = "role:read"
+	PermissionRoleAssign    = "role:assign"
+	PermissionOAuthClient   = "oauth:client"
+	PermissionIntrospect    = "token:introspect"
+	PermissionUserRestore   = "user:restore"
+)
+
+// Require returns a handler that only calls the handler if the caller was
+// granted the permission as a scope or one of the roles of the caller is
+// granted the permission.
+func (c Core) Require(permission string, fn router.Handler) router.Handler {
+	return func(w http.ResponseWriter, r *http.Request) (int, error) {
+		caller, ok := principal.FromRequest(r)
+		if !ok {
+			return http.StatusUnauthorized, errors.New("authorization token is missing")
+		} else if caller.HasScope(permission) {
+			return fn(w, r)
+		}
+
+		allowed, err := c.Access.Allowed(caller.Roles, permission)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if !allowed {
+			return http.StatusForbidden, fmt.Errorf("permission %v is required", permission)
+		}
+
+		return fn(w, r)
+	}
+}
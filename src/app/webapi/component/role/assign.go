@@ -0,0 +1,70 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// Assign .
+// swagger:route POST /v1/user/{user_id}/role role UserRoleAssign
+//
+// Assign a role to a user. The role is added to the tokens that are issued
+// after the change.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Assign(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserRoleAssign
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+		// in: formData
+		// Required: true
+		Role string `json:"role" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB stores.
+	u := store.NewUser(p.DB, p.Q)
+	role := store.NewRole(p.DB, p.Q)
+
+	// Determine if the user exists.
+	exists, err := u.ExistsByID(u, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user not found")
+	}
+
+	// Determine if the role exists.
+	exists, _, err = role.ExistsByField(role, "name", req.Role)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("role not found")
+	}
+
+	// Assign the role.
+	if err = role.Assign(req.UserID, req.Role); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "role assigned")
+}
@@ -0,0 +1,121 @@
+package role_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssign(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	form := url.Values{}
+	form.Add("role", "admin")
+
+	w := testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+ID+"/role", form)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "role assigned", r.Body.Message)
+
+	// Assigning the role again is not an error.
+	w = testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+ID+"/role", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = testrequest.SendFormAs(t, core, admin, "GET", "/v1/user/"+ID+"/role", nil)
+
+	rs := new(model.UserRoleIndexResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &rs.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"admin"}, rs.Body.Data)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestAssignNotFound(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	for _, v := range []struct {
+		userID  string
+		role    string
+		message string
+	}{
+		{"1", "admin", "user not found"},
+		{ID, "unknown", "role not found"},
+	} {
+		form := url.Values{}
+		form.Add("role", v.role)
+
+		w := testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+v.userID+"/role", form)
+
+		r := new(model.BadRequestResponse)
+		err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, v.message, r.Body.Message)
+	}
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestAssignForbidden(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("role", "admin")
+
+	// A user cannot make themselves an admin.
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "POST", "/v1/user/"+ID+"/role", form)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	roles, err := store.NewRole(core.DB, core.Q).NamesByUserID(ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(roles))
+
+	testutil.TeardownDatabase(unique)
+}
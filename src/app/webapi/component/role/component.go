@@ -0,0 +1,25 @@
+package role
+
+import (
+	"app/webapi/component"
+)
+
+// New returns a new instance of the endpoint.
+func New(bc component.Core) *Endpoint {
+	return &Endpoint{
+		Core: bc,
+	}
+}
+
+// Endpoint contains the dependencies.
+type Endpoint struct {
+	component.Core
+}
+
+// Routes will set up the endpoints.
+func (p *Endpoint) Routes(router component.IRouter) {
+	router.Get("/v1/role", p.Require(component.PermissionRoleRead, p.Index))
+	router.Get("/v1/user/:user_id/role", p.Require(component.PermissionRoleRead, p.UserIndex))
+	router.Post("/v1/user/:user_id/role", p.Require(component.PermissionRoleAssign, p.Assign))
+	router.Delete("/v1/user/:user_id/role/:role", p.Require(component.PermissionRoleAssign, p.Unassign))
+}
@@ -0,0 +1,52 @@
+package role
+
+import (
+	"net/http"
+
+	"app/webapi/model"
+	"app/webapi/pkg/structcopy"
+	"app/webapi/store"
+)
+
+// Index .
+// swagger:route GET /v1/role role RoleIndex
+//
+// Return all roles.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: RoleIndexResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Index(w http.ResponseWriter, r *http.Request) (int, error) {
+	// Create the DB store.
+	role := store.NewRole(p.DB, p.Q)
+
+	// Get all items.
+	results := make(store.RoleGroup, 0)
+	_, err := role.FindAll(&results)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Copy the items to the JSON model.
+	arr := make([]model.RoleIndexResponseData, 0)
+	for _, v := range results {
+		item := new(model.RoleIndexResponseData)
+		err = structcopy.ByTag(&v, "db", item, "json")
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		arr = append(arr, *item)
+	}
+
+	// Send the response.
+	resp := new(model.RoleIndexResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data = arr
+	return p.Response.JSON(w, resp.Body)
+}
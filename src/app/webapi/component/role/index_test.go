@@ -0,0 +1,57 @@
+package role_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}, "GET", "/v1/role", nil)
+
+	r := new(model.RoleIndexResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "OK", r.Body.Status)
+	assert.Equal(t, 2, len(r.Body.Data))
+	assert.Equal(t, "admin", r.Body.Data[0].Name)
+	assert.Equal(t, "user", r.Body.Data[1].Name)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestIndexForbidden(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/role", nil)
+
+	r := new(model.ForbiddenResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Forbidden", r.Body.Status)
+	assert.Equal(t, "permission role:read is required", r.Body.Message)
+
+	testutil.TeardownDatabase(unique)
+}
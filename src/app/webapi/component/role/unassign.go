@@ -0,0 +1,56 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// Unassign .
+// swagger:route DELETE /v1/user/{user_id}/role/{role} role UserRoleUnassign
+//
+// Remove a role from a user. The role is removed from the tokens that are
+// issued after the change.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Unassign(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserRoleUnassign
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+		// in: path
+		// x-example: admin
+		Role string `json:"role" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	role := store.NewRole(p.DB, p.Q)
+
+	// Remove the role.
+	count, err := role.Unassign(req.UserID, req.Role)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if count < 1 {
+		return http.StatusBadRequest, errors.New("user does not have the role")
+	}
+
+	return p.Response.OK(w, "role removed")
+}
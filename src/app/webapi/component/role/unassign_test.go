@@ -0,0 +1,59 @@
+package role_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnassign(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	role := store.NewRole(core.DB, core.Q)
+	assert.Nil(t, role.Assign(ID, "user"))
+	assert.Nil(t, role.Assign(ID, "admin"))
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	w := testrequest.SendFormAs(t, core, admin, "DELETE", "/v1/user/"+ID+"/role/admin", nil)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "role removed", r.Body.Message)
+
+	roles, err := role.NamesByUserID(ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user"}, roles)
+
+	// The role cannot be removed twice.
+	w = testrequest.SendFormAs(t, core, admin, "DELETE", "/v1/user/"+ID+"/role/admin", nil)
+
+	rb := new(model.BadRequestResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &rb.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "user does not have the role", rb.Body.Message)
+
+	testutil.TeardownDatabase(unique)
+}
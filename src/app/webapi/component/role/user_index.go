@@ -0,0 +1,63 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/model"
+	"app/webapi/store"
+)
+
+// UserIndex .
+// swagger:route GET /v1/user/{user_id}/role role UserRoleIndex
+//
+// Return the roles of a user.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: UserRoleIndexResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) UserIndex(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserRoleIndex
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Determine if the user exists.
+	exists, err := u.ExistsByID(u, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user not found")
+	}
+
+	// Get the roles of the user.
+	names, err := store.NewRole(p.DB, p.Q).NamesByUserID(req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Send the response.
+	resp := new(model.UserRoleIndexResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data = names
+	return p.Response.JSON(w, resp.Body)
+}
@@ -19,9 +19,15 @@ type Endpoint struct {
 // Routes will set up the endpoints.
 func (p *Endpoint) Routes(router component.IRouter) {
 	router.Post("/v1/user", p.Create)
-	router.Get("/v1/user/:user_id", p.Show)
-	router.Get("/v1/user", p.Index)
-	router.Put("/v1/user/:user_id", p.Update)
-	router.Delete("/v1/user/:user_id", p.Destroy)
-	router.Delete("/v1/user", p.DestroyAll)
+	router.Get("/v1/user/:user_id", p.Require(component.PermissionUserRead, p.Show))
+	router.Get("/v1/user", p.Require(component.PermissionUserRead, p.Index))
+	router.Put("/v1/user/:user_id", p.Require(component.PermissionUserUpdate, p.Update))
+	router.Patch("/v1/user/:user_id", p.Require(component.PermissionUserUpdate, p.Patch))
+	router.Delete("/v1/user/:user_id", p.Require(component.PermissionUserDelete, p.Destroy))
+	router.Delete("/v1/user", p.Require(component.PermissionUserDeleteAll, p.DestroyAll))
+	router.Post("/v1/user/:user_id/unlock", p.Require(component.PermissionUserUnlock, p.Unlock))
+	router.Post("/v1/user/:user_id/restore", p.Require(component.PermissionUserRestore, p.Restore))
+	router.Post("/v1/user/:user_id/password", p.Require(component.PermissionUserUpdate, p.PasswordChange))
+	router.Get("/v1/user/:user_id/sessions", p.Require(component.PermissionUserRead, p.SessionIndex))
+	router.Delete("/v1/user/:user_id/sessions/:session_id", p.Require(component.PermissionUserUpdate, p.SessionDestroy))
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"app/webapi/internal/principal"
 	"app/webapi/store"
 )
 
@@ -34,7 +35,7 @@ func (p *Endpoint) Create(w http.ResponseWriter, r *http.Request) (int, error) {
 		Email string `json:"email" validate:"required,email"`
 		// in: formData
 		// Required: true
-		Password string `json:"password" validate:"required"`
+		Password string `json:"password" validate:"required,password=FirstName LastName Email"`
 	}
 
 	// Request validation.
@@ -48,8 +49,8 @@ func (p *Endpoint) Create(w http.ResponseWriter, r *http.Request) (int, error) {
 	// Create the DB store.
 	u := store.NewUser(p.DB, p.Q)
 
-	// Check for existing item.
-	exists, _, err := u.ExistsByField(u, "email", req.Email)
+	// Check for existing item, including deleted users that can be restored.
+	exists, _, err := p.Q.WithDeleted().ExistsByField(u, "email", req.Email)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	} else if exists {
@@ -68,5 +69,16 @@ func (p *Endpoint) Create(w http.ResponseWriter, r *http.Request) (int, error) {
 		return http.StatusInternalServerError, err
 	}
 
+	// Give the user the default role.
+	role := store.NewRole(p.DB, p.Q)
+	if err = role.Assign(ID, principal.RoleUser); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Send the token that activates the user.
+	if err = p.sendVerification(ID, req.Email); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	return p.Response.Created(w, ID)
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -18,7 +19,15 @@ import (
 
 func TestCreate(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
-	core, _ := component.NewCoreMock(db)
+	core, m := component.NewCoreMock(db)
+
+	mailTo := ""
+	mailBody := ""
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		mailTo = to
+		mailBody = body
+		return nil
+	}
 
 	form := url.Values{}
 	form.Add("first_name", "John")
@@ -36,6 +45,27 @@ func TestCreate(t *testing.T) {
 	assert.Equal(t, "Created", r.Body.Status)
 	assert.Equal(t, 36, len(r.Body.RecordID))
 
+	// The user is given the default role.
+	roles, err := store.NewRole(core.DB, core.Q).NamesByUserID(r.Body.RecordID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user"}, roles)
+
+	// The user is inactive until the email is verified.
+	u := store.NewUser(core.DB, core.Q)
+	found, err := u.FindOneByID(u, r.Body.RecordID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, store.StatusInactive, u.StatusID)
+
+	// The verification token is emailed to the user.
+	assert.Equal(t, "jsmith@example.com", mailTo)
+	token := regexp.MustCompile(`[A-Za-z0-9_-]{43}`).FindString(mailBody)
+	ut := store.NewUserToken(core.DB, core.Q)
+	found, err = ut.FindOneByToken(token, store.TokenEmailVerify)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, r.Body.RecordID, ut.UserID)
+
 	testutil.TeardownDatabase(unique)
 }
 
@@ -89,6 +119,39 @@ func TestCreateBadEmail(t *testing.T) {
 	testutil.TeardownDatabase(unique)
 }
 
+func TestCreateWeakPassword(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	for password, message := range map[string]string{
+		"a":            "password must be at least 8 characters",
+		"smith-family": "password must not contain your name or email address",
+	} {
+		form := url.Values{}
+		form.Add("first_name", "John")
+		form.Add("last_name", "Smith")
+		form.Add("email", "jsmith@example.com")
+		form.Add("password", password)
+
+		w := testrequest.SendForm(t, core, "POST", "/v1/user", form)
+
+		r := new(model.BadRequestResponse)
+		err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, message, r.Body.Message)
+	}
+
+	// The user is not created.
+	u := store.NewUser(core.DB, core.Q)
+	exists, _, err := u.ExistsByField(u, "email", "jsmith@example.com")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	testutil.TeardownDatabase(unique)
+}
+
 func TestCreateValidation(t *testing.T) {
 	for _, v := range []string{
 		"POST /v1/user",
@@ -3,6 +3,7 @@ package user
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"app/webapi/store"
 )
@@ -10,7 +11,8 @@ import (
 // Destroy .
 // swagger:route DELETE /v1/user/{user_id} user UserDestroy
 //
-// Delete a user.
+// Delete a user. The user is kept until it is purged so an admin can restore
+// it. Every token of the user is revoked.
 //
 // Security:
 //   token:
@@ -19,6 +21,7 @@ import (
 //   200: OKResponse
 //   400: BadRequestResponse
 //   401: UnauthorizedResponse
+//   403: ForbiddenResponse
 //   500: InternalServerErrorResponse
 func (p *Endpoint) Destroy(w http.ResponseWriter, r *http.Request) (int, error) {
 	// swagger:parameters UserDestroy
@@ -36,6 +39,11 @@ func (p *Endpoint) Destroy(w http.ResponseWriter, r *http.Request) (int, error)
 		return http.StatusBadRequest, err
 	}
 
+	// Only allow users to change themselves unless they are an admin.
+	if status, err := authorizeSelf(r, req.UserID); err != nil {
+		return status, err
+	}
+
 	// Create the DB store.
 	u := store.NewUser(p.DB, p.Q)
 
@@ -47,5 +55,16 @@ func (p *Endpoint) Destroy(w http.ResponseWriter, r *http.Request) (int, error)
 		return http.StatusBadRequest, errors.New("user does not exist")
 	}
 
+	// Revoke the sessions and the access tokens of the user.
+	err = p.revokeSessions(req.UserID, "")
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = p.Revocation.RevokeUser(req.UserID, time.Now())
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	return p.Response.OK(w, "user deleted")
 }
@@ -3,6 +3,7 @@ package user
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"app/webapi/store"
 )
@@ -10,7 +11,7 @@ import (
 // DestroyAll .
 // swagger:route DELETE /v1/user user UserDestroyAll
 //
-// Delete all users.
+// Delete all users. Every token of the users is revoked.
 //
 // Security:
 //   token:
@@ -19,11 +20,19 @@ import (
 //   200: OKResponse
 //   400: BadRequestResponse
 //   401: UnauthorizedResponse
+//   403: ForbiddenResponse
 //   500: InternalServerErrorResponse
 func (p *Endpoint) DestroyAll(w http.ResponseWriter, r *http.Request) (int, error) {
 	// Create the DB store.
 	u := store.NewUser(p.DB, p.Q)
 
+	// Get the users before they are deleted to revoke their tokens.
+	group := u.NewGroup()
+	_, err := u.FindAll(group)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
 	// Delete all items.
 	count, err := u.DeleteAll(u)
 	if err != nil {
@@ -32,5 +41,18 @@ func (p *Endpoint) DestroyAll(w http.ResponseWriter, r *http.Request) (int, erro
 		return http.StatusBadRequest, errors.New("no users to delete")
 	}
 
+	// Revoke the sessions and the access tokens of the users.
+	for _, v := range *group {
+		err = p.revokeSessions(v.ID, "")
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		err = p.Revocation.RevokeUser(v.ID, time.Now())
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
 	return p.Response.OK(w, "users deleted")
 }
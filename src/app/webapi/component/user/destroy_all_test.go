@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"app/webapi/component"
+	"app/webapi/internal/principal"
 	"app/webapi/internal/testrequest"
 	"app/webapi/internal/testutil"
 	"app/webapi/model"
@@ -20,10 +22,20 @@ func TestDestroyAll(t *testing.T) {
 	core, _ := component.NewCoreMock(db)
 
 	u := store.NewUser(core.DB, core.Q)
-	_, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
 	assert.Nil(t, err)
 
-	w := testrequest.SendForm(t, core, "DELETE", "/v1/user", nil)
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+	ort := store.NewOAuthRefreshToken(core.DB, core.Q)
+	oauthRefresh, err := ort.Create(clientID, ID, "grant", "user:read", time.Hour)
+	assert.Nil(t, err)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}, "DELETE", "/v1/user", nil)
 
 	r := new(model.OKResponse)
 	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -33,6 +45,16 @@ func TestDestroyAll(t *testing.T) {
 	assert.Equal(t, "OK", r.Body.Status)
 	assert.Equal(t, "users deleted", r.Body.Message)
 
+	revoked, err := core.Revocation.IsUserRevoked(ID, time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// The OAuth refresh tokens are revoked.
+	found, err := ort.FindOneByToken(oauthRefresh)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, ort.Used())
+
 	testutil.TeardownDatabase(unique)
 }
 
@@ -40,7 +62,10 @@ func TestDestroyAllNoUsers(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
 	core, _ := component.NewCoreMock(db)
 
-	w := testrequest.SendForm(t, core, "DELETE", "/v1/user", nil)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}, "DELETE", "/v1/user", nil)
 
 	r := new(model.BadRequestResponse)
 	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -62,10 +87,41 @@ func TestDestroyValidation(t *testing.T) {
 
 		arr := strings.Split(v, " ")
 
-		w := testrequest.SendForm(t, core, arr[0], arr[1], nil)
+		w := testrequest.SendFormAs(t, core, &principal.Principal{
+			UserID: "admin",
+			Roles:  []string{principal.RoleAdmin},
+		}, arr[0], arr[1], nil)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 
 		testutil.TeardownDatabase(unique)
 	}
 }
+
+func TestDestroyAllForbidden(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "DELETE", "/v1/user", nil)
+
+	r := new(model.ForbiddenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Forbidden", r.Body.Status)
+	assert.Equal(t, "permission user:delete_all is required", r.Body.Message)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+
+	testutil.TeardownDatabase(unique)
+}
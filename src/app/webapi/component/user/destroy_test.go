@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"app/webapi/component"
+	"app/webapi/internal/principal"
 	"app/webapi/internal/testrequest"
 	"app/webapi/internal/testutil"
 	"app/webapi/model"
@@ -23,13 +25,23 @@ func TestDestroy(t *testing.T) {
 	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
 	assert.Nil(t, err)
 
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+	ort := store.NewOAuthRefreshToken(core.DB, core.Q)
+	oauthRefresh, err := ort.Create(clientID, ID, "grant", "user:read", time.Hour)
+	assert.Nil(t, err)
+
 	form := url.Values{}
 	form.Add("first_name", "John")
 	form.Add("last_name", "Smith")
 	form.Add("email", "jsmith@example.com")
 	form.Add("password", "password")
 
-	w := testrequest.SendForm(t, core, "DELETE", "/v1/user/"+ID, form)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "DELETE", "/v1/user/"+ID, form)
 
 	r := new(model.OKResponse)
 	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -43,5 +55,53 @@ func TestDestroy(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, found)
 
+	// The user is kept until it is purged.
+	found, err = core.Q.WithDeleted().FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.NotNil(t, u.DeletedAt)
+
+	revoked, err := core.Revocation.IsUserRevoked(ID, time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// The OAuth refresh tokens are revoked.
+	found, err = ort.FindOneByToken(oauthRefresh)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, ort.Used())
+
+	// The email cannot be used by a new user until the user is purged.
+	w = testrequest.SendForm(t, core, "POST", "/v1/user", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "user already exists")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestDestroyOtherUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "other",
+		Roles:  []string{principal.RoleUser},
+	}, "DELETE", "/v1/user/"+ID, nil)
+
+	r := new(model.ForbiddenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Forbidden", r.Body.Status)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+
 	testutil.TeardownDatabase(unique)
 }
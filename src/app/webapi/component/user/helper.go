@@ -0,0 +1,204 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"app/webapi/internal/principal"
+	"app/webapi/model"
+	"app/webapi/store"
+)
+
+// authorizeSelf returns an error status if the caller is not the user and is
+// not an admin.
+func authorizeSelf(r *http.Request, userID string) (int, error) {
+	caller, ok := principal.FromRequest(r)
+	if !ok {
+		return http.StatusUnauthorized, errors.New("authorization token is missing")
+	} else if caller.UserID != userID && !caller.IsAdmin() {
+		return http.StatusForbidden, errors.New("you can only change your own user")
+	}
+
+	return http.StatusOK, nil
+}
+
+// revokeSessions will revoke every session of a user except one, with the
+// refresh tokens and the access tokens issued in them. The except ID is empty
+// to revoke every session.
+func (p *Endpoint) revokeSessions(userID, exceptID string) error {
+	us := store.NewUserSession(p.DB, p.Q)
+	sessions := us.NewGroup()
+	err := us.FindAllActiveByUser(sessions, userID)
+	if err != nil {
+		return err
+	}
+
+	// The access tokens of a session expire within the access token duration.
+	expiresAt := time.Now().Add(p.Auth.AccessTokenDuration())
+	for _, v := range *sessions {
+		if v.ID == exceptID {
+			continue
+		}
+		if err = p.Revocation.Revoke(v.ID, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	// Revoke the refresh tokens so no new access tokens can be issued.
+	err = store.NewRefreshToken(p.DB, p.Q).RevokeUserExceptFamily(userID, exceptID)
+	if err != nil {
+		return err
+	}
+
+	err = store.NewOAuthRefreshToken(p.DB, p.Q).RevokeUserExceptFamily(userID, exceptID)
+	if err != nil {
+		return err
+	}
+
+	return us.RevokeUserExcept(userID, exceptID)
+}
+
+// changeEmail will store the new email of a user as pending and email a
+// token to the new address. The email is only changed once the token is
+// verified so the user can still log in and reset the password with the old
+// address. Tokens sent for an earlier change can no longer be used.
+func (p *Endpoint) changeEmail(userID, email string) error {
+	ut := store.NewUserToken(p.DB, p.Q)
+	err := ut.MarkAllUsed(userID, store.TokenEmailChange)
+	if err != nil {
+		return err
+	}
+
+	err = store.NewUser(p.DB, p.Q).UpdatePendingEmail(userID, email)
+	if err != nil {
+		return err
+	}
+
+	duration := p.Auth.EmailVerifyDuration()
+	token, err := ut.Create(userID, store.TokenEmailChange, duration)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Use the following token within %v hours to "+
+		"verify your new email address:\n\n"+
+		"%v%v\n",
+		int(duration.Hours()), p.Auth.EmailVerifyURL, token)
+
+	return p.Mail.Send(email, "Verify your new email address", body)
+}
+
+// sendVerification will email a token to the user that verifies the email
+// address and activates the user.
+func (p *Endpoint) sendVerification(userID, email string) error {
+	duration := p.Auth.EmailVerifyDuration()
+	ut := store.NewUserToken(p.DB, p.Q)
+	token, err := ut.Create(userID, store.TokenEmailVerify, duration)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Welcome! Use the following token within %v hours to "+
+		"verify your email address and activate your account:\n\n"+
+		"%v%v\n",
+		int(duration.Hours()), p.Auth.EmailVerifyURL, token)
+
+	return p.Mail.Send(email, "Verify your email address", body)
+}
+
+// positiveInt returns the number in the string or the default if the string
+// is empty. The number is limited to the max if the max is not 0.
+func positiveInt(s string, def, max int) (int, error) {
+	if len(s) == 0 {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errors.New("must be a positive number")
+	} else if max > 0 && n > max {
+		return max, nil
+	}
+
+	return n, nil
+}
+
+// parseDate returns the time of a date, like 2019-01-31, or of a time in the
+// RFC 3339 format.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, errors.New("must be a date like 2019-01-31 or 2019-01-31T15:04:05Z")
+	}
+	return t, nil
+}
+
+// newPageMeta returns the metadata of a page.
+func newPageMeta(total, page, perPage int) model.PageMeta {
+	return model.PageMeta{
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+}
+
+// pageLinks returns the Link header with the first, previous, next, and last
+// pages of a list. The other parameters of the request are kept.
+func pageLinks(u *url.URL, meta model.PageMeta) string {
+	link := func(page int, rel string) string {
+		v := u.Query()
+		v.Set("page", strconv.Itoa(page))
+		v.Set("per_page", strconv.Itoa(meta.PerPage))
+		return fmt.Sprintf(`<%v?%v>; rel="%v"`, u.Path, v.Encode(), rel)
+	}
+
+	links := make([]string, 0, 4)
+	if meta.TotalPages > 0 {
+		links = append(links, link(1, "first"))
+	}
+	if meta.Page > 1 && meta.Page <= meta.TotalPages {
+		links = append(links, link(meta.Page-1, "prev"))
+	}
+	if meta.Page < meta.TotalPages {
+		links = append(links, link(meta.Page+1, "next"))
+	}
+	if meta.TotalPages > 0 {
+		links = append(links, link(meta.TotalPages, "last"))
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// cursorLinks returns the Link header value of the first, previous, and next
+// pages of a list that is read with cursors.
+func cursorLinks(u *url.URL, meta model.CursorMeta) string {
+	link := func(param, cursor, rel string) string {
+		v := u.Query()
+		v.Del("after")
+		v.Del("before")
+		v.Del("page")
+		v.Set(param, cursor)
+		v.Set("per_page", strconv.Itoa(meta.PerPage))
+		return fmt.Sprintf(`<%v?%v>; rel="%v"`, u.Path, v.Encode(), rel)
+	}
+
+	links := []string{link("after", "", "first")}
+	if len(meta.PrevCursor) > 0 {
+		links = append(links, link("before", meta.PrevCursor, "prev"))
+	}
+	if len(meta.NextCursor) > 0 {
+		links = append(links, link("after", meta.NextCursor, "next"))
+	}
+
+	return strings.Join(links, ", ")
+}
@@ -1,17 +1,42 @@
 package user
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"app/webapi/model"
+	"app/webapi/pkg/query"
 	"app/webapi/pkg/structcopy"
 	"app/webapi/store"
 )
 
+// Page sizes of the user list.
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	// maxPage keeps the offset of a page from overflowing.
+	maxPage = 1000000
+)
+
+// sortFields are the fields the user list can be sorted by.
+var sortFields = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+	"status_id":  true,
+	"created_at": true,
+	"updated_at": true,
+}
+
 // Index .
 // swagger:route GET /v1/user user UserIndex
 //
-// Return all users.
+// Return a page of users. The Link header contains the URLs of the first,
+// previous, next, and last pages. Pass the after or before parameter to page
+// with cursors instead of page numbers, an empty after starts at the first
+// page. Cursor pages stay consistent when users are added or removed.
 //
 // Security:
 //   token:
@@ -20,16 +45,146 @@ import (
 //   200: UserIndexResponse
 //   400: BadRequestResponse
 //   401: UnauthorizedResponse
+//   403: ForbiddenResponse
 //   500: InternalServerErrorResponse
 func (p *Endpoint) Index(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserIndex
+	type request struct {
+		// Page is the page number, starting at 1.
+		//
+		// in: query
+		Page string `json:"page" validate:"omitempty,numeric"`
+		// After is the cursor of the next page.
+		//
+		// in: query
+		After string `json:"after"`
+		// Before is the cursor of the previous page.
+		//
+		// in: query
+		Before string `json:"before"`
+		// PerPage is the number of users per page, defaults to 20 with a
+		// maximum of 100.
+		//
+		// in: query
+		PerPage string `json:"per_page" validate:"omitempty,numeric"`
+		// Sort is the field to sort by, like created_at. Prefix it with a
+		// dash to sort in descending order.
+		//
+		// in: query
+		Sort string `json:"sort"`
+		// in: query
+		StatusID string `json:"status_id" validate:"omitempty,numeric"`
+		// Email only returns users with an email that contains the value.
+		//
+		// in: query
+		Email string `json:"email"`
+		// CreatedAfter only returns users created at or after the date, like
+		// 2019-01-31 or 2019-01-31T15:04:05Z.
+		//
+		// in: query
+		CreatedAfter string `json:"created_after"`
+		// CreatedBefore only returns users created before the date.
+		//
+		// in: query
+		CreatedBefore string `json:"created_before"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	page, err := positiveInt(req.Page, 1, 0)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("page %v", err)
+	} else if page > maxPage {
+		return http.StatusBadRequest, fmt.Errorf("page must be at most %v, use the after parameter to page further", maxPage)
+	}
+
+	perPage, err := positiveInt(req.PerPage, defaultPerPage, maxPerPage)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("per_page %v", err)
+	}
+
+	// Build the filters of the query.
+	sort := strings.TrimPrefix(req.Sort, "-")
+	desc := strings.HasPrefix(req.Sort, "-")
+	if len(sort) > 0 && !sortFields[sort] {
+		return http.StatusBadRequest, errors.New("sort must be one of: created_at, email, first_name, last_name, status_id, updated_at")
+	}
+
+	filters := make([]query.Filter, 0)
+	if len(req.StatusID) > 0 {
+		filters = append(filters, query.Filter{Field: "status_id", Operator: "=", Value: req.StatusID})
+	}
+	if len(req.Email) > 0 {
+		filters = append(filters, query.Filter{Field: "email", Operator: "LIKE",
+			Value: "%" + query.EscapeLike(req.Email) + "%"})
+	}
+	if len(req.CreatedAfter) > 0 {
+		t, err := parseDate(req.CreatedAfter)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("created_after %v", err)
+		}
+		filters = append(filters, query.Filter{Field: "created_at", Operator: ">=", Value: t})
+	}
+	if len(req.CreatedBefore) > 0 {
+		t, err := parseDate(req.CreatedBefore)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("created_before %v", err)
+		}
+		filters = append(filters, query.Filter{Field: "created_at", Operator: "<", Value: t})
+	}
+
 	// Create the DB store.
 	u := store.NewUser(p.DB, p.Q)
 
-	// Get all items.
+	// Get the items of the page.
+	resp := new(model.UserIndexResponse)
 	results := make(store.UserGroup, 0)
-	_, err := u.FindAll(&results)
-	if err != nil {
-		return http.StatusInternalServerError, err
+	var links string
+	params := r.URL.Query()
+	_, after := params["after"]
+	_, before := params["before"]
+	if after || before {
+		cursors, err := u.FindCursor(&results, query.CursorPage{
+			Filters: filters,
+			Sort:    sort,
+			Desc:    desc,
+			Limit:   perPage,
+			After:   req.After,
+			Before:  req.Before,
+		})
+		if err == query.ErrCursorInvalid {
+			return http.StatusBadRequest, err
+		} else if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		resp.Body.Cursor = &model.CursorMeta{
+			PerPage:    perPage,
+			NextCursor: cursors.Next,
+			PrevCursor: cursors.Prev,
+		}
+		links = cursorLinks(r.URL, *resp.Body.Cursor)
+	} else {
+		total, err := u.FindPage(&results, query.Page{
+			Filters: filters,
+			Sort:    sort,
+			Desc:    desc,
+			Limit:   perPage,
+			Offset:  (page - 1) * perPage,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		meta := newPageMeta(total, page, perPage)
+		resp.Body.Meta = &meta
+		links = pageLinks(r.URL, meta)
 	}
 
 	// Copy the items to the JSON model.
@@ -43,8 +198,12 @@ func (p *Endpoint) Index(w http.ResponseWriter, r *http.Request) (int, error) {
 		arr = append(arr, *item)
 	}
 
+	// Link to the other pages.
+	if len(links) > 0 {
+		w.Header().Set("Link", links)
+	}
+
 	// Send the response.
-	resp := new(model.UserIndexResponse)
 	resp.Body.Status = http.StatusText(http.StatusOK)
 	resp.Body.Data = arr
 	return p.Response.JSON(w, resp.Body)
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"app/webapi/component"
+	"app/webapi/internal/principal"
 	"app/webapi/internal/testrequest"
 	"app/webapi/internal/testutil"
 	"app/webapi/model"
@@ -18,7 +19,10 @@ func TestIndexEmpty(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
 	core, _ := component.NewCoreMock(db)
 
-	w := testrequest.SendForm(t, core, "GET", "/v1/user", nil)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/user", nil)
 
 	r := new(model.UserIndexResponse)
 	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -39,7 +43,10 @@ func TestIndexOne(t *testing.T) {
 	_, err := u.Create("John", "Smith", "jsmith@example.com", "password")
 	assert.Nil(t, err)
 
-	w := testrequest.SendForm(t, core, "GET", "/v1/user", nil)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/user", nil)
 
 	r := new(model.UserIndexResponse)
 	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -53,3 +60,221 @@ func TestIndexOne(t *testing.T) {
 
 	testutil.TeardownDatabase(unique)
 }
+
+func TestIndexNoRole(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{UserID: "1"},
+		"GET", "/v1/user", nil)
+
+	r := new(model.ForbiddenResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Forbidden", r.Body.Status)
+	assert.Equal(t, "permission user:read is required", r.Body.Message)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestIndexScope(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	// An API key can be granted the permission as a scope.
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "1",
+		KeyID:  "1",
+		Scopes: []string{component.PermissionUserRead},
+	}, "GET", "/v1/user", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestIndexPage(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	for _, v := range []string{"c", "a", "b"} {
+		ID, err := u.Create("John", "Smith", v+"@example.com", "password")
+		assert.Nil(t, err)
+		if v == "b" {
+			assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+		}
+	}
+
+	p := &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}
+
+	w := testrequest.SendFormAs(t, core, p, "GET", "/v1/user?per_page=2&sort=-email", nil)
+
+	r := new(model.UserIndexResponse)
+	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(r.Body.Data))
+	assert.Equal(t, "c@example.com", r.Body.Data[0].Email)
+	assert.Equal(t, "b@example.com", r.Body.Data[1].Email)
+	assert.Equal(t, 3, r.Body.Meta.Total)
+	assert.Equal(t, 1, r.Body.Meta.Page)
+	assert.Equal(t, 2, r.Body.Meta.PerPage)
+	assert.Equal(t, 2, r.Body.Meta.TotalPages)
+	assert.Equal(t, `</v1/user?page=1&per_page=2&sort=-email>; rel="first", `+
+		`</v1/user?page=2&per_page=2&sort=-email>; rel="next", `+
+		`</v1/user?page=2&per_page=2&sort=-email>; rel="last"`, w.Header().Get("Link"))
+
+	// The last page.
+	w = testrequest.SendFormAs(t, core, p, "GET", "/v1/user?page=2&per_page=2&sort=-email", nil)
+	r = new(model.UserIndexResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(r.Body.Data))
+	assert.Equal(t, "a@example.com", r.Body.Data[0].Email)
+	assert.Contains(t, w.Header().Get("Link"), `rel="prev"`)
+	assert.NotContains(t, w.Header().Get("Link"), `rel="next"`)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestIndexFilter(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+	_, err = u.Create("Jane", "Doe", "jdoe@example.com", "password")
+	assert.Nil(t, err)
+	_, err = u.Create("Jim", "Smith", "j_smith@example.com", "password")
+	assert.Nil(t, err)
+
+	p := &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}
+
+	for _, v := range []struct {
+		query string
+		total int
+	}{
+		{"status_id=1", 1},
+		{"status_id=2", 2},
+		{"email=smith", 2},
+		{"email=j_", 1}, // The underscore is not a wildcard.
+		{"email=smith&status_id=2", 1},
+		{"created_after=2000-01-01", 3},
+		{"created_before=2000-01-01T00:00:00Z", 0},
+	} {
+		w := testrequest.SendFormAs(t, core, p, "GET", "/v1/user?"+v.query, nil)
+
+		r := new(model.UserIndexResponse)
+		err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusOK, w.Code, v.query)
+		assert.Equal(t, v.total, r.Body.Meta.Total, v.query)
+		assert.Equal(t, v.total, len(r.Body.Data), v.query)
+	}
+
+	// Invalid parameters.
+	for _, v := range []string{
+		"sort=password",
+		"page=0",
+		"page=1000001",
+		"page=9223372036854775807",
+		"page=99999999999999999999",
+		"per_page=abc",
+		"created_after=yesterday",
+	} {
+		w := testrequest.SendFormAs(t, core, p, "GET", "/v1/user?"+v, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, v)
+	}
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestIndexCursor(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	// Every user has the same last name so the order depends on the ID.
+	u := store.NewUser(core.DB, core.Q)
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		_, err := u.Create("John", "Smith", v+"@example.com", "password")
+		assert.Nil(t, err)
+	}
+
+	p := &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}
+
+	get := func(query string) *model.UserIndexResponse {
+		w := testrequest.SendFormAs(t, core, p, "GET", "/v1/user?per_page=2&sort=-last_name&"+query, nil)
+		assert.Equal(t, http.StatusOK, w.Code, query)
+
+		r := new(model.UserIndexResponse)
+		err := json.Unmarshal(w.Body.Bytes(), &r.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, r.Body.Meta)
+		return r
+	}
+
+	// Read every page forward.
+	pages := make([][]string, 0)
+	r := get("after=")
+	assert.Equal(t, "", r.Body.Cursor.PrevCursor)
+	for {
+		emails := make([]string, 0)
+		for _, v := range r.Body.Data {
+			emails = append(emails, v.Email)
+		}
+		pages = append(pages, emails)
+
+		if len(r.Body.Cursor.NextCursor) == 0 {
+			break
+		}
+		r = get("after=" + r.Body.Cursor.NextCursor)
+	}
+
+	assert.Equal(t, 3, len(pages))
+	seen := make(map[string]bool)
+	for _, page := range pages {
+		for _, v := range page {
+			assert.False(t, seen[v], v)
+			seen[v] = true
+		}
+	}
+	assert.Equal(t, 5, len(seen))
+
+	// Read the pages backward from the last page.
+	for i := len(pages) - 2; i >= 0; i-- {
+		assert.NotEqual(t, "", r.Body.Cursor.PrevCursor)
+		r = get("before=" + r.Body.Cursor.PrevCursor)
+		assert.Equal(t, len(pages[i]), len(r.Body.Data))
+		for j, v := range r.Body.Data {
+			assert.Equal(t, pages[i][j], v.Email)
+		}
+	}
+	assert.Equal(t, "", r.Body.Cursor.PrevCursor)
+
+	// The cursor only works with the same sort.
+	r = get("after=")
+	w := testrequest.SendFormAs(t, core, p, "GET", "/v1/user?per_page=2&sort=email&after="+r.Body.Cursor.NextCursor, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The cursor must be signed.
+	w = testrequest.SendFormAs(t, core, p, "GET", "/v1/user?after=abc.def", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
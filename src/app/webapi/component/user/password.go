@@ -0,0 +1,114 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/internal/principal"
+	"app/webapi/internal/throttle"
+	"app/webapi/store"
+)
+
+// PasswordChange .
+// swagger:route POST /v1/user/{user_id}/password user UserPasswordChange
+//
+// Change the password of a user. The current password is required and every
+// other session of the user is revoked.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   423: LockedResponse
+//   429: TooManyRequestsResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) PasswordChange(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserPasswordChange
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+		// in: formData
+		// Required: true
+		CurrentPassword string `json:"current_password" validate:"required"`
+		// in: formData
+		// Required: true
+		Password string `json:"password" validate:"required,password"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Only allow users to change themselves unless they are an admin.
+	if status, err := authorizeSelf(r, req.UserID); err != nil {
+		return status, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Get the item by ID.
+	exists, err := u.FindOneByID(u, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user not found")
+	}
+
+	// Reject the guess early while the account or IP address is blocked.
+	// Wrong passwords count against the logins of the account so a stolen
+	// access token cannot be used to guess the password.
+	ip := throttle.ClientIP(r)
+	lt := throttle.New(p.DB, p.Q, p.Auth)
+	if status, err := lt.Blocked(w, u.Email, ip); err != nil {
+		return status, err
+	}
+
+	// Ensure the caller knows the current password.
+	if !p.Password.MatchString(u.Password, req.CurrentPassword) {
+		if err = lt.Failed(u.ID, u.Email, ip); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusBadRequest, errors.New("current password is incorrect")
+	}
+
+	// Ensure the password does not contain the name or email of the user.
+	if err = p.Bind.ValidatePassword(req.Password, u.FirstName, u.LastName, u.Email); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Encrypt the password.
+	hash, err := p.Password.HashString(req.Password)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Change the password.
+	err = u.UpdatePassword(u.ID, hash)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Keep the session of the caller if the caller is the user.
+	sessionID := ""
+	if caller, ok := principal.FromRequest(r); ok && caller.UserID == u.ID {
+		sessionID = caller.SessionID
+	}
+
+	// Revoke the other sessions.
+	err = p.revokeSessions(u.ID, sessionID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "password changed")
+}
@@ -0,0 +1,180 @@
+package user_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordChange(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+
+	// The user is logged in on two devices.
+	us := store.NewUserSession(core.DB, core.Q)
+	assert.Nil(t, us.Save("family1", ID, "", "", "", time.Hour))
+	assert.Nil(t, us.Save("family2", ID, "", "", "", time.Hour))
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	token1, err := rt.Create(ID, "family1", time.Hour)
+	assert.Nil(t, err)
+	token2, err := rt.Create(ID, "family2", time.Hour)
+	assert.Nil(t, err)
+
+	// The user also granted access to an OAuth client.
+	c := store.NewOAuthClient(core.DB, core.Q)
+	clientID, _, err := c.Create("Partner", "user:read", "", true, "")
+	assert.Nil(t, err)
+	assert.Nil(t, us.Save("family3", ID, clientID, "", "", time.Hour))
+
+	ort := store.NewOAuthRefreshToken(core.DB, core.Q)
+	token3, err := ort.Create(clientID, ID, "family3", "user:read", time.Hour)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{
+		UserID:    ID,
+		TokenID:   "jti1",
+		SessionID: "family1",
+		Roles:     []string{principal.RoleUser},
+	}
+
+	for _, v := range []struct {
+		current  string
+		password string
+		message  string
+	}{
+		{"", "correct horse battery staple", "failed"},
+		{"wrong", "correct horse battery staple", "current password is incorrect"},
+		{"password", "short", "password must be at least 8 characters"},
+		{"password", "johnsmith123", "password must not contain your name or email address"},
+	} {
+		form := url.Values{}
+		form.Add("current_password", v.current)
+		form.Add("password", v.password)
+
+		w := testrequest.SendFormAs(t, core, p, "POST", "/v1/user/"+ID+"/password", form)
+		assert.Equal(t, http.StatusBadRequest, w.Code, v.message)
+		assert.Contains(t, w.Body.String(), v.message)
+	}
+
+	form := url.Values{}
+	form.Add("current_password", "password")
+	form.Add("password", "correct horse battery staple")
+
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/user/"+ID+"/password", form)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "password changed", r.Body.Message)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, core.Password.MatchString(u.Password, "correct horse battery staple"))
+
+	// The session of the caller is kept.
+	revoked, err := core.Revocation.IsRevoked("family1")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	found, err = rt.FindOneByToken(token1)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.False(t, rt.Used())
+
+	// The other session is revoked.
+	revoked, err = core.Revocation.IsRevoked("family2")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	found, err = rt.FindOneByToken(token2)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	// The OAuth grant is revoked.
+	revoked, err = core.Revocation.IsRevoked("family3")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	found, err = ort.FindOneByToken(token3)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, ort.Used())
+
+	group := us.NewGroup()
+	assert.Nil(t, us.FindAllActiveByUser(group, ID))
+	assert.Equal(t, 1, len(*group))
+	assert.Equal(t, "family1", (*group)[0].ID)
+
+	// Another user cannot change the password.
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "other",
+		Roles:  []string{principal.RoleUser},
+	}, "POST", "/v1/user/"+ID+"/password", form)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPasswordChangeThrottle(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+	core.Auth.LoginBackoffAfter = 2
+
+	password, err := core.Password.HashString("password")
+	assert.Nil(t, err)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", password)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}
+
+	form := url.Values{}
+	form.Add("current_password", "wrong")
+	form.Add("password", "correct horse battery staple")
+
+	for i := 0; i < 2; i++ {
+		w := testrequest.SendFormAs(t, core, p, "POST", "/v1/user/"+ID+"/password", form)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	// The wrong passwords count against the logins of the account so the
+	// next guess is delayed, even with the correct password.
+	form.Set("current_password", "password")
+	w := testrequest.SendFormAs(t, core, p, "POST", "/v1/user/"+ID+"/password", form)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, core.Password.MatchString(u.Password, "password"))
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,93 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// Patch .
+// swagger:route PATCH /v1/user/{user_id} user UserPatch
+//
+// Make changes to only the fields of a user that are sent. A field that is
+// sent empty is invalid, not cleared. A new email is only used once the
+// token sent to it is verified.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Patch(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserPatch
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+		// in: formData
+		FirstName *string `json:"first_name" validate:"omitempty,min=1"`
+		// in: formData
+		LastName *string `json:"last_name" validate:"omitempty,min=1"`
+		// in: formData
+		Email *string `json:"email" validate:"omitempty,email"`
+	}
+
+	// Request validation. The fields that are not sent are nil.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	} else if req.FirstName == nil && req.LastName == nil && req.Email == nil {
+		return http.StatusBadRequest, errors.New("no fields to update")
+	}
+
+	// Only allow users to change themselves unless they are an admin.
+	if status, err := authorizeSelf(r, req.UserID); err != nil {
+		return status, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Get the item by ID.
+	exists, err := u.FindOneByID(u, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user not found")
+	}
+
+	// Ensure no other user has the new email, including deleted users.
+	if req.Email != nil && *req.Email != u.Email {
+		exists, ID, err := p.Q.WithDeleted().ExistsByField(u, "email", *req.Email)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if exists && ID != u.ID {
+			return http.StatusBadRequest, errors.New("email is already in use")
+		}
+	}
+
+	// Update the fields that were sent.
+	err = u.UpdateFields(u.ID, store.UserChanges{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// A new email must be verified before it is used.
+	if req.Email != nil && *req.Email != u.Email {
+		if err = p.changeEmail(u.ID, *req.Email); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	return p.Response.OK(w, "user updated")
+}
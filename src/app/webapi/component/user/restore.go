@@ -0,0 +1,52 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/store"
+)
+
+// Restore .
+// swagger:route POST /v1/user/{user_id}/restore user UserRestore
+//
+// Restore a user that was deleted and has not been purged yet.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Restore(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserRestore
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Restore the item.
+	count, err := u.Restore(u, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if count < 1 {
+		return http.StatusBadRequest, errors.New("user is not deleted")
+	}
+
+	return p.Response.OK(w, "user restored")
+}
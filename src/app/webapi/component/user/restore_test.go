@@ -0,0 +1,62 @@
+package user_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestore(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	// A user that is not deleted cannot be restored.
+	w := testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+ID+"/restore", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "user is not deleted")
+
+	count, err := u.DeleteOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// A user cannot restore a user.
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "POST", "/v1/user/"+ID+"/restore", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+ID+"/restore", nil)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user restored", r.Body.Message)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Nil(t, u.DeletedAt)
+
+	testutil.TeardownDatabase(unique)
+}
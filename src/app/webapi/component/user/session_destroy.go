@@ -0,0 +1,84 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"app/webapi/store"
+)
+
+// SessionDestroy .
+// swagger:route DELETE /v1/user/{user_id}/sessions/{session_id} user UserSessionDestroy
+//
+// Revoke a session of a user with the refresh token and the access tokens
+// that were issued in it.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) SessionDestroy(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserSessionDestroy
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+		// in: path
+		// x-example: SESSIONID
+		SessionID string `json:"session_id" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Only allow users to change themselves unless they are an admin.
+	if status, err := authorizeSelf(r, req.UserID); err != nil {
+		return status, err
+	}
+
+	// Create the DB store.
+	us := store.NewUserSession(p.DB, p.Q)
+
+	// Determine if the item exists.
+	exists, err := us.FindOneActiveByUser(req.SessionID, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("session does not exist")
+	}
+
+	// Revoke the access tokens of the session until they expire.
+	err = p.Revocation.Revoke(us.ID, time.Now().Add(p.Auth.AccessTokenDuration()))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Revoke the refresh tokens so no new access tokens can be issued.
+	err = store.NewRefreshToken(p.DB, p.Q).RevokeFamily(us.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = store.NewOAuthRefreshToken(p.DB, p.Q).RevokeFamily(us.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	err = us.RevokeOne(us.ID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "session revoked")
+}
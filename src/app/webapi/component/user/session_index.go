@@ -0,0 +1,82 @@
+package user
+
+import (
+	"net/http"
+
+	"app/webapi/model"
+	"app/webapi/store"
+)
+
+// SessionIndex .
+// swagger:route GET /v1/user/{user_id}/sessions user UserSessionIndex
+//
+// List the sessions of a user that are not revoked or expired. A session
+// lasts as long as its refresh token.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: UserSessionIndexResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) SessionIndex(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserSessionIndex
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Only allow users to see their own sessions unless they are an admin.
+	if status, err := authorizeSelf(r, req.UserID); err != nil {
+		return status, err
+	}
+
+	// Create the DB store.
+	us := store.NewUserSession(p.DB, p.Q)
+
+	// Get all items.
+	results := us.NewGroup()
+	err := us.FindAllActiveByUser(results, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// Copy the items to the JSON model.
+	arr := make([]model.UserSessionIndexResponseData, 0)
+	for _, v := range *results {
+		item := model.UserSessionIndexResponseData{
+			ID:        v.ID,
+			ClientID:  v.ClientID,
+			UserAgent: v.UserAgent,
+			IPAddress: v.IPAddress,
+		}
+		if v.CreatedAt != nil {
+			item.CreatedAt = *v.CreatedAt
+		}
+		if v.LastSeenAt != nil {
+			item.LastSeenAt = *v.LastSeenAt
+		}
+		if v.ExpiresAt != nil {
+			item.ExpiresAt = *v.ExpiresAt
+		}
+		arr = append(arr, item)
+	}
+
+	// Send the response.
+	resp := new(model.UserSessionIndexResponse)
+	resp.Body.Status = http.StatusText(http.StatusOK)
+	resp.Body.Data = arr
+	return p.Response.JSON(w, resp.Body)
+}
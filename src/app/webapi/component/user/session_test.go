@@ -0,0 +1,133 @@
+package user_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionIndex(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	us := store.NewUserSession(core.DB, core.Q)
+	assert.Nil(t, us.Save("family1", ID, "", "curl/7.64.1", "192.0.2.1", time.Hour))
+	assert.Nil(t, us.Save("family2", ID, "", "curl/7.64.1", "192.0.2.2", time.Hour))
+	assert.Nil(t, us.Save("family3", ID, "", "curl/7.64.1", "192.0.2.3", time.Hour))
+	assert.Nil(t, us.RevokeOne("family3"))
+	assert.Nil(t, us.Save("family4", ID, "", "curl/7.64.1", "192.0.2.4", -time.Minute))
+
+	// A session is listed until the refresh token expires, long after the
+	// last access token expired.
+	_, err = core.DB.Exec(`UPDATE user_session
+		SET last_seen_at = DATE_SUB(NOW(), INTERVAL 30 MINUTE)
+		WHERE id = ?`, "family1")
+	assert.Nil(t, err)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/user/"+ID+"/sessions", nil)
+
+	r := new(model.UserSessionIndexResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	// Only the sessions that are not revoked or expired are listed, most
+	// recently used first.
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(r.Body.Data))
+	assert.Equal(t, "family2", r.Body.Data[0].ID)
+	assert.Equal(t, "family1", r.Body.Data[1].ID)
+	for _, v := range r.Body.Data {
+		assert.Equal(t, "curl/7.64.1", v.UserAgent)
+		assert.True(t, v.ExpiresAt.After(v.LastSeenAt))
+	}
+
+	// Another user cannot see the sessions.
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "other",
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/user/"+ID+"/sessions", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestSessionDestroy(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	// The user is logged in on two devices.
+	us := store.NewUserSession(core.DB, core.Q)
+	assert.Nil(t, us.Save("family1", ID, "", "", "", time.Hour))
+	assert.Nil(t, us.Save("family2", ID, "", "", "", time.Hour))
+
+	rt := store.NewRefreshToken(core.DB, core.Q)
+	token, err := rt.Create(ID, "family1", time.Hour)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}
+
+	w := testrequest.SendFormAs(t, core, p, "DELETE", "/v1/user/"+ID+"/sessions/family1", nil)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "session revoked", r.Body.Message)
+
+	// The access tokens issued in the session are revoked.
+	revoked, err := core.Revocation.IsRevoked("family1")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = core.Revocation.IsRevoked("family2")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	// The refresh token is revoked.
+	found, err := rt.FindOneByToken(token)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, rt.Used())
+
+	group := us.NewGroup()
+	assert.Nil(t, us.FindAllActiveByUser(group, ID))
+	assert.Equal(t, 1, len(*group))
+
+	// A revoked session cannot be revoked again.
+	w = testrequest.SendFormAs(t, core, p, "DELETE", "/v1/user/"+ID+"/sessions/family1", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A user cannot revoke the session of another user.
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "other",
+		Roles:  []string{principal.RoleUser},
+	}, "DELETE", "/v1/user/"+ID+"/sessions/family2", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
@@ -25,6 +25,7 @@ import (
 //   200: UserShowResponse
 //   400: BadRequestResponse
 //   401: UnauthorizedResponse
+//   403: ForbiddenResponse
 //   500: InternalServerErrorResponse
 func (p *Endpoint) Show(w http.ResponseWriter, r *http.Request) (int, error) {
 	// swagger:parameters UserShow
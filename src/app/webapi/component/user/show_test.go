@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"app/webapi/component"
+	"app/webapi/internal/principal"
 	"app/webapi/internal/testrequest"
 	"app/webapi/internal/testutil"
 	"app/webapi/model"
@@ -22,7 +23,10 @@ func TestShowOne(t *testing.T) {
 	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
 	assert.Nil(t, err)
 
-	w := testrequest.SendForm(t, core, "GET", "/v1/user/"+ID, nil)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/user/"+ID, nil)
 
 	r := new(model.UserShowResponse)
 	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -42,7 +46,10 @@ func TestShowNotFound(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
 	core, _ := component.NewCoreMock(db)
 
-	w := testrequest.SendForm(t, core, "GET", "/v1/user/1", nil)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}, "GET", "/v1/user/1", nil)
 
 	r := new(model.BadRequestResponse)
 	err := json.Unmarshal(w.Body.Bytes(), &r.Body)
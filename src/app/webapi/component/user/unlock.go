@@ -0,0 +1,70 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"app/webapi/internal/principal"
+	"app/webapi/store"
+)
+
+// Unlock .
+// swagger:route POST /v1/user/{user_id}/unlock user UserUnlock
+//
+// Unlock a user that is locked after too many failed logins and forget the
+// failed logins.
+//
+// Security:
+//   token:
+//
+// Responses:
+//   200: OKResponse
+//   400: BadRequestResponse
+//   401: UnauthorizedResponse
+//   403: ForbiddenResponse
+//   500: InternalServerErrorResponse
+func (p *Endpoint) Unlock(w http.ResponseWriter, r *http.Request) (int, error) {
+	// swagger:parameters UserUnlock
+	type request struct {
+		// in: path
+		// x-example: USERID
+		UserID string `json:"user_id" validate:"required"`
+	}
+
+	// Request validation.
+	req := new(request)
+	if err := p.Bind.FormUnmarshal(req, r); err != nil {
+		return http.StatusBadRequest, err
+	} else if err = p.Bind.Validate(req); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	// Create the DB store.
+	u := store.NewUser(p.DB, p.Q)
+
+	// Get the item.
+	exists, err := u.FindOneByID(u, req.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if !exists {
+		return http.StatusBadRequest, errors.New("user does not exist")
+	}
+
+	// Remove the failed logins and the lockout.
+	lt := store.NewLoginThrottle(p.DB, p.Q)
+	count, err := lt.Clear(store.ThrottleAccount, u.Email)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	} else if count < 1 {
+		return p.Response.OK(w, "user is not locked")
+	}
+
+	// Record who unlocked the user.
+	caller, _ := principal.FromRequest(r)
+	le := store.NewLockoutEvent(p.DB, p.Q)
+	if _, err = le.Unlocked(u.ID, u.Email, caller.UserID); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.Response.OK(w, "user unlocked")
+}
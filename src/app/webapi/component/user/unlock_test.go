@@ -0,0 +1,83 @@
+package user_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/internal/principal"
+	"app/webapi/internal/testrequest"
+	"app/webapi/internal/testutil"
+	"app/webapi/model"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnlock(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	lt := store.NewLoginThrottle(core.DB, core.Q)
+	_, err = lt.Fail(store.ThrottleAccount, "jsmith@example.com", time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, lt.Block(store.ThrottleAccount, "jsmith@example.com", time.Hour))
+
+	admin := &principal.Principal{
+		UserID: "admin",
+		Roles:  []string{principal.RoleAdmin},
+	}
+
+	w := testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+ID+"/unlock", nil)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user unlocked", r.Body.Message)
+
+	exists, err := lt.Find(store.ThrottleAccount, "jsmith@example.com")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// The unlock is recorded.
+	le := store.NewLockoutEvent(core.DB, core.Q)
+	group := le.NewGroup()
+	assert.Nil(t, le.FindAllByEmail(group, "jsmith@example.com"))
+	assert.Equal(t, 1, len(*group))
+	assert.Equal(t, store.LockoutUnlocked, (*group)[0].Event)
+	assert.Equal(t, "admin", *(*group)[0].CreatedBy)
+
+	// A user that is not locked.
+	w = testrequest.SendFormAs(t, core, admin, "POST", "/v1/user/"+ID+"/unlock", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "user is not locked")
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestUnlockForbidden(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "POST", "/v1/user/"+ID+"/unlock", nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "permission user:unlock is required")
+
+	testutil.TeardownDatabase(unique)
+}
@@ -10,7 +10,8 @@ import (
 // Update .
 // swagger:route PUT /v1/user/{user_id} user UserUpdate
 //
-// Make changes to a user.
+// Make changes to a user. Change the password with the password endpoint
+// instead. A new email is only used once the token sent to it is verified.
 //
 // Security:
 //   token:
@@ -19,6 +20,7 @@ import (
 //   200: OKResponse
 //   400: BadRequestResponse
 //   401: UnauthorizedResponse
+//   403: ForbiddenResponse
 //   500: InternalServerErrorResponse
 func (p *Endpoint) Update(w http.ResponseWriter, r *http.Request) (int, error) {
 	// swagger:parameters UserUpdate
@@ -35,9 +37,6 @@ func (p *Endpoint) Update(w http.ResponseWriter, r *http.Request) (int, error) {
 		// in: formData
 		// Required: true
 		Email string `json:"email" validate:"required"`
-		// in: formData
-		// Required: true
-		Password string `json:"password" validate:"required"`
 	}
 
 	// Request validation.
@@ -46,30 +45,48 @@ func (p *Endpoint) Update(w http.ResponseWriter, r *http.Request) (int, error) {
 		return http.StatusBadRequest, err
 	} else if err = p.Bind.Validate(req); err != nil {
 		return http.StatusBadRequest, err
+	} else if _, ok := r.Form["password"]; ok {
+		return http.StatusBadRequest, errors.New("change the password with POST /v1/user/{user_id}/password")
+	}
+
+	// Only allow users to change themselves unless they are an admin.
+	if status, err := authorizeSelf(r, req.UserID); err != nil {
+		return status, err
 	}
 
 	// Create the DB store.
 	u := store.NewUser(p.DB, p.Q)
 
-	// Determine if the item exists.
-	exists, err := u.ExistsByID(u, req.UserID)
+	// Get the item by ID.
+	exists, err := u.FindOneByID(u, req.UserID)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	} else if !exists {
 		return http.StatusBadRequest, errors.New("user not found")
 	}
 
-	// Encrypt the password.
-	password, err := p.Password.HashString(req.Password)
-	if err != nil {
-		return http.StatusInternalServerError, err
+	// Ensure no other user has the new email, including deleted users.
+	if req.Email != u.Email {
+		exists, ID, err := p.Q.WithDeleted().ExistsByField(u, "email", req.Email)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if exists && ID != u.ID {
+			return http.StatusBadRequest, errors.New("email is already in use")
+		}
 	}
 
 	// Update the item.
-	err = u.Update(u.ID, req.FirstName, req.LastName, req.Email, password)
+	err = u.Update(u.ID, req.FirstName, req.LastName)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
 
+	// A new email must be verified before it is used.
+	if req.Email != u.Email {
+		if err = p.changeEmail(u.ID, req.Email); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
 	return p.Response.OK(w, "user updated")
 }
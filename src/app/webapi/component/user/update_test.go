@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"app/webapi/component"
+	"app/webapi/internal/principal"
 	"app/webapi/internal/testrequest"
 	"app/webapi/internal/testutil"
 	"app/webapi/model"
@@ -17,19 +19,28 @@ import (
 
 func TestUpdateUserAllFields(t *testing.T) {
 	db, unique := testutil.LoadDatabase()
-	core, _ := component.NewCoreMock(db)
+	core, m := component.NewCoreMock(db)
+
+	mailTo := ""
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		mailTo = to
+		return nil
+	}
 
 	u := store.NewUser(core.DB, core.Q)
 	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
 	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
 
 	form := url.Values{}
 	form.Add("first_name", "John1")
 	form.Add("last_name", "Smith2")
 	form.Add("email", "jsmith3@example.com")
-	form.Add("password", "password4")
 
-	w := testrequest.SendForm(t, core, "PUT", "/v1/user/"+ID, form)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "PUT", "/v1/user/"+ID, form)
 
 	r := new(model.OKResponse)
 	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -44,8 +55,24 @@ func TestUpdateUserAllFields(t *testing.T) {
 	assert.True(t, found)
 	assert.Equal(t, "John1", u.FirstName)
 	assert.Equal(t, "Smith2", u.LastName)
-	assert.Equal(t, "jsmith3@example.com", u.Email)
-	assert.True(t, core.Password.MatchString(u.Password, "password4"))
+	assert.Equal(t, "password", u.Password)
+
+	// The new email is only used once it is verified and the user stays
+	// active.
+	assert.Equal(t, "jsmith@example.com", u.Email)
+	assert.Equal(t, "jsmith3@example.com", *u.PendingEmail)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+	assert.Equal(t, "jsmith3@example.com", mailTo)
+
+	// The password cannot be changed.
+	form.Add("password", "password4")
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "PUT", "/v1/user/"+ID, form)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "/password")
 
 	testutil.TeardownDatabase(unique)
 }
@@ -61,7 +88,10 @@ func TestUpdateMissingFields(t *testing.T) {
 	form := url.Values{}
 	form.Add("first_name", "John1")
 
-	w := testrequest.SendForm(t, core, "PUT", "/v1/user/"+ID, form)
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "PUT", "/v1/user/"+ID, form)
 
 	r := new(model.BadRequestResponse)
 	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
@@ -81,3 +111,209 @@ func TestUpdateMissingFields(t *testing.T) {
 
 	testutil.TeardownDatabase(unique)
 }
+
+func TestUpdateOtherUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("first_name", "John1")
+	form.Add("last_name", "Smith2")
+	form.Add("email", "jsmith3@example.com")
+
+	// A different user cannot make changes.
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "other",
+		Roles:  []string{principal.RoleUser},
+	}, "PUT", "/v1/user/"+ID, form)
+
+	r := new(model.ForbiddenResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "Forbidden", r.Body.Status)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "John", u.FirstName)
+
+	// An admin can make changes.
+	w = testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: "other",
+		Roles:  []string{principal.RoleAdmin},
+	}, "PUT", "/v1/user/"+ID, form)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, err = u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "John1", u.FirstName)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestUpdateUnauthenticated(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	form := url.Values{}
+	form.Add("first_name", "John1")
+	form.Add("last_name", "Smith2")
+	form.Add("email", "jsmith3@example.com")
+
+	w := testrequest.SendForm(t, core, "PUT", "/v1/user/1", form)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPatchOneField(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	form := url.Values{}
+	form.Add("last_name", "Smith2")
+
+	w := testrequest.SendFormAs(t, core, &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}, "PATCH", "/v1/user/"+ID, form)
+
+	r := new(model.OKResponse)
+	err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user updated", r.Body.Message)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "John", u.FirstName)
+	assert.Equal(t, "Smith2", u.LastName)
+	assert.Equal(t, "jsmith@example.com", u.Email)
+	assert.Equal(t, "password", u.Password)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPatchEmail(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, m := component.NewCoreMock(db)
+
+	mailTo := ""
+	m.Mail.SendFunc = func(to, subject, body string) error {
+		mailTo = to
+		return nil
+	}
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	assert.Nil(t, u.UpdateStatus(ID, store.StatusActive))
+	_, err = u.Create("Jane", "Smith", "jane@example.com", "password")
+	assert.Nil(t, err)
+
+	// A token sent for an earlier change cannot be used.
+	ut := store.NewUserToken(core.DB, core.Q)
+	old, err := ut.Create(ID, store.TokenEmailChange, time.Hour)
+	assert.Nil(t, err)
+
+	p := &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}
+
+	// The email of another user cannot be used.
+	form := url.Values{}
+	form.Add("email", "jane@example.com")
+	w := testrequest.SendFormAs(t, core, p, "PATCH", "/v1/user/"+ID, form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "email is already in use")
+
+	// The same email does not need to be verified again.
+	form.Set("email", "jsmith@example.com")
+	w = testrequest.SendFormAs(t, core, p, "PATCH", "/v1/user/"+ID, form)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mailTo)
+
+	form.Set("email", "jsmith2@example.com")
+	w = testrequest.SendFormAs(t, core, p, "PATCH", "/v1/user/"+ID, form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "jsmith@example.com", u.Email)
+	assert.Equal(t, "jsmith2@example.com", *u.PendingEmail)
+	assert.Equal(t, store.StatusActive, u.StatusID)
+	assert.Equal(t, "jsmith2@example.com", mailTo)
+
+	found, err = ut.FindOneByToken(old, store.TokenEmailChange)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPatchInvalidFields(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+	core, _ := component.NewCoreMock(db)
+
+	u := store.NewUser(core.DB, core.Q)
+	ID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+	_, err = u.Create("Jane", "Doe", "jdoe@example.com", "password")
+	assert.Nil(t, err)
+
+	p := &principal.Principal{
+		UserID: ID,
+		Roles:  []string{principal.RoleUser},
+	}
+
+	for _, v := range []struct {
+		form    url.Values
+		message string
+	}{
+		{url.Values{}, "no fields to update"},
+		{url.Values{"first_name": {""}}, "failed"},
+		{url.Values{"email": {"jsmith"}}, "failed"},
+		{url.Values{"email": {"jdoe@example.com"}}, "email is already in use"},
+	} {
+		w := testrequest.SendFormAs(t, core, p, "PATCH", "/v1/user/"+ID, v.form)
+
+		r := new(model.BadRequestResponse)
+		err = json.Unmarshal(w.Body.Bytes(), &r.Body)
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, v.form.Encode())
+		assert.Contains(t, r.Body.Message, v.message, v.form.Encode())
+	}
+
+	// The user can keep the same email.
+	w := testrequest.SendFormAs(t, core, p, "PATCH", "/v1/user/"+ID, url.Values{
+		"first_name": {"Johnny"},
+		"email":      {"jsmith@example.com"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found, err := u.FindOneByID(u, ID)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Johnny", u.FirstName)
+	assert.Equal(t, "Smith", u.LastName)
+
+	testutil.TeardownDatabase(unique)
+}
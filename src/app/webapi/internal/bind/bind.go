@@ -3,28 +3,125 @@ package bind
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
+
+	"app/webapi/pkg/passpolicy"
 
 	"github.com/matryer/way"
 	"gopkg.in/go-playground/validator.v9"
 )
 
+// passwordTag is the validation tag that checks a password against the
+// password policy. The parameter can list the fields with the name and email
+// of the user, like: password=FirstName LastName Email.
+const passwordTag = "password"
+
 // Binder contains the request bind an validator objects.
 type Binder struct {
 	validator *validator.Validate
+	policy    *passpolicy.Policy
 }
 
-// New returns a new binder for request bind and validation.
+// New returns a new binder for request bind and validation. The password tag
+// uses the default policy until another policy is set.
 func New() *Binder {
-	return &Binder{
+	policy, _ := passpolicy.New(passpolicy.Config{})
+
+	b := &Binder{
 		validator: validator.New(),
+		policy:    policy,
 	}
+	b.validator.RegisterValidation(passwordTag, b.password)
+
+	return b
 }
 
-// Validate will validate a struct using the validator.
+// SetPasswordPolicy will set the policy of the password tag.
+func (b *Binder) SetPasswordPolicy(p *passpolicy.Policy) {
+	b.policy = p
+}
+
+// Validate will validate a struct using the validator. A password that does
+// not meet the policy returns the reason, like "password must be at least 8
+// characters".
 func (b *Binder) Validate(s interface{}) error {
-	return b.validator.Struct(s)
+	err := b.validator.Struct(s)
+	if errs, ok := err.(validator.ValidationErrors); ok {
+		for _, fe := range errs {
+			if fe.Tag() == passwordTag {
+				return b.passwordError(s, fe, err)
+			}
+		}
+	}
+
+	return err
+}
+
+// ValidatePassword will check the password against the policy. The personal
+// values, like the name and email of the user, must not be part of the
+// password.
+func (b *Binder) ValidatePassword(password string, personal ...string) error {
+	if err := b.policy.Check(password, personal...); err != nil {
+		return fmt.Errorf("password %v", err)
+	}
+	return nil
+}
+
+// password is the validation func of the password tag.
+func (b *Binder) password(fl validator.FieldLevel) bool {
+	personal := fieldValues(fl.Parent(), fl.Param())
+	return b.policy.Check(fl.Field().String(), personal...) == nil
+}
+
+// passwordError returns the reason the password field failed the policy or
+// the validation error if there is no reason.
+func (b *Binder) passwordError(s interface{}, fe validator.FieldError, verr error) error {
+	v := indirect(reflect.ValueOf(s))
+
+	name := fe.Field()
+	if f, ok := v.Type().FieldByName(fe.StructField()); ok {
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; len(tag) > 0 {
+			name = tag
+		}
+	}
+
+	password, _ := fe.Value().(string)
+	err := b.policy.Check(password, fieldValues(v, fe.Param())...)
+	if err == nil {
+		return verr
+	}
+
+	return fmt.Errorf("%v %v", name, err)
+}
+
+// fieldValues returns the string values of the space separated field names
+// in the struct.
+func fieldValues(v reflect.Value, names string) []string {
+	v = indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	values := make([]string, 0)
+	for _, name := range strings.Fields(names) {
+		f := v.FieldByName(name)
+		if f.IsValid() && f.Kind() == reflect.String {
+			values = append(values, f.String())
+		}
+	}
+
+	return values
+}
+
+// indirect returns the value that a pointer points to.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return v
 }
 
 // JSONUnmarshal will perform an unmarshal on an interface using JSON.
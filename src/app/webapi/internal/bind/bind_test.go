@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"app/webapi/internal/bind"
+	"app/webapi/pkg/passpolicy"
 	"app/webapi/pkg/router"
 
 	"github.com/stretchr/testify/assert"
@@ -101,3 +102,86 @@ func TestMissingPointer(t *testing.T) {
 
 	assert.Equal(t, true, called)
 }
+
+func TestPasswordTag(t *testing.T) {
+	type request struct {
+		FirstName string `json:"first_name" validate:"required"`
+		Email     string `json:"email" validate:"required,email"`
+		Password  string `json:"new_password" validate:"required,password=FirstName Email"`
+	}
+
+	b := bind.New()
+
+	req := &request{
+		FirstName: "John",
+		Email:     "jsmith@example.com",
+		Password:  "a",
+	}
+	assert.EqualError(t, b.Validate(req), "new_password must be at least 8 characters")
+
+	req.Password = "johnnyboy"
+	assert.EqualError(t, b.Validate(req), "new_password must not contain your name or email address")
+
+	req.Password = "JSmith-1234"
+	assert.EqualError(t, b.Validate(req), "new_password must not contain your name or email address")
+
+	req.Password = "correct horse battery staple"
+	assert.Nil(t, b.Validate(req))
+
+	// The other tags are still checked.
+	req.Email = "jsmith"
+	assert.NotNil(t, b.Validate(req))
+
+	// The policy can be changed.
+	policy, err := passpolicy.New(passpolicy.Config{
+		MinClasses: 3,
+	})
+	assert.Nil(t, err)
+	b.SetPasswordPolicy(policy)
+
+	req.Email = "jsmith@example.com"
+	assert.EqualError(t, b.Validate(req), "new_password must contain 3 of these: "+
+		"lowercase letters, uppercase letters, digits, and symbols")
+
+	assert.EqualError(t, b.ValidatePassword("Summer-2024", "summer@example.com"),
+		"password must not contain your name or email address")
+	assert.Nil(t, b.ValidatePassword("Summer-2024", "John", "Smith"))
+}
+
+func TestOptionalFields(t *testing.T) {
+	type request struct {
+		FirstName *string `json:"first_name" validate:"omitempty,min=1"`
+		LastName  *string `json:"last_name" validate:"omitempty,min=1"`
+		Email     *string `json:"email" validate:"omitempty,email"`
+	}
+
+	b := bind.New()
+
+	send := func(form url.Values) (*request, error) {
+		r := httptest.NewRequest("PATCH", "/user", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		req := new(request)
+		assert.Nil(t, b.FormUnmarshal(req, r))
+		return req, b.Validate(req)
+	}
+
+	// A field that is not sent is nil and is not validated.
+	req, err := send(url.Values{"first_name": {"john"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "john", *req.FirstName)
+	assert.Nil(t, req.LastName)
+	assert.Nil(t, req.Email)
+
+	// A field that is sent empty is validated.
+	req, err = send(url.Values{"last_name": {""}})
+	assert.NotNil(t, err)
+	assert.NotNil(t, req.LastName)
+	assert.Equal(t, "", *req.LastName)
+
+	_, err = send(url.Values{"email": {""}})
+	assert.NotNil(t, err)
+
+	_, err = send(url.Values{"email": {"jsmith"}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,171 @@
+// Package cookie stores the tokens of browser clients in cookies and protects
+// the cookies from cross-site request forgery with a double-submit token.
+package cookie
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"app/webapi/pkg/securegen"
+)
+
+const (
+	// AccessTokenName is the name of the cookie with the access token.
+	AccessTokenName = "access_token"
+	// RefreshTokenName is the name of the cookie with the refresh token.
+	RefreshTokenName = "refresh_token"
+	// CSRFTokenName is the name of the cookie with the CSRF token. The cookie
+	// can be read by scripts so the client can send it in the CSRF header.
+	CSRFTokenName = "csrf_token"
+	// CSRFHeader is the header that must match the CSRF cookie on requests
+	// that change state.
+	CSRFHeader = "X-CSRF-Token"
+	// OIDCStateName is the name of the cookie with the hash of the state of
+	// an OpenID Connect login.
+	OIDCStateName = "oidc_state"
+
+	// refreshPath is the path of the refresh token cookie so it is only sent
+	// to the auth endpoints.
+	refreshPath = "/v1/auth"
+	// oidcPath is the path of the state cookie so it is only sent to the
+	// OpenID Connect endpoints.
+	oidcPath = "/v1/auth/oidc"
+)
+
+// Config contains the cookie settings.
+type Config struct {
+	Enabled  bool   `json:"Enabled"`  // Allow browser clients to receive the tokens in cookies.
+	Domain   string `json:"Domain"`   // Domain of the cookies, defaults to the host of the request.
+	SameSite string `json:"SameSite"` // Strict, Lax, or None, defaults to Strict.
+	Insecure bool   `json:"Insecure"` // Send the cookies over HTTP, only use it in development.
+}
+
+// sameSite returns the SameSite mode of the cookies.
+func (c Config) sameSite() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+// newCookie returns a cookie with the settings of the config.
+func (c Config) newCookie(name, value, path string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   maxAge,
+		Secure:   !c.Insecure,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite(),
+	}
+}
+
+// SetTokens will write the access token and the refresh token to HttpOnly
+// cookies along with a new CSRF token. The CSRF token is returned so it can
+// also be sent in the body to clients on another domain.
+func (c Config) SetTokens(w http.ResponseWriter, access string, accessDuration time.Duration,
+	refresh string, refreshDuration time.Duration) (string, error) {
+	b, err := securegen.Bytes(32)
+	if err != nil {
+		return "", err
+	}
+	csrf := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, c.newCookie(AccessTokenName, access, "/", int(accessDuration.Seconds()), true))
+	http.SetCookie(w, c.newCookie(RefreshTokenName, refresh, refreshPath, int(refreshDuration.Seconds()), true))
+	http.SetCookie(w, c.newCookie(CSRFTokenName, csrf, "/", int(refreshDuration.Seconds()), false))
+
+	return csrf, nil
+}
+
+// Clear will remove the cookies from the client.
+func (c Config) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, c.newCookie(AccessTokenName, "", "/", -1, true))
+	http.SetCookie(w, c.newCookie(RefreshTokenName, "", refreshPath, -1, true))
+	http.SetCookie(w, c.newCookie(CSRFTokenName, "", "/", -1, false))
+}
+
+// SetOIDCState will write the hash of the state of an OpenID Connect login to
+// an HttpOnly cookie so the callback only finishes the login in the browser
+// that started it. The cookie is set even if cookies are not enabled for the
+// tokens. The provider redirects the user back from another site, so the
+// cookie is sent with a Lax SameSite mode unless the mode is None.
+func (c Config) SetOIDCState(w http.ResponseWriter, state string, duration time.Duration) {
+	oc := c.newCookie(OIDCStateName, hash(state), oidcPath, int(duration.Seconds()), true)
+	if oc.SameSite == http.SameSiteStrictMode {
+		oc.SameSite = http.SameSiteLaxMode
+	}
+	http.SetCookie(w, oc)
+}
+
+// ClearOIDCState will remove the state cookie from the client.
+func (c Config) ClearOIDCState(w http.ResponseWriter) {
+	http.SetCookie(w, c.newCookie(OIDCStateName, "", oidcPath, -1, true))
+}
+
+// ValidOIDCState returns true if the state matches the hash in the state
+// cookie.
+func ValidOIDCState(r *http.Request, state string) bool {
+	sum := value(r, OIDCStateName)
+	if len(sum) == 0 || len(state) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(sum), []byte(hash(state))) == 1
+}
+
+// hash returns the SHA-256 hash of the value.
+func hash(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AccessToken returns the access token from the cookie or an empty string.
+func AccessToken(r *http.Request) string {
+	return value(r, AccessTokenName)
+}
+
+// RefreshToken returns the refresh token from the cookie or an empty string.
+func RefreshToken(r *http.Request) string {
+	return value(r, RefreshTokenName)
+}
+
+// Safe returns true if the method does not change state so it does not need
+// a CSRF token.
+func Safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// ValidCSRF returns true if the CSRF header matches the CSRF cookie. Another
+// site can make the browser send the cookie, but cannot read it to set the
+// header.
+func ValidCSRF(r *http.Request) bool {
+	token := value(r, CSRFTokenName)
+	header := r.Header.Get(CSRFHeader)
+	if len(token) == 0 || len(header) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(header)) == 1
+}
+
+// value returns the value of the cookie or an empty string.
+func value(r *http.Request, name string) string {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
@@ -0,0 +1,119 @@
+package cookie_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"app/webapi/internal/cookie"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTokens(t *testing.T) {
+	c := cookie.Config{Enabled: true}
+
+	w := httptest.NewRecorder()
+	csrf, err := c.SetTokens(w, "access", time.Minute, "refresh", time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, csrf)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, v := range w.Result().Cookies() {
+		cookies[v.Name] = v
+	}
+
+	access := cookies[cookie.AccessTokenName]
+	assert.Equal(t, "access", access.Value)
+	assert.Equal(t, "/", access.Path)
+	assert.Equal(t, 60, access.MaxAge)
+	assert.True(t, access.HttpOnly)
+	assert.True(t, access.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, access.SameSite)
+
+	refresh := cookies[cookie.RefreshTokenName]
+	assert.Equal(t, "refresh", refresh.Value)
+	assert.Equal(t, "/v1/auth", refresh.Path)
+	assert.True(t, refresh.HttpOnly)
+
+	// The CSRF token can be read by scripts.
+	token := cookies[cookie.CSRFTokenName]
+	assert.Equal(t, csrf, token.Value)
+	assert.False(t, token.HttpOnly)
+
+	// The cookies are read back from a request.
+	r := httptest.NewRequest("POST", "/v1/auth/refresh", nil)
+	for _, v := range w.Result().Cookies() {
+		r.AddCookie(v)
+	}
+	assert.Equal(t, "access", cookie.AccessToken(r))
+	assert.Equal(t, "refresh", cookie.RefreshToken(r))
+}
+
+func TestConfig(t *testing.T) {
+	c := cookie.Config{
+		Domain:   "example.com",
+		SameSite: "Lax",
+		Insecure: true,
+	}
+
+	w := httptest.NewRecorder()
+	c.Clear(w)
+
+	assert.Equal(t, 3, len(w.Result().Cookies()))
+	for _, v := range w.Result().Cookies() {
+		assert.Empty(t, v.Value)
+		assert.True(t, v.MaxAge < 0)
+		assert.Equal(t, "example.com", v.Domain)
+		assert.Equal(t, http.SameSiteLaxMode, v.SameSite)
+		assert.False(t, v.Secure)
+	}
+}
+
+func TestValidCSRF(t *testing.T) {
+	r := httptest.NewRequest("POST", "/v1/user", nil)
+	assert.False(t, cookie.ValidCSRF(r))
+
+	r.AddCookie(&http.Cookie{Name: cookie.CSRFTokenName, Value: "csrf"})
+	assert.False(t, cookie.ValidCSRF(r))
+
+	r.Header.Set(cookie.CSRFHeader, "other")
+	assert.False(t, cookie.ValidCSRF(r))
+
+	r.Header.Set(cookie.CSRFHeader, "csrf")
+	assert.True(t, cookie.ValidCSRF(r))
+
+	// The header alone is not enough.
+	r = httptest.NewRequest("POST", "/v1/user", nil)
+	r.Header.Set(cookie.CSRFHeader, "csrf")
+	assert.False(t, cookie.ValidCSRF(r))
+
+	assert.True(t, cookie.Safe("GET"))
+	assert.False(t, cookie.Safe("DELETE"))
+}
+
+func TestOIDCState(t *testing.T) {
+	c := cookie.Config{}
+
+	w := httptest.NewRecorder()
+	c.SetOIDCState(w, "state", time.Minute)
+
+	assert.Equal(t, 1, len(w.Result().Cookies()))
+	state := w.Result().Cookies()[0]
+	assert.Equal(t, cookie.OIDCStateName, state.Name)
+	assert.NotEqual(t, "state", state.Value)
+	assert.Equal(t, "/v1/auth/oidc", state.Path)
+	assert.True(t, state.HttpOnly)
+
+	// The cookie is sent when the provider redirects the user back.
+	assert.Equal(t, http.SameSiteLaxMode, state.SameSite)
+
+	r := httptest.NewRequest("GET", "/v1/auth/oidc/callback", nil)
+	assert.False(t, cookie.ValidOIDCState(r, "state"))
+
+	r.AddCookie(state)
+	assert.True(t, cookie.ValidOIDCState(r, "state"))
+	assert.False(t, cookie.ValidOIDCState(r, "other"))
+	assert.False(t, cookie.ValidOIDCState(r, ""))
+}
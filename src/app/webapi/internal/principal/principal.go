@@ -0,0 +1,73 @@
+// Package principal stores the authenticated caller of a request in the
+// request context.
+package principal
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	// RoleAdmin is the role that allows a principal to manage every user.
+	RoleAdmin = "admin"
+	// RoleUser is the role given to every new user.
+	RoleUser = "user"
+)
+
+// contextKey is the type of the context key so it cannot collide with keys
+// from other packages.
+type contextKey struct{}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID    string    // User the token was issued to.
+	TokenID   string    // Unique ID of the token.
+	KeyID     string    // ID of the API key if one was used instead of a token.
+	ClientID  string    // OAuth client the token was issued to.
+	SessionID string    // Session the token was issued in.
+	ExpiresAt time.Time // Time the token or API key expires.
+	Scopes    []string  // Scopes granted to the token or API key.
+	Roles     []string  // Roles of the user when the token was issued.
+}
+
+// HasScope returns true if the principal was granted the scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole returns true if the principal has the role.
+func (p *Principal) HasRole(role string) bool {
+	for _, s := range p.Roles {
+		if s == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin returns true if the principal can manage every user.
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+// NewContext returns a copy of the context that contains the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal from the context.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// FromRequest returns the principal from the request context.
+func FromRequest(r *http.Request) (*Principal, bool) {
+	return FromContext(r.Context())
+}
@@ -0,0 +1,50 @@
+package principal_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"app/webapi/internal/principal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	p, ok := principal.FromContext(context.Background())
+	assert.False(t, ok)
+	assert.Nil(t, p)
+
+	ctx := principal.NewContext(context.Background(), &principal.Principal{
+		UserID: "1",
+	})
+
+	p, ok = principal.FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "1", p.UserID)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(ctx)
+	p, ok = principal.FromRequest(r)
+	assert.True(t, ok)
+	assert.Equal(t, "1", p.UserID)
+}
+
+func TestScopes(t *testing.T) {
+	p := &principal.Principal{
+		Scopes: []string{"read"},
+	}
+	assert.True(t, p.HasScope("read"))
+	assert.False(t, p.HasScope("write"))
+}
+
+func TestRoles(t *testing.T) {
+	p := &principal.Principal{
+		Roles: []string{principal.RoleUser, principal.RoleAdmin},
+	}
+	assert.True(t, p.HasRole(principal.RoleUser))
+	assert.True(t, p.IsAdmin())
+
+	p.Roles = []string{principal.RoleUser}
+	assert.False(t, p.IsAdmin())
+}
@@ -2,6 +2,7 @@ package testrequest
 
 import (
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
@@ -9,13 +10,53 @@ import (
 
 	"app/webapi"
 	"app/webapi/component"
+	"app/webapi/internal/cookie"
+	"app/webapi/internal/principal"
 )
 
 // SendForm is a helper to quickly make a form request.
 func SendForm(t *testing.T, core component.Core, method string, target string,
 	v url.Values) *httptest.ResponseRecorder {
-	mux := webapi.Routes(core)
+	return send(core, newRequest(method, target, v))
+}
+
+// SendFormAs is a helper to quickly make a form request as an authenticated
+// principal.
+func SendFormAs(t *testing.T, core component.Core, p *principal.Principal,
+	method string, target string, v url.Values) *httptest.ResponseRecorder {
+	r := newRequest(method, target, v)
+	r = r.WithContext(principal.NewContext(r.Context(), p))
+
+	return send(core, r)
+}
+
+// SendFormBasicAuth is a helper to quickly make a form request with HTTP
+// basic authentication.
+func SendFormBasicAuth(t *testing.T, core component.Core, username, password string,
+	method string, target string, v url.Values) *httptest.ResponseRecorder {
+	r := newRequest(method, target, v)
+	r.SetBasicAuth(username, password)
+
+	return send(core, r)
+}
 
+// SendFormCookies is a helper to quickly make a form request from a browser
+// with the cookies and the CSRF header.
+func SendFormCookies(t *testing.T, core component.Core, cookies []*http.Cookie, csrf string,
+	method string, target string, v url.Values) *httptest.ResponseRecorder {
+	r := newRequest(method, target, v)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	if len(csrf) > 0 {
+		r.Header.Set(cookie.CSRFHeader, csrf)
+	}
+
+	return send(core, r)
+}
+
+// newRequest returns a form request.
+func newRequest(method string, target string, v url.Values) *http.Request {
 	var body io.Reader
 	if v != nil {
 		body = strings.NewReader(v.Encode())
@@ -23,6 +64,12 @@ func SendForm(t *testing.T, core component.Core, method string, target string,
 
 	r := httptest.NewRequest(method, target, body)
 	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// send will serve the request using the application routes.
+func send(core component.Core, r *http.Request) *httptest.ResponseRecorder {
+	mux := webapi.Routes(core)
 	w := httptest.NewRecorder()
 	mux.ServeHTTP(w, r)
 
@@ -0,0 +1,22 @@
+package testutil
+
+// MockMailer is a mocked mail sender.
+type MockMailer struct {
+	SendFunc SendFuncType
+}
+
+// SendFuncType .
+type SendFuncType func(to, subject, body string) error
+
+// SendFuncDefault .
+var SendFuncDefault = func(to, subject, body string) error {
+	return nil
+}
+
+// Send .
+func (m *MockMailer) Send(to, subject, body string) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(to, subject, body)
+	}
+	return SendFuncDefault(to, subject, body)
+}
@@ -1,24 +1,62 @@
 package testutil
 
-import "time"
+import (
+	"time"
+
+	"app/webapi/pkg/webtoken"
+)
 
 // MockToken is a mocked webtoken.
 type MockToken struct {
 	GenerateFunc GenerateFuncType
+	VerifyFunc   VerifyFuncType
+	JWKSFunc     JWKSFuncType
 }
 
 // GenerateFuncType .
-type GenerateFuncType func(userID string, duration time.Duration) (string, error)
+type GenerateFuncType func(claims webtoken.Claims, duration time.Duration) (string, error)
 
 // GenerateFuncDefault .
-var GenerateFuncDefault = func(userID string, duration time.Duration) (string, error) {
+var GenerateFuncDefault = func(claims webtoken.Claims, duration time.Duration) (string, error) {
 	return "", nil
 }
 
 // Generate .
-func (mt *MockToken) Generate(userID string, duration time.Duration) (string, error) {
+func (mt *MockToken) Generate(claims webtoken.Claims, duration time.Duration) (string, error) {
 	if mt.GenerateFunc != nil {
-		return mt.GenerateFunc(userID, duration)
+		return mt.GenerateFunc(claims, duration)
+	}
+	return GenerateFuncDefault(claims, duration)
+}
+
+// VerifyFuncType .
+type VerifyFuncType func(s string) (*webtoken.Claims, error)
+
+// VerifyFuncDefault .
+var VerifyFuncDefault = func(s string) (*webtoken.Claims, error) {
+	return nil, webtoken.ErrMalformed
+}
+
+// Verify .
+func (mt *MockToken) Verify(s string) (*webtoken.Claims, error) {
+	if mt.VerifyFunc != nil {
+		return mt.VerifyFunc(s)
+	}
+	return VerifyFuncDefault(s)
+}
+
+// JWKSFuncType .
+type JWKSFuncType func() []webtoken.JSONWebKey
+
+// JWKSFuncDefault .
+var JWKSFuncDefault = func() []webtoken.JSONWebKey {
+	return make([]webtoken.JSONWebKey, 0)
+}
+
+// JWKS .
+func (mt *MockToken) JWKS() []webtoken.JSONWebKey {
+	if mt.JWKSFunc != nil {
+		return mt.JWKSFunc()
 	}
-	return GenerateFuncDefault(userID, duration)
+	return JWKSFuncDefault()
 }
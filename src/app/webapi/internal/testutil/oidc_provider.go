@@ -0,0 +1,217 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"app/webapi/pkg/webtoken"
+)
+
+// OIDCProvider is a fake OpenID Connect provider for tests. Every
+// authorization request is approved for the user in the fields and the ID
+// tokens are signed with an RSA key.
+type OIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+
+	mu    sync.Mutex
+	token *webtoken.Configuration
+	codes map[string]oidcCode
+}
+
+// oidcCode is an authorization code issued by the fake provider.
+type oidcCode struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// NewOIDCProvider returns a running fake provider. Close the provider at the
+// end of the test.
+func NewOIDCProvider() *OIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &OIDCProvider{
+		ClientID:      "webapi",
+		ClientSecret:  "secret",
+		Subject:       "248289761001",
+		Email:         "jsmith@example.com",
+		EmailVerified: true,
+		GivenName:     "John",
+		FamilyName:    "Smith",
+		token:         webtoken.New(nil),
+		codes:         make(map[string]oidcCode),
+	}
+	p.token.AddKey("fake", key)
+	p.token.SetSigningKey("fake")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.exchange)
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// Issuer returns the issuer URL of the provider.
+func (p *OIDCProvider) Issuer() string {
+	return p.Server.URL
+}
+
+// Close will stop the provider.
+func (p *OIDCProvider) Close() {
+	p.Server.Close()
+}
+
+// IDToken returns a signed ID token for the user with the nonce.
+func (p *OIDCProvider) IDToken(nonce string) (string, error) {
+	return p.token.Generate(webtoken.Claims{
+		Subject:  p.Subject,
+		Issuer:   p.Issuer(),
+		Audience: []string{p.ClientID},
+		Private: map[string]interface{}{
+			"nonce":          nonce,
+			"email":          p.Email,
+			"email_verified": p.EmailVerified,
+			"given_name":     p.GivenName,
+			"family_name":    p.FamilyName,
+		},
+	}, 5*time.Minute)
+}
+
+// Login will follow the authorization URL like a browser where the user
+// approves the request and return the callback URL with the code.
+func (p *OIDCProvider) Login(authURL string) (*url.URL, error) {
+	client := &http.Client{
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return nil, errors.New("authorization failed: " + resp.Status)
+	}
+
+	return url.Parse(resp.Header.Get("Location"))
+}
+
+// discovery writes the discovery document.
+func (p *OIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+// jwks writes the public keys.
+func (p *OIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": p.token.JWKS(),
+	})
+}
+
+// authorize will redirect back to the client with a new code.
+func (p *OIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" ||
+		q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	code := base64.RawURLEncoding.EncodeToString(b)
+
+	p.mu.Lock()
+	p.codes[code] = oidcCode{
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	u, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	v := u.Query()
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	u.RawQuery = v.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// exchange will exchange a code for an ID token.
+func (p *OIDCProvider) exchange(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	if id != p.ClientID || secret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	r.ParseForm()
+
+	p.mu.Lock()
+	c, found := p.codes[r.Form.Get("code")]
+	delete(p.codes, r.Form.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !found || r.Form.Get("redirect_uri") != c.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != c.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := p.IDToken(c.nonce)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// writeJSON will write the value as JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,141 @@
+// Package throttle delays and locks the logins of an account and delays the
+// logins from an IP address after failed logins. Any check of a password or
+// a second factor counts as a login.
+package throttle
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/store"
+)
+
+var (
+	// ErrLocked is returned when an account is locked after too many failed
+	// logins.
+	ErrLocked = errors.New("account is locked after too many failed logins, try again later")
+	// ErrThrottled is returned when logins are delayed after failed logins.
+	ErrThrottled = errors.New("too many failed logins, try again later")
+)
+
+// ClientIP returns the IP address of the client. The forwarded headers are
+// not trusted because the client can set them.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// New returns a login throttle.
+func New(db component.IDatabase, q component.IQuery, config component.AuthConfig) *Throttle {
+	return &Throttle{
+		db:     db,
+		q:      q,
+		config: config,
+	}
+}
+
+// Throttle counts the failed logins of accounts and IP addresses.
+type Throttle struct {
+	db     component.IDatabase
+	q      component.IQuery
+	config component.AuthConfig
+}
+
+// backoff returns how long logins are blocked after the failures. The block
+// starts at one second once the failures reach the limit and doubles with
+// each failure after that.
+func backoff(failures, after int, max time.Duration) time.Duration {
+	if failures < after {
+		return 0
+	}
+
+	n := uint(failures - after)
+	if n > 30 {
+		return max
+	}
+
+	d := time.Second << n
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// Blocked returns an error if logins of the account or from the IP address
+// are blocked. The Retry-After header is set to the seconds until the block
+// ends.
+func (t *Throttle) Blocked(w http.ResponseWriter, email, ip string) (int, error) {
+	lt := store.NewLoginThrottle(t.db, t.q)
+
+	for _, scope := range []string{store.ThrottleAccount, store.ThrottleIP} {
+		subject := email
+		if scope == store.ThrottleIP {
+			subject = ip
+		}
+
+		exists, err := lt.Find(scope, subject)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		} else if !exists || lt.RetryAfter < 1 {
+			continue
+		}
+
+		w.Header().Set("Retry-After", fmt.Sprint(lt.RetryAfter))
+		if scope == store.ThrottleAccount && lt.Failures >= t.config.LockoutAfter() {
+			return http.StatusLocked, ErrLocked
+		}
+		return http.StatusTooManyRequests, ErrThrottled
+	}
+
+	return http.StatusOK, nil
+}
+
+// Failed counts a failed login of the account and from the IP address and
+// blocks further logins once there are too many. The user ID is empty if the
+// email does not belong to a user.
+func (t *Throttle) Failed(userID, email, ip string) error {
+	lt := store.NewLoginThrottle(t.db, t.q)
+	window := t.config.LockoutDuration()
+
+	// Lock the account or delay the next login.
+	failures, err := lt.Fail(store.ThrottleAccount, email, window)
+	if err != nil {
+		return err
+	} else if failures >= t.config.LockoutAfter() {
+		if err = lt.Block(store.ThrottleAccount, email, window); err != nil {
+			return err
+		}
+
+		le := store.NewLockoutEvent(t.db, t.q)
+		if _, err = le.Locked(userID, email, ip, failures, window); err != nil {
+			return err
+		}
+	} else if d := backoff(failures, t.config.BackoffAfter(), window); d > 0 {
+		if err = lt.Block(store.ThrottleAccount, email, d); err != nil {
+			return err
+		}
+	}
+
+	// Delay the next login from the IP address.
+	failures, err = lt.Fail(store.ThrottleIP, ip, window)
+	if err != nil {
+		return err
+	} else if d := backoff(failures, t.config.IPBackoffAfter(), window); d > 0 {
+		return lt.Block(store.ThrottleIP, ip, d)
+	}
+
+	return nil
+}
+
+// Succeeded forgets the failed logins of the account.
+func (t *Throttle) Succeeded(email string) error {
+	_, err := store.NewLoginThrottle(t.db, t.q).Clear(store.ThrottleAccount, email)
+	return err
+}
@@ -0,0 +1,24 @@
+package throttle_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"app/webapi/internal/throttle"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	assert.Equal(t, "192.0.2.1", throttle.ClientIP(r))
+
+	r.RemoteAddr = "[2001:db8::1]:1234"
+	assert.Equal(t, "2001:db8::1", throttle.ClientIP(r))
+
+	// An address without a port is used as is.
+	r.RemoteAddr = "192.0.2.1"
+	assert.Equal(t, "192.0.2.1", throttle.ClientIP(r))
+}
@@ -5,68 +5,195 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"app/webapi/internal/cookie"
+	"app/webapi/internal/principal"
 	"app/webapi/model"
+	"app/webapi/pkg/apikey"
 	"app/webapi/pkg/webtoken"
 )
 
+// IRevocation provides revoked token lookups.
+type IRevocation interface {
+	IsRevoked(tokenID string) (bool, error)
+	IsUserRevoked(userID string, issuedAt time.Time) (bool, error)
+}
+
+// IKeyring provides API key lookups.
+type IKeyring interface {
+	Authenticate(key string) (*apikey.Key, error)
+}
+
+// IVerifier provides token verification.
+type IVerifier interface {
+	Verify(s string) (*webtoken.Claims, error)
+}
+
 // Config contains the dependencies for the handler.
 type Config struct {
-	secret    []byte
-	whitelist []string
+	verifier   IVerifier
+	whitelist  []string
+	revocation IRevocation
+	keyring    IKeyring
+	cookies    bool
 }
 
 // New returns a new loq request middleware.
-func New(secret []byte, whitelist []string) *Config {
+func New(verifier IVerifier, whitelist []string) *Config {
 	return &Config{
-		secret:    secret,
+		verifier:  verifier,
 		whitelist: whitelist,
 	}
 }
 
-// Handler will require a JWT.
+// SetRevocation will set the list of revoked tokens to check.
+func (c *Config) SetRevocation(r IRevocation) {
+	c.revocation = r
+}
+
+// SetKeyring will set the API keys to accept in the X-API-Key header.
+func (c *Config) SetKeyring(k IKeyring) {
+	c.keyring = k
+}
+
+// SetCookies will allow the JWT in a cookie when the Authorization header is
+// missing. Requests that change state must have a CSRF token that matches the
+// CSRF cookie.
+func (c *Config) SetCookies(enabled bool) {
+	c.cookies = enabled
+}
+
+// Handler will require a JWT or an API key.
 func (c *Config) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Determine if the page is in the JWT whitelist.
 		if !IsWhitelisted(r.Method, r.URL.Path, c.whitelist) {
-			// Require JWT on all routes.
+			// Allow an API key in place of the JWT.
 			bearer := r.Header.Get("Authorization")
+			if len(bearer) == 0 && c.keyring != nil {
+				if key := r.Header.Get("X-API-Key"); len(key) > 0 {
+					c.handleKey(w, r, next, key)
+					return
+				}
+			}
+
+			// Allow the JWT in a cookie for browser clients.
+			if len(bearer) == 0 && c.cookies {
+				if t := cookie.AccessToken(r); len(t) > 0 {
+					if !cookie.Safe(r.Method) && !cookie.ValidCSRF(r) {
+						writeError(w, http.StatusForbidden, "csrf token is invalid")
+						return
+					}
+					bearer = "Bearer " + t
+				}
+			}
+
+			// Require JWT on all routes.
 
 			// If the token is missing, show an error.
 			if len(bearer) < 8 || !strings.HasPrefix(bearer, "Bearer ") {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				r := new(model.UnauthorizedResponse)
-				r.Body.Status = http.StatusText(http.StatusUnauthorized)
-				r.Body.Message = "authorization token is missing"
-				err := json.NewEncoder(w).Encode(r.Body)
-				if err != nil {
-					w.Write([]byte(`{"status":"Internal Server Error","message":"problem encoding JSON"}`))
-					return
-				}
+				writeError(w, http.StatusUnauthorized, "authorization token is missing")
 				return
 			}
 
-			token := webtoken.New(c.secret)
-			_, err := token.Verify(bearer[7:])
+			claims, err := c.verifier.Verify(bearer[7:])
 			if err != nil {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusUnauthorized)
-				r := new(model.UnauthorizedResponse)
-				r.Body.Status = http.StatusText(http.StatusUnauthorized)
-				r.Body.Message = "authorization token is invalid"
-				err := json.NewEncoder(w).Encode(r.Body)
+				writeError(w, http.StatusUnauthorized, "authorization token is invalid")
+				return
+			}
+
+			// Determine if the token was revoked before it expired.
+			if c.revocation != nil {
+				revoked, err := c.revocation.IsRevoked(claims.ID)
+				if err == nil && !revoked && len(claims.SessionID) > 0 {
+					// Determine if the session was revoked.
+					revoked, err = c.revocation.IsRevoked(claims.SessionID)
+				}
+				if err == nil && !revoked && len(claims.ClientID) > 0 {
+					// Determine if the client was deleted.
+					revoked, err = c.revocation.IsRevoked(claims.ClientID)
+				}
+				if err == nil && !revoked {
+					// Determine if every token of the user was revoked. A
+					// token with a session is also revoked with the session,
+					// so one issued in the same second as the revocation is
+					// from a new login.
+					issuedAt := claims.IssuedAt
+					if len(claims.SessionID) > 0 {
+						issuedAt = issuedAt.Add(time.Second)
+					}
+					revoked, err = c.revocation.IsUserRevoked(claims.Subject, issuedAt)
+				}
 				if err != nil {
-					w.Write([]byte(`{"status":"Internal Server Error","message":"problem encoding JSON"}`))
+					writeError(w, http.StatusInternalServerError, "authorization token could not be checked")
+					return
+				} else if revoked {
+					writeError(w, http.StatusUnauthorized, "authorization token is revoked")
 					return
 				}
-				return
 			}
+
+			// Make the caller available to the handlers.
+			p := &principal.Principal{
+				UserID:    claims.Subject,
+				TokenID:   claims.ID,
+				ClientID:  claims.ClientID,
+				SessionID: claims.SessionID,
+				ExpiresAt: claims.ExpiresAt,
+				Scopes:    claims.Scopes,
+				Roles:     claims.Roles,
+			}
+
+			// A token from the client credentials grant is issued to the
+			// client instead of a user.
+			if len(p.ClientID) > 0 && p.UserID == p.ClientID {
+				p.UserID = ""
+			}
+
+			r = r.WithContext(principal.NewContext(r.Context(), p))
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// handleKey will serve the request if the API key is valid.
+func (c *Config) handleKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	item, err := c.keyring.Authenticate(key)
+	if err == apikey.ErrKeyInvalid {
+		writeError(w, http.StatusUnauthorized, "api key is invalid")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "api key could not be checked")
+		return
+	}
+
+	// Make the caller available to the handlers.
+	p := &principal.Principal{
+		UserID: item.UserID,
+		KeyID:  item.ID,
+		Scopes: item.Scopes(),
+	}
+	if item.ExpiresAt != nil {
+		p.ExpiresAt = *item.ExpiresAt
+	}
+
+	next.ServeHTTP(w, r.WithContext(principal.NewContext(r.Context(), p)))
+}
+
+// writeError will write the status and message as JSON.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	r := new(model.GenericResponse)
+	r.Body.Status = http.StatusText(status)
+	r.Body.Message = message
+	err := json.NewEncoder(w).Encode(r.Body)
+	if err != nil {
+		w.Write([]byte(`{"status":"Internal Server Error","message":"problem encoding JSON"}`))
+	}
+}
+
 // IsWhitelisted returns true if the request is in the whitelist. If an
 // asterisk is found in the whitelist, allow all routes.
 func IsWhitelisted(method string, path string, arr []string) (found bool) {
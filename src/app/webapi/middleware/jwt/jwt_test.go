@@ -1,15 +1,50 @@
 package jwt_test
 
 import (
+	"app/webapi/internal/cookie"
+	"app/webapi/internal/principal"
 	"app/webapi/middleware/jwt"
+	"app/webapi/pkg/apikey"
+	"app/webapi/pkg/webtoken"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+type MockRevocation struct {
+	revoked map[string]bool
+	users   map[string]time.Time
+	err     error
+}
+
+func (m *MockRevocation) IsRevoked(tokenID string) (bool, error) {
+	return m.revoked[tokenID], m.err
+}
+
+func (m *MockRevocation) IsUserRevoked(userID string, issuedAt time.Time) (bool, error) {
+	revokedAt, found := m.users[userID]
+	return found && !issuedAt.After(revokedAt), m.err
+}
+
+type MockKeyring struct {
+	keys map[string]*apikey.Key
+	err  error
+}
+
+func (m *MockKeyring) Authenticate(key string) (*apikey.Key, error) {
+	if m.err != nil {
+		return nil, m.err
+	} else if k, found := m.keys[key]; found {
+		return k, nil
+	}
+	return nil, apikey.ErrKeyInvalid
+}
+
 func TestWhitelistAllowed(t *testing.T) {
 	for _, v := range []string{
 		"GET /v1",
@@ -24,7 +59,7 @@ func TestWhitelistAllowed(t *testing.T) {
 			"GET /v1/auth",
 		}
 
-		token := jwt.New([]byte("secret"), whitelist)
+		token := jwt.New(webtoken.New([]byte("secret")), whitelist)
 		h := token.Handler(mux)
 
 		r := httptest.NewRequest(arr[0], arr[1], nil)
@@ -50,7 +85,7 @@ func TestWhitelistNotAllowed(t *testing.T) {
 			"GET /v1/auth",
 		}
 
-		token := jwt.New([]byte("secret"), whitelist)
+		token := jwt.New(webtoken.New([]byte("secret")), whitelist)
 		h := token.Handler(mux)
 
 		r := httptest.NewRequest(arr[0], arr[1], nil)
@@ -70,7 +105,7 @@ func TestWhitelistBadBearer(t *testing.T) {
 		"GET /v1/auth",
 	}
 
-	token := jwt.New([]byte("secret"), whitelist)
+	token := jwt.New(webtoken.New([]byte("secret")), whitelist)
 	h := token.Handler(mux)
 
 	r := httptest.NewRequest("POST", "/v1/user", nil)
@@ -82,6 +117,368 @@ func TestWhitelistBadBearer(t *testing.T) {
 	assert.Contains(t, w.Body.String(), `authorization token is invalid`)
 }
 
+func TestPrincipal(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{
+		Subject:   "jsmith",
+		SessionID: "session",
+		Roles:     []string{principal.RoleUser},
+	}, 1*time.Hour)
+	assert.Nil(t, err)
+	claims, err := wt.Verify(ss)
+	assert.Nil(t, err)
+
+	var p *principal.Principal
+	found := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		p, found = principal.FromRequest(r)
+	})
+
+	token := jwt.New(webtoken.New(secret), nil)
+	h := token.Handler(mux)
+
+	r := httptest.NewRequest("GET", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+ss)
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, found)
+	assert.Equal(t, "jsmith", p.UserID)
+	assert.Equal(t, claims.ID, p.TokenID)
+	assert.Equal(t, "session", p.SessionID)
+	assert.Equal(t, claims.ExpiresAt, p.ExpiresAt)
+	assert.Equal(t, []string{principal.RoleUser}, p.Roles)
+}
+
+func TestPrincipalClient(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	var p *principal.Principal
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		p, _ = principal.FromRequest(r)
+	})
+
+	h := jwt.New(webtoken.New(secret), nil).Handler(mux)
+
+	for _, v := range []struct {
+		subject string
+		userID  string
+	}{
+		{"jsmith", "jsmith"}, // Issued to a client on behalf of a user.
+		{"partner", ""},      // Issued to the client itself.
+	} {
+		ss, err := wt.Generate(webtoken.Claims{
+			Subject:  v.subject,
+			ClientID: "partner",
+			Scopes:   []string{"user:read"},
+		}, 1*time.Hour)
+		assert.Nil(t, err)
+
+		r := httptest.NewRequest("GET", "/v1/user", nil)
+		w := httptest.NewRecorder()
+		r.Header.Set("Authorization", "Bearer "+ss)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, v.userID, p.UserID)
+		assert.Equal(t, "partner", p.ClientID)
+		assert.True(t, p.HasScope("user:read"))
+	}
+}
+
+func TestRevoked(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+	claims, err := wt.Verify(ss)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+
+	token := jwt.New(webtoken.New(secret), nil)
+	mr := &MockRevocation{revoked: map[string]bool{}}
+	token.SetRevocation(mr)
+	h := token.Handler(mux)
+
+	// The token is valid.
+	r := httptest.NewRequest("POST", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+ss)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The token is revoked.
+	mr.revoked[claims.ID] = true
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is revoked`)
+
+	// The revocation list is not available.
+	mr.err = errors.New("database error")
+	mr.revoked[claims.ID] = false
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestSessionRevoked(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "jsmith", SessionID: "session"}, 1*time.Hour)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+
+	token := jwt.New(webtoken.New(secret), nil)
+	mr := &MockRevocation{revoked: map[string]bool{}}
+	token.SetRevocation(mr)
+	h := token.Handler(mux)
+
+	// The session is revoked so every token issued in it is too.
+	mr.revoked["session"] = true
+	r := httptest.NewRequest("POST", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+ss)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is revoked`)
+}
+
+func TestClientRevoked(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "client", ClientID: "client"}, 1*time.Hour)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+
+	token := jwt.New(webtoken.New(secret), nil)
+	mr := &MockRevocation{revoked: map[string]bool{}}
+	token.SetRevocation(mr)
+	h := token.Handler(mux)
+
+	// The client was deleted so every token issued to it is revoked.
+	mr.revoked["client"] = true
+	r := httptest.NewRequest("POST", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+ss)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is revoked`)
+}
+
+func TestUserRevoked(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+	claims, err := wt.Verify(ss)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+
+	token := jwt.New(webtoken.New(secret), nil)
+	mr := &MockRevocation{users: map[string]time.Time{}}
+	token.SetRevocation(mr)
+	h := token.Handler(mux)
+
+	// The tokens of the user were revoked before the token was issued.
+	mr.users["jsmith"] = claims.IssuedAt.Add(-time.Minute)
+	r := httptest.NewRequest("POST", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+ss)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The tokens of the user were revoked in the same second the token was
+	// issued.
+	mr.users["jsmith"] = claims.IssuedAt
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is revoked`)
+}
+
+func TestUserRevokedSession(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "jsmith", SessionID: "session"}, 1*time.Hour)
+	assert.Nil(t, err)
+	claims, err := wt.Verify(ss)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+
+	token := jwt.New(webtoken.New(secret), nil)
+	mr := &MockRevocation{users: map[string]time.Time{}}
+	token.SetRevocation(mr)
+	h := token.Handler(mux)
+
+	// A token with a session issued in the same second as the revocation is
+	// from a new login.
+	mr.users["jsmith"] = claims.IssuedAt
+	r := httptest.NewRequest("POST", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("Authorization", "Bearer "+ss)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The tokens of the user were revoked after the token was issued.
+	mr.users["jsmith"] = claims.IssuedAt.Add(time.Second)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is revoked`)
+}
+
+func TestAPIKey(t *testing.T) {
+	var p *principal.Principal
+	found := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		p, found = principal.FromRequest(r)
+	})
+
+	token := jwt.New(webtoken.New([]byte("0123456789ABCDEF0123456789ABCDEF")), nil)
+	mk := &MockKeyring{keys: map[string]*apikey.Key{
+		"secretkey": {
+			ID:     "1",
+			UserID: "jsmith",
+			Scope:  "user:read user:update",
+		},
+	}}
+	token.SetKeyring(mk)
+	h := token.Handler(mux)
+
+	// The key is valid.
+	r := httptest.NewRequest("GET", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("X-API-Key", "secretkey")
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, found)
+	assert.Equal(t, "jsmith", p.UserID)
+	assert.Equal(t, "1", p.KeyID)
+	assert.Equal(t, []string{"user:read", "user:update"}, p.Scopes)
+
+	// The key is invalid.
+	r.Header.Set("X-API-Key", "wrongkey")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `api key is invalid`)
+
+	// The keys are not available.
+	mk.err = errors.New("database error")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAPIKeyDisabled(t *testing.T) {
+	mux := http.NewServeMux()
+
+	// Without a keyring, the key is ignored.
+	token := jwt.New(webtoken.New([]byte("0123456789ABCDEF0123456789ABCDEF")), nil)
+	h := token.Handler(mux)
+
+	r := httptest.NewRequest("GET", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	r.Header.Set("X-API-Key", "secretkey")
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is missing`)
+}
+
+func TestCookie(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+
+	var p *principal.Principal
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/user", func(w http.ResponseWriter, r *http.Request) {
+		p, _ = principal.FromRequest(r)
+	})
+
+	token := jwt.New(webtoken.New(secret), nil)
+	token.SetCookies(true)
+	h := token.Handler(mux)
+
+	// A safe method does not need the CSRF token.
+	r := httptest.NewRequest("GET", "/v1/user", nil)
+	r.AddCookie(&http.Cookie{Name: cookie.AccessTokenName, Value: ss})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jsmith", p.UserID)
+
+	// A method that changes state needs the CSRF header to match the cookie.
+	r = httptest.NewRequest("POST", "/v1/user", nil)
+	r.AddCookie(&http.Cookie{Name: cookie.AccessTokenName, Value: ss})
+	r.AddCookie(&http.Cookie{Name: cookie.CSRFTokenName, Value: "csrf"})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `csrf token is invalid`)
+
+	r.Header.Set(cookie.CSRFHeader, "other")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r.Header.Set(cookie.CSRFHeader, "csrf")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A bearer token does not need the CSRF token.
+	r = httptest.NewRequest("POST", "/v1/user", nil)
+	r.Header.Set("Authorization", "Bearer "+ss)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCookieDisabled(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	wt := webtoken.New(secret)
+
+	ss, err := wt.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+
+	mux := http.NewServeMux()
+	h := jwt.New(webtoken.New(secret), nil).Handler(mux)
+
+	// Without cookies enabled, the cookie is ignored.
+	r := httptest.NewRequest("GET", "/v1/user", nil)
+	r.AddCookie(&http.Cookie{Name: cookie.AccessTokenName, Value: ss})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `authorization token is missing`)
+}
+
 func TestIsWhitelisted(t *testing.T) {
 	assert.Equal(t, true, jwt.IsWhitelisted("GET", "/v1", []string{
 		"GET /v1",
@@ -13,15 +13,32 @@ import (
 // *****************************************************************************
 
 // Wrap will return the http.Handler wrapped in middleware.
-func Wrap(h http.Handler, l logrequest.ILog, secret []byte) http.Handler {
+func Wrap(h http.Handler, l logrequest.ILog, v jwt.IVerifier, rev jwt.IRevocation,
+	keys jwt.IKeyring, cookies bool) http.Handler {
 	// JWT whitelist.
 	whitelist := []string{
 		"GET /v1",
 		"GET /v1/auth",
+		"POST /v1/auth/login",
+		"POST /v1/auth/refresh",
+		"POST /v1/auth/password/forgot",
+		"POST /v1/auth/password/reset",
+		"POST /v1/auth/magic",
+		"POST /v1/auth/magic/verify",
+		"GET /v1/auth/verify",
+		"POST /v1/auth/verify",
+		"POST /v1/auth/2fa/verify",
+		"GET /v1/auth/oidc",
+		"GET /v1/auth/oidc/callback",
+		"GET /.well-known/jwks.json",
+		"POST /oauth/token",
 	}
 
 	// JWT validation.
-	token := jwt.New(secret, whitelist)
+	token := jwt.New(v, whitelist)
+	token.SetRevocation(rev)
+	token.SetKeyring(keys)
+	token.SetCookies(cookies)
 	h = token.Handler(h)
 
 	// CORS for the endpoints.
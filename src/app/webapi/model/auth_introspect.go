@@ -0,0 +1,23 @@
+package model
+
+// AuthIntrospectResponse returns 200. The body follows RFC 7662 so it does
+// not have a status. Only active is returned for a token that is not active.
+// swagger:response AuthIntrospectResponse
+type AuthIntrospectResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Active bool `json:"active"`
+		// Scope contains the scopes of the token separated by spaces.
+		Scope     string   `json:"scope,omitempty"`
+		ClientID  string   `json:"client_id,omitempty"`
+		TokenType string   `json:"token_type,omitempty"`
+		Exp       int64    `json:"exp,omitempty"`
+		Iat       int64    `json:"iat,omitempty"`
+		Nbf       int64    `json:"nbf,omitempty"`
+		Sub       string   `json:"sub,omitempty"`
+		Aud       []string `json:"aud,omitempty"`
+		Iss       string   `json:"iss,omitempty"`
+		Jti       string   `json:"jti,omitempty"`
+	}
+}
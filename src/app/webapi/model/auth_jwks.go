@@ -0,0 +1,26 @@
+package model
+
+// AuthJWKSResponse returns 200.
+// swagger:response AuthJWKSResponse
+type AuthJWKSResponse struct {
+	// in: body
+	Body struct {
+		// Keys contains the public keys that verify the tokens.
+		//
+		// Required: true
+		Keys []AuthJWKSResponseKey `json:"keys"`
+	}
+}
+
+// AuthJWKSResponseKey is a public key in the JSON Web Key format.
+type AuthJWKSResponseKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
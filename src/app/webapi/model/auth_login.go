@@ -0,0 +1,24 @@
+package model
+
+// AuthLoginResponse returns 200.
+// swagger:response AuthLoginResponse
+type AuthLoginResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			Token        string `json:"token,omitempty"`
+			RefreshToken string `json:"refresh_token,omitempty"`
+			ExpiresIn    int    `json:"expires_in,omitempty"`
+			// Returned instead of the tokens when the tokens are written to
+			// cookies. Send it in the X-CSRF-Token header.
+			CSRFToken string `json:"csrf_token,omitempty"`
+			// Returned instead of the tokens when the user has two-factor
+			// authentication enabled. Exchange it with a code at
+			// /v1/auth/2fa/verify.
+			TwoFactorToken string `json:"two_factor_token,omitempty"`
+		} `json:"data"`
+	}
+}
@@ -0,0 +1,23 @@
+package model
+
+// AuthRefreshResponse returns 200.
+// swagger:response AuthRefreshResponse
+type AuthRefreshResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			// Token and RefreshToken are empty when the tokens are written
+			// to cookies.
+			Token        string `json:"token,omitempty"`
+			RefreshToken string `json:"refresh_token,omitempty"`
+			// Required: true
+			ExpiresIn int `json:"expires_in"`
+			// Returned instead of the tokens when the tokens are written to
+			// cookies. Send it in the X-CSRF-Token header.
+			CSRFToken string `json:"csrf_token,omitempty"`
+		} `json:"data"`
+	}
+}
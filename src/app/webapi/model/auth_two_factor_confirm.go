@@ -0,0 +1,16 @@
+package model
+
+// AuthTwoFactorConfirmResponse returns 200.
+// swagger:response AuthTwoFactorConfirmResponse
+type AuthTwoFactorConfirmResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			// Required: true
+			RecoveryCodes []string `json:"recovery_codes"`
+		} `json:"data"`
+	}
+}
@@ -0,0 +1,18 @@
+package model
+
+// AuthTwoFactorEnrollResponse returns 200.
+// swagger:response AuthTwoFactorEnrollResponse
+type AuthTwoFactorEnrollResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			// Required: true
+			Secret string `json:"secret"`
+			// Required: true
+			URI string `json:"uri"`
+		} `json:"data"`
+	}
+}
@@ -45,8 +45,33 @@ type UnauthorizedResponse struct {
 	GenericResponse
 }
 
+// ForbiddenResponse returns 403.
+// swagger:response ForbiddenResponse
+type ForbiddenResponse struct {
+	GenericResponse
+}
+
+// LockedResponse returns 423.
+// swagger:response LockedResponse
+type LockedResponse struct {
+	GenericResponse
+}
+
+// TooManyRequestsResponse returns 429.
+// swagger:response TooManyRequestsResponse
+type TooManyRequestsResponse struct {
+	GenericResponse
+}
+
 // InternalServerErrorResponse returns 500.
 // swagger:response InternalServerErrorResponse
 type InternalServerErrorResponse struct {
 	GenericResponse
 }
+
+// FoundResponse returns 302.
+// swagger:response FoundResponse
+type FoundResponse struct {
+	// Location is the URL the client is redirected to.
+	Location string
+}
@@ -0,0 +1,40 @@
+package model
+
+// OAuthAuthorizeResponse returns 200.
+// swagger:response OAuthAuthorizeResponse
+type OAuthAuthorizeResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			// Required: true
+			ClientID string `json:"client_id"`
+			// Required: true
+			ClientName string `json:"client_name"`
+			// Required: true
+			RedirectURI string `json:"redirect_uri"`
+			// Required: true
+			Scope []string `json:"scope"`
+			State string   `json:"state,omitempty"`
+		} `json:"data"`
+	}
+}
+
+// OAuthAuthorizeDecisionResponse returns 200.
+// swagger:response OAuthAuthorizeDecisionResponse
+type OAuthAuthorizeDecisionResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			// RedirectTo is the redirect URI with the code or the error.
+			//
+			// Required: true
+			RedirectTo string `json:"redirect_to"`
+		} `json:"data"`
+	}
+}
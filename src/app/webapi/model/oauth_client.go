@@ -0,0 +1,19 @@
+package model
+
+// OAuthClientCreateResponse returns 200.
+// swagger:response OAuthClientCreateResponse
+type OAuthClientCreateResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data struct {
+			// Required: true
+			ClientID string `json:"client_id"`
+			// ClientSecret is only shown once and is empty for a public
+			// client.
+			ClientSecret string `json:"client_secret,omitempty"`
+		} `json:"data"`
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+// OAuthTokenResponse returns 200. The body follows RFC 6749 so it does not
+// have a status.
+// swagger:response OAuthTokenResponse
+type OAuthTokenResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		AccessToken string `json:"access_token"`
+		// Required: true
+		TokenType string `json:"token_type"`
+		// Required: true
+		ExpiresIn int `json:"expires_in"`
+		// RefreshToken is only issued to a client acting for a user.
+		RefreshToken string `json:"refresh_token,omitempty"`
+		// Scope contains the granted scopes separated by spaces.
+		Scope string `json:"scope,omitempty"`
+	}
+}
+
+// OAuthErrorResponse returns 400 or 401 with an RFC 6749 error.
+// swagger:response OAuthErrorResponse
+type OAuthErrorResponse struct {
+	// in: body
+	Body struct {
+		// Error is the error code, like invalid_grant.
+		//
+		// Required: true
+		Error string `json:"error"`
+		// ErrorDescription can contain a developer friendly message.
+		ErrorDescription string `json:"error_description,omitempty"`
+	}
+}
@@ -0,0 +1,31 @@
+package model
+
+// PageMeta describes a page of a list.
+type PageMeta struct {
+	// Total is the number of items that match the filters.
+	//
+	// Required: true
+	Total int `json:"total"`
+	// Required: true
+	Page int `json:"page"`
+	// Required: true
+	PerPage int `json:"per_page"`
+	// Required: true
+	TotalPages int `json:"total_pages"`
+}
+
+// CursorMeta describes a page of a list that is read with cursors.
+type CursorMeta struct {
+	// Required: true
+	PerPage int `json:"per_page"`
+	// NextCursor is the after parameter of the next page, empty on the last
+	// page.
+	//
+	// Required: true
+	NextCursor string `json:"next_cursor"`
+	// PrevCursor is the before parameter of the previous page, empty on the
+	// first page.
+	//
+	// Required: true
+	PrevCursor string `json:"prev_cursor"`
+}
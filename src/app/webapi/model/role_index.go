@@ -0,0 +1,19 @@
+package model
+
+// RoleIndexResponse returns 200.
+// swagger:response RoleIndexResponse
+type RoleIndexResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data []RoleIndexResponseData `json:"data"`
+	}
+}
+
+// RoleIndexResponseData is the role data.
+type RoleIndexResponseData struct {
+	ID   uint8  `json:"id"`
+	Name string `json:"name"`
+}
@@ -9,6 +9,10 @@ type UserIndexResponse struct {
 		Status string `json:"status"`
 		// Required: true
 		Data []UserIndexResponseData `json:"data"`
+		// Meta is only returned for pages by number.
+		Meta *PageMeta `json:"meta,omitempty"`
+		// Cursor is only returned for pages by cursor.
+		Cursor *CursorMeta `json:"cursor,omitempty"`
 	}
 }
 
@@ -0,0 +1,15 @@
+package model
+
+// UserRoleIndexResponse returns 200.
+// swagger:response UserRoleIndexResponse
+type UserRoleIndexResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Data contains the names of the roles.
+		//
+		// Required: true
+		Data []string `json:"data"`
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// UserSessionIndexResponse returns 200.
+// swagger:response UserSessionIndexResponse
+type UserSessionIndexResponse struct {
+	// in: body
+	Body struct {
+		// Required: true
+		Status string `json:"status"`
+		// Required: true
+		Data []UserSessionIndexResponseData `json:"data"`
+	}
+}
+
+// UserSessionIndexResponseData is the session data.
+type UserSessionIndexResponseData struct {
+	ID         string    `json:"id"`
+	ClientID   string    `json:"client_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
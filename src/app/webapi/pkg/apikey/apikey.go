@@ -0,0 +1,167 @@
+// Package apikey provides long-lived API keys for service-to-service clients.
+// Only the SHA-256 hash of each key is stored in MySQL.
+package apikey
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"app/webapi/pkg/securegen"
+)
+
+var (
+	// ErrKeyInvalid is when a key does not exist, is expired, or is revoked.
+	ErrKeyInvalid = errors.New("api key is invalid")
+)
+
+// IDatabase provides data query capabilities.
+type IDatabase interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+}
+
+// Key contains the details of an API key.
+type Key struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	Scope      string     `db:"scope"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  *time.Time `db:"created_at"`
+}
+
+// Scopes returns the scopes granted to the key.
+func (k *Key) Scopes() []string {
+	return strings.Fields(k.Scope)
+}
+
+// Keyring manages the API keys.
+type Keyring struct {
+	db IDatabase
+}
+
+// New returns a new keyring.
+func New(db IDatabase) *Keyring {
+	return &Keyring{
+		db: db,
+	}
+}
+
+// Create adds a new API key for a user and returns the key to give to the
+// client. The key never expires if the duration is 0.
+func (k *Keyring) Create(userID, name string, scopes []string,
+	duration time.Duration) (ID string, key string, err error) {
+	ID, err = securegen.UUID()
+	if err != nil {
+		return "", "", err
+	}
+
+	b, err := securegen.Bytes(32)
+	if err != nil {
+		return "", "", err
+	}
+	key = base64.RawURLEncoding.EncodeToString(b)
+
+	var expiresAt interface{}
+	if duration > 0 {
+		expiresAt = time.Now().Add(duration).Unix()
+	}
+
+	_, err = k.db.Exec(`
+		INSERT INTO api_key
+		(id, user_id, name, prefix, key_hash, scope, expires_at)
+		VALUES
+		(?,?,?,?,?,?,FROM_UNIXTIME(?))
+		`,
+		ID, userID, name, key[:8], hash(key), strings.Join(scopes, " "),
+		expiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	return ID, key, nil
+}
+
+// Authenticate returns the details of a key if it exists, has not expired,
+// has not been revoked, and the owner has not been deleted. The last used time
+// of the key is updated at most once a minute.
+func (k *Keyring) Authenticate(key string) (*Key, error) {
+	item := new(Key)
+	err := k.db.Get(item, `
+		SELECT id, user_id, name, prefix, scope, last_used_at, expires_at,
+			revoked_at, created_at
+		FROM api_key
+		WHERE key_hash = ?
+		AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > NOW())
+		AND user_id IN (SELECT id FROM user WHERE deleted_at IS NULL)
+		LIMIT 1`,
+		hash(key))
+	if err == sql.ErrNoRows {
+		return nil, ErrKeyInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	_, err = k.db.Exec(`
+		UPDATE api_key
+		SET last_used_at = NOW()
+		WHERE id = ?
+		AND (last_used_at IS NULL OR last_used_at < DATE_SUB(NOW(), INTERVAL 1 MINUTE))
+		`,
+		item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// List returns all the keys, including the expired and revoked keys.
+func (k *Keyring) List() ([]Key, error) {
+	items := make([]Key, 0)
+	err := k.db.Select(&items, `
+		SELECT id, user_id, name, prefix, scope, last_used_at, expires_at,
+			revoked_at, created_at
+		FROM api_key
+		ORDER BY created_at, id`)
+	return items, err
+}
+
+// Revoke will prevent a key from being used. It returns false if the key does
+// not exist or is already revoked.
+func (k *Keyring) Revoke(ID string) (bool, error) {
+	result, err := k.db.Exec(`
+		UPDATE api_key
+		SET revoked_at = NOW()
+		WHERE id = ?
+		AND revoked_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// hash returns the SHA-256 hash of a key. A fast hash is safe here because the
+// keys have 256 bits of entropy.
+func hash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,111 @@
+package apikey_test
+
+import (
+	"testing"
+	"time"
+
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/apikey"
+	"app/webapi/pkg/query"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	u := store.NewUser(db, query.New(db))
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	k := apikey.New(db)
+	ID, key, err := k.Create(userID, "batch", []string{"user:read", "user:update"}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 36, len(ID))
+	assert.Equal(t, 43, len(key))
+
+	item, err := k.Authenticate(key)
+	assert.Nil(t, err)
+	assert.Equal(t, ID, item.ID)
+	assert.Equal(t, userID, item.UserID)
+	assert.Equal(t, key[:8], item.Prefix)
+	assert.Equal(t, []string{"user:read", "user:update"}, item.Scopes())
+	assert.Nil(t, item.ExpiresAt)
+
+	// The last used time is recorded.
+	items, err := k.List()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.NotNil(t, items[0].LastUsedAt)
+
+	_, err = k.Authenticate("wrong")
+	assert.Equal(t, apikey.ErrKeyInvalid, err)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestRevoke(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	u := store.NewUser(db, query.New(db))
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	k := apikey.New(db)
+	ID, key, err := k.Create(userID, "batch", nil, 24*time.Hour)
+	assert.Nil(t, err)
+
+	revoked, err := k.Revoke(ID)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// A key cannot be revoked twice.
+	revoked, err = k.Revoke(ID)
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	_, err = k.Authenticate(key)
+	assert.Equal(t, apikey.ErrKeyInvalid, err)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestExpired(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	u := store.NewUser(db, query.New(db))
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	k := apikey.New(db)
+	_, key, err := k.Create(userID, "batch", nil, 1*time.Second)
+	assert.Nil(t, err)
+
+	time.Sleep(2 * time.Second)
+
+	_, err = k.Authenticate(key)
+	assert.Equal(t, apikey.ErrKeyInvalid, err)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestDeletedOwner(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	u := store.NewUser(db, query.New(db))
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	k := apikey.New(db)
+	_, key, err := k.Create(userID, "batch", nil, 0)
+	assert.Nil(t, err)
+
+	_, err = u.DeleteOneByID(u, userID)
+	assert.Nil(t, err)
+
+	_, err = k.Authenticate(key)
+	assert.Equal(t, apikey.ErrKeyInvalid, err)
+
+	testutil.TeardownDatabase(unique)
+}
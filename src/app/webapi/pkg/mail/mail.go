@@ -0,0 +1,119 @@
+// Package mail provides senders that deliver email messages. The senders in
+// this package do not connect to a mail server so they are suited for local
+// development and testing.
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"app/webapi/pkg/securegen"
+)
+
+// ErrHeaderInvalid is when an address or subject contains a line break.
+var ErrHeaderInvalid = errors.New("mail header is invalid")
+
+// ISender sends email messages.
+type ISender interface {
+	Send(to, subject, body string) error
+}
+
+// ILog provides logging capabilities.
+type ILog interface {
+	Printf(format string, v ...interface{})
+}
+
+// Config contains the mail settings.
+type Config struct {
+	From      string `json:"From"`      // Address the messages are sent from.
+	Directory string `json:"Directory"` // Folder the messages are written to, the messages are logged if empty.
+}
+
+// New returns a sender that writes the messages to the directory or to the
+// logger if the directory is not set.
+func New(c Config, l ILog) ISender {
+	if len(c.Directory) > 0 {
+		return NewFile(c.From, c.Directory)
+	}
+	return NewLog(c.From, l)
+}
+
+// Log is a sender that writes the messages to a logger.
+type Log struct {
+	from string
+	log  ILog
+}
+
+// NewLog returns a sender that writes the messages to a logger.
+func NewLog(from string, l ILog) *Log {
+	return &Log{
+		from: from,
+		log:  l,
+	}
+}
+
+// Send will write the message to the logger.
+func (s *Log) Send(to, subject, body string) error {
+	msg, err := format(s.from, to, subject, body, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.log.Printf("Mail:\n%v", msg)
+	return nil
+}
+
+// File is a sender that writes each message to a file.
+type File struct {
+	from string
+	dir  string
+}
+
+// NewFile returns a sender that writes each message to a file in the
+// directory.
+func NewFile(from, dir string) *File {
+	return &File{
+		from: from,
+		dir:  dir,
+	}
+}
+
+// Send will write the message to a new file in the directory. The files are
+// only readable by the owner because messages can contain secrets.
+func (s *File) Send(to, subject, body string) error {
+	now := time.Now()
+	msg, err := format(s.from, to, subject, body, now)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%v-%v.eml", now.Format("20060102-150405"), uuid)
+	return ioutil.WriteFile(filepath.Join(s.dir, name), []byte(msg), 0600)
+}
+
+// format returns the message with the headers.
+func format(from, to, subject, body string, date time.Time) (string, error) {
+	for _, v := range []string{from, to, subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return "", ErrHeaderInvalid
+		}
+	}
+
+	return fmt.Sprintf("From: %v\r\nTo: %v\r\nSubject: %v\r\nDate: %v\r\n\r\n%v",
+		from, to, subject, date.Format(time.RFC1123Z), body), nil
+}
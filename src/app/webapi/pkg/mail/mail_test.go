@@ -0,0 +1,69 @@
+package mail_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"app/webapi/pkg/mail"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockLogger struct {
+	output string
+}
+
+func (l *MockLogger) Printf(format string, v ...interface{}) {
+	l.output += fmt.Sprintf(format, v...)
+}
+
+func TestLog(t *testing.T) {
+	ml := new(MockLogger)
+	s := mail.New(mail.Config{From: "webapi@example.com"}, ml)
+
+	err := s.Send("jsmith@example.com", "Hello", "Message body.")
+	assert.Nil(t, err)
+	assert.Contains(t, ml.output, "From: webapi@example.com\r\n")
+	assert.Contains(t, ml.output, "To: jsmith@example.com\r\n")
+	assert.Contains(t, ml.output, "Subject: Hello\r\n")
+	assert.Contains(t, ml.output, "\r\n\r\nMessage body.")
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mail")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := mail.New(mail.Config{
+		From:      "webapi@example.com",
+		Directory: filepath.Join(dir, "outbox"),
+	}, nil)
+
+	err = s.Send("jsmith@example.com", "Hello", "Message body.")
+	assert.Nil(t, err)
+	err = s.Send("jsmith@example.com", "Hello again", "Message body.")
+	assert.Nil(t, err)
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "outbox"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	assert.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "outbox", files[0].Name()))
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "To: jsmith@example.com\r\n")
+	assert.Contains(t, string(b), "\r\n\r\nMessage body.")
+}
+
+func TestHeaderInvalid(t *testing.T) {
+	s := mail.NewLog("webapi@example.com", new(MockLogger))
+
+	err := s.Send("jsmith@example.com\r\nBcc: other@example.com", "Hello", "Message body.")
+	assert.Equal(t, mail.ErrHeaderInvalid, err)
+
+	err = s.Send("jsmith@example.com", "Hello\nBcc: other@example.com", "Message body.")
+	assert.Equal(t, mail.ErrHeaderInvalid, err)
+}
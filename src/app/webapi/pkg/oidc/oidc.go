@@ -0,0 +1,405 @@
+// Package oidc provides an OpenID Connect relying party that signs in users
+// with an identity provider using the authorization code flow.
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"app/webapi/pkg/webtoken"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// discoveryPath is appended to the issuer to get the discovery document.
+	discoveryPath = "/.well-known/openid-configuration"
+	// maxBodySize is the largest response that is read from the provider.
+	maxBodySize = 1 << 20
+	// leeway is the clock skew allowed when checking the times of an ID token.
+	leeway = time.Minute
+)
+
+var (
+	// ErrDiscoveryInvalid is when the discovery document is incomplete or is
+	// for another issuer.
+	ErrDiscoveryInvalid = errors.New("discovery document is invalid")
+	// ErrTokenInvalid is when an ID token fails verification.
+	ErrTokenInvalid = errors.New("id token is invalid")
+	// ErrNonceInvalid is when the nonce of an ID token does not match.
+	ErrNonceInvalid = errors.New("id token nonce is invalid")
+)
+
+// IClock provides clock capabilities.
+type IClock interface {
+	Now() time.Time
+}
+
+// clock is the standard system clock.
+type clock struct{}
+
+// Now returns the current time.
+func (c *clock) Now() time.Time {
+	return time.Now()
+}
+
+// Config contains the settings of an identity provider.
+type Config struct {
+	Name         string   `json:"Name"`         // Name of the provider that is sent to start a login.
+	Issuer       string   `json:"Issuer"`       // Issuer URL, the discovery document is read from below it.
+	ClientID     string   `json:"ClientID"`     // Client ID registered with the provider.
+	ClientSecret string   `json:"ClientSecret"` // Client secret registered with the provider.
+	RedirectURL  string   `json:"RedirectURL"`  // Callback URL registered with the provider.
+	Scopes       []string `json:"Scopes"`       // Scopes in addition to openid, defaults to email and profile.
+	LinkByEmail  bool     `json:"LinkByEmail"`  // Link an existing user with the same verified email on the first login.
+}
+
+// Discovery is the part of the provider metadata that is used.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDToken contains the verified claims of an ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+}
+
+// idClaims are the claims stored in an ID token.
+type idClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	AuthorizedBy  string   `json:"azp"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Name          string   `json:"name"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+}
+
+// Valid always passes because the claims are validated by the provider after
+// the signature is verified.
+func (c *idClaims) Valid() error {
+	return nil
+}
+
+// audience is a single value or an array in the token.
+type audience []string
+
+// UnmarshalJSON will unmarshal a string or an array of strings.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	*a = audience(arr)
+	return nil
+}
+
+// Provider is an identity provider. The discovery document and the keys are
+// cached after they are fetched.
+type Provider struct {
+	Config
+
+	client *http.Client
+	clock  IClock
+
+	mu        sync.Mutex
+	discovery *Discovery
+	keys      map[string]interface{}
+}
+
+// New returns a provider from the config.
+func New(c Config) *Provider {
+	return &Provider{
+		Config: c,
+		client: &http.Client{Timeout: 10 * time.Second},
+		clock:  new(clock),
+	}
+}
+
+// SetHTTPClient will set the client that sends the requests to the provider.
+func (p *Provider) SetHTTPClient(c *http.Client) {
+	p.client = c
+}
+
+// SetClock will set the clock.
+func (p *Provider) SetClock(clock IClock) {
+	p.clock = clock
+}
+
+// Discover returns the discovery document of the provider. The issuer in the
+// document must match the configured issuer.
+func (p *Provider) Discover() (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := new(Discovery)
+	err := p.getJSON(strings.TrimSuffix(p.Issuer, "/")+discoveryPath, d)
+	if err != nil {
+		return nil, err
+	} else if d.Issuer != p.Issuer || len(d.AuthorizationEndpoint) == 0 ||
+		len(d.TokenEndpoint) == 0 || len(d.JWKSURI) == 0 {
+		return nil, ErrDiscoveryInvalid
+	}
+
+	p.discovery = d
+	return d, nil
+}
+
+// AuthCodeURL returns the URL of the provider that the user is sent to. The
+// challenge is the S256 PKCE challenge of the code verifier.
+func (p *Provider) AuthCodeURL(state, nonce, challenge string) (string, error) {
+	d, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", "openid "+strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange will exchange an authorization code for the tokens and return the
+// ID token.
+func (p *Provider) Exchange(code, verifier string) (string, error) {
+	d, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	r, err := http.NewRequest("POST", d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+	r.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	resp, err := p.client.Do(r)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&body)
+	if err != nil && resp.StatusCode == http.StatusOK {
+		return "", err
+	} else if len(body.Error) > 0 {
+		return "", fmt.Errorf("code exchange failed: %v %v", body.Error, body.ErrorDescription)
+	} else if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("code exchange failed: %v", resp.Status)
+	} else if len(body.IDToken) == 0 {
+		return "", errors.New("code exchange failed: id_token is missing")
+	}
+
+	return body.IDToken, nil
+}
+
+// Verify will check the signature of the ID token with the keys of the
+// provider and check that the token was issued to this client for the nonce.
+func (p *Provider) Verify(raw, nonce string) (*IDToken, error) {
+	d, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := new(idClaims)
+	_, err = jwt.ParseWithClaims(raw, claims, p.keyFunc)
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Inner != nil && ve.Errors&jwt.ValidationErrorUnverifiable != 0 {
+			return nil, ve.Inner
+		}
+		return nil, ErrTokenInvalid
+	}
+
+	now := p.clock.Now()
+	if claims.Issuer != d.Issuer || len(claims.Subject) == 0 {
+		return nil, ErrTokenInvalid
+	} else if !claims.Audience.contains(p.ClientID) {
+		return nil, ErrTokenInvalid
+	} else if len(claims.Audience) > 1 && claims.AuthorizedBy != p.ClientID {
+		return nil, ErrTokenInvalid
+	} else if claims.ExpiresAt == 0 || !now.Before(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return nil, ErrTokenInvalid
+	} else if claims.IssuedAt == 0 || now.Add(leeway).Before(time.Unix(claims.IssuedAt, 0)) {
+		return nil, ErrTokenInvalid
+	} else if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceInvalid
+	}
+
+	return &IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+	}, nil
+}
+
+// contains returns true if the audience includes the value.
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// keyFunc returns the key that verifies the ID token. The keys are fetched
+// again once if the key ID is not known in case the provider rotated them.
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, err := p.key(kid, false)
+	if err == webtoken.ErrKeyNotFound {
+		key, err = p.key(kid, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm must match the key so a public key cannot be used as an
+	// HMAC secret.
+	var method jwt.SigningMethod
+	switch key.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		method = webtoken.SigningMethodEdDSA
+	}
+	if method == nil || token.Method.Alg() != method.Alg() {
+		return nil, webtoken.ErrAlgorithmInvalid
+	}
+
+	return key, nil
+}
+
+// key returns the public key with the key ID. A token without a key ID can
+// only be verified if the provider has a single key.
+func (p *Provider) key(kid string, refresh bool) (interface{}, error) {
+	d, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys == nil || refresh {
+		var set struct {
+			Keys []webtoken.JSONWebKey `json:"keys"`
+		}
+		if err = p.getJSON(d.JWKSURI, &set); err != nil {
+			return nil, err
+		}
+
+		// Skip the keys that are not for signatures or are not supported.
+		keys := make(map[string]interface{})
+		for _, k := range set.Keys {
+			if len(k.Use) > 0 && k.Use != "sig" {
+				continue
+			}
+			if key, err := k.PublicKey(); err == nil {
+				keys[k.Kid] = key
+			}
+		}
+		p.keys = keys
+	}
+
+	if len(kid) == 0 && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, nil
+		}
+	}
+
+	key, found := p.keys[kid]
+	if !found {
+		return nil, webtoken.ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// getJSON will decode the JSON response of a GET request.
+func (p *Provider) getJSON(target string, v interface{}) error {
+	resp, err := p.client.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %v failed: %v", target, resp.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(v)
+}
@@ -0,0 +1,159 @@
+package oidc_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/oidc"
+	"app/webapi/pkg/webtoken"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type NowFn func() time.Time
+
+type MockClock struct {
+	nowfn NowFn
+}
+
+func (c *MockClock) SetNow(fn NowFn) {
+	c.nowfn = fn
+}
+
+func (c *MockClock) Now() time.Time {
+	if c.nowfn == nil {
+		return time.Now()
+	}
+	return c.nowfn()
+}
+
+// verifier is the PKCE code verifier from RFC 7636.
+const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func newProvider(fake *testutil.OIDCProvider) *oidc.Provider {
+	return oidc.New(oidc.Config{
+		Name:         "company",
+		Issuer:       fake.Issuer(),
+		ClientID:     fake.ClientID,
+		ClientSecret: fake.ClientSecret,
+		RedirectURL:  "http://localhost:8080/v1/auth/oidc/callback",
+	})
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestLogin(t *testing.T) {
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+
+	p := newProvider(fake)
+
+	authURL, err := p.AuthCodeURL("state", "nonce", challenge(verifier))
+	assert.Nil(t, err)
+
+	u, err := url.Parse(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.Equal(t, "nonce", u.Query().Get("nonce"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	callback, err := fake.Login(authURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "/v1/auth/oidc/callback", callback.Path)
+	assert.Equal(t, "state", callback.Query().Get("state"))
+
+	raw, err := p.Exchange(callback.Query().Get("code"), verifier)
+	assert.Nil(t, err)
+
+	id, err := p.Verify(raw, "nonce")
+	assert.Nil(t, err)
+	assert.Equal(t, fake.Issuer(), id.Issuer)
+	assert.Equal(t, "248289761001", id.Subject)
+	assert.Equal(t, "jsmith@example.com", id.Email)
+	assert.True(t, id.EmailVerified)
+	assert.Equal(t, "John", id.GivenName)
+	assert.Equal(t, "Smith", id.FamilyName)
+
+	// A code can only be exchanged once.
+	_, err = p.Exchange(callback.Query().Get("code"), verifier)
+	assert.NotNil(t, err)
+
+	// The verifier must match the challenge.
+	callback, err = fake.Login(authURL)
+	assert.Nil(t, err)
+	_, err = p.Exchange(callback.Query().Get("code"), strings.Repeat("a", 43))
+	assert.NotNil(t, err)
+}
+
+func TestDiscoverIssuer(t *testing.T) {
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+
+	// The issuer in the document must match exactly.
+	p := newProvider(fake)
+	p.Issuer = fake.Issuer() + "/"
+	_, err := p.Discover()
+	assert.Equal(t, oidc.ErrDiscoveryInvalid, err)
+
+	p = newProvider(fake)
+	d, err := p.Discover()
+	assert.Nil(t, err)
+	assert.Equal(t, fake.Issuer()+"/token", d.TokenEndpoint)
+}
+
+func TestVerify(t *testing.T) {
+	fake := testutil.NewOIDCProvider()
+	defer fake.Close()
+
+	p := newProvider(fake)
+
+	raw, err := fake.IDToken("nonce")
+	assert.Nil(t, err)
+
+	_, err = p.Verify(raw, "nonce")
+	assert.Nil(t, err)
+
+	// The nonce must match the login.
+	_, err = p.Verify(raw, "other")
+	assert.Equal(t, oidc.ErrNonceInvalid, err)
+
+	// The signature must be valid.
+	parts := strings.Split(raw, ".")
+	_, err = p.Verify(parts[0]+"."+parts[1]+".c2lnbmF0dXJl", "nonce")
+	assert.Equal(t, oidc.ErrTokenInvalid, err)
+
+	// The token must be issued to this client.
+	other := newProvider(fake)
+	other.ClientID = "other"
+	_, err = other.Verify(raw, "nonce")
+	assert.Equal(t, oidc.ErrTokenInvalid, err)
+
+	// The token must not be expired.
+	mc := new(MockClock)
+	mc.SetNow(func() time.Time {
+		return time.Now().Add(time.Hour)
+	})
+	p.SetClock(mc)
+	_, err = p.Verify(raw, "nonce")
+	assert.Equal(t, oidc.ErrTokenInvalid, err)
+
+	// A token signed with a key of another issuer.
+	secret := webtoken.New([]byte(strings.Repeat("s", 32)))
+	hs, err := secret.Generate(webtoken.Claims{
+		Subject:  fake.Subject,
+		Issuer:   fake.Issuer(),
+		Audience: []string{fake.ClientID},
+		Private:  map[string]interface{}{"nonce": "nonce"},
+	}, time.Hour)
+	assert.Nil(t, err)
+	_, err = newProvider(fake).Verify(hs, "nonce")
+	assert.NotNil(t, err)
+}
@@ -1,40 +1,146 @@
-// Package passhash provides password hashing functionality using bcrypt.
+// Package passhash provides password hashing functionality using bcrypt or
+// Argon2id.
 package passhash
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"app/webapi/pkg/securegen"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// New returns a password hashing tool.
+// Algorithms that can hash a password.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+const (
+	// argon2idPrefix starts every Argon2id hash.
+	argon2idPrefix = "$argon2id$"
+	// saltLength is the number of random bytes in an Argon2id salt.
+	saltLength = 16
+	// keyLength is the number of bytes in an Argon2id key.
+	keyLength = 32
+)
+
+var (
+	// ErrAlgorithmInvalid is when the algorithm is not supported.
+	ErrAlgorithmInvalid = errors.New("password hash algorithm is invalid")
+	// ErrHashInvalid is when a hash is not in the expected format.
+	ErrHashInvalid = errors.New("password hash is invalid")
+	// ErrCostInvalid is when the bcrypt cost is outside the allowed range.
+	ErrCostInvalid = fmt.Errorf("password hash cost must be from %v to %v", bcrypt.MinCost, bcrypt.MaxCost)
+)
+
+// Config contains the password hashing settings.
+type Config struct {
+	Algorithm string `json:"Algorithm"` // Algorithm of new hashes: bcrypt or argon2id, defaults to bcrypt.
+	Cost      int    `json:"Cost"`      // Work factor of bcrypt from 4 to 31, defaults to 10.
+	Memory    uint32 `json:"Memory"`    // Memory of Argon2id in KiB, defaults to 65536.
+	Time      uint32 `json:"Time"`      // Iterations of Argon2id, defaults to 3.
+	Threads   uint8  `json:"Threads"`   // Parallelism of Argon2id, defaults to 4.
+}
+
+// Validate returns an error if the settings cannot hash a password.
+func (c Config) Validate() error {
+	switch c.Algorithm {
+	case "", AlgorithmBcrypt, AlgorithmArgon2id:
+	default:
+		return ErrAlgorithmInvalid
+	}
+
+	// A cost of 0 uses the default.
+	if c.Cost != 0 && (c.Cost < bcrypt.MinCost || c.Cost > bcrypt.MaxCost) {
+		return ErrCostInvalid
+	}
+
+	return nil
+}
+
+// New returns a password hashing tool that uses bcrypt with the default
+// cost.
 func New() *Passhash {
-	return &Passhash{}
+	return &Passhash{
+		Config: Config{
+			Algorithm: AlgorithmBcrypt,
+			Cost:      bcrypt.DefaultCost,
+		},
+	}
+}
+
+// Passhash is a password hashing tool. New hashes use the settings in the
+// config and existing hashes of either algorithm can be matched.
+type Passhash struct {
+	Config
+}
+
+// cost returns the work factor of new bcrypt hashes.
+func (p *Passhash) cost() int {
+	if p.Cost < bcrypt.MinCost {
+		return bcrypt.DefaultCost
+	}
+	return p.Cost
 }
 
-// Passhash is a password hashing tool.
-type Passhash struct{}
+// params returns the parameters of new Argon2id hashes.
+func (p *Passhash) params() argon2Params {
+	ap := argon2Params{
+		memory:  p.Memory,
+		time:    p.Time,
+		threads: p.Threads,
+	}
+	if ap.memory == 0 {
+		ap.memory = 64 * 1024
+	}
+	if ap.time == 0 {
+		ap.time = 3
+	}
+	if ap.threads == 0 {
+		ap.threads = 4
+	}
+	return ap
+}
 
 // HashString returns a hashed string and an error.
 func (p *Passhash) HashString(password string) (string, error) {
-	key, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
+	switch p.Algorithm {
+	case "", AlgorithmBcrypt:
+		key, err := bcrypt.GenerateFromPassword([]byte(password), p.cost())
+		if err != nil {
+			return "", err
+		}
+		return string(key), nil
+	case AlgorithmArgon2id:
+		return hashArgon2id([]byte(password), p.params())
 	}
 
-	return string(key), nil
+	return "", ErrAlgorithmInvalid
 }
 
 // HashBytes returns a hashed byte array and an error.
 func (p *Passhash) HashBytes(password []byte) ([]byte, error) {
-	key, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	key, err := p.HashString(string(password))
 	if err != nil {
 		return nil, err
 	}
 
-	return key, nil
+	return []byte(key), nil
 }
 
-// MatchString returns true if the hash matches the password.
+// MatchString returns true if the hash matches the password. The algorithm
+// is read from the hash.
 func (p *Passhash) MatchString(hash, password string) bool {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		return matchArgon2id(hash, []byte(password))
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	if err == nil {
 		return true
@@ -45,10 +151,89 @@ func (p *Passhash) MatchString(hash, password string) bool {
 
 // MatchBytes returns true if the hash matches the password.
 func (p *Passhash) MatchBytes(hash, password []byte) bool {
-	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
-	if err == nil {
+	return p.MatchString(string(hash), string(password))
+}
+
+// NeedsRehash returns true if the hash was not created with the current
+// settings, like a hash with a lower cost or from another algorithm, so it
+// should be replaced the next time the password is available.
+func (p *Passhash) NeedsRehash(hash string) bool {
+	if p.Algorithm == AlgorithmArgon2id {
+		ap, _, _, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return ap != p.params()
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
 		return true
 	}
 
-	return false
+	return cost < p.cost()
+}
+
+// argon2Params are the parameters of an Argon2id hash.
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// hashArgon2id returns the PHC string of the password with a random salt,
+// like $argon2id$v=19$m=65536,t=3,p=4$salt$key.
+func hashArgon2id(password []byte, ap argon2Params) (string, error) {
+	salt, err := securegen.Bytes(saltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey(password, salt, ap.time, ap.memory, ap.threads, keyLength)
+
+	return fmt.Sprintf("%vv=%d$m=%d,t=%d,p=%d$%v$%v", argon2idPrefix,
+		argon2.Version, ap.memory, ap.time, ap.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// matchArgon2id returns true if the PHC string matches the password.
+func matchArgon2id(hash string, password []byte) bool {
+	ap, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey(password, salt, ap.time, ap.memory, ap.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+// decodeArgon2id returns the parameters, salt, and key from a PHC string.
+func decodeArgon2id(hash string) (ap argon2Params, salt []byte, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return ap, nil, nil, ErrHashInvalid
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return ap, nil, nil, ErrHashInvalid
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &ap.memory, &ap.time, &ap.threads)
+	if err != nil || ap.memory == 0 || ap.time == 0 || ap.threads == 0 {
+		return ap, nil, nil, ErrHashInvalid
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return ap, nil, nil, ErrHashInvalid
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return ap, nil, nil, ErrHashInvalid
+	}
+
+	return ap, salt, key, nil
 }
@@ -1,11 +1,13 @@
 package passhash_test
 
 import (
+	"strings"
 	"testing"
 
 	"app/webapi/pkg/passhash"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // TestStringString tests string to string hash.
@@ -62,3 +64,178 @@ func TestHashStringEmpty(t *testing.T) {
 	assert.Nil(t, err)
 	assert.True(t, ph.MatchString(hash, plainText))
 }
+
+// TestCost tests the hash uses the configured cost.
+func TestCost(t *testing.T) {
+	ph := passhash.New()
+	ph.Cost = bcrypt.MinCost + 1
+	hash, err := ph.HashString("This is a test.")
+	assert.Nil(t, err)
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	assert.Nil(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, cost)
+
+	// A cost below the minimum uses the default.
+	ph.Cost = 0
+	hash, err = ph.HashString("This is a test.")
+	assert.Nil(t, err)
+
+	cost, err = bcrypt.Cost([]byte(hash))
+	assert.Nil(t, err)
+	assert.Equal(t, bcrypt.DefaultCost, cost)
+}
+
+// TestValidate tests the settings are checked before a password is hashed.
+func TestValidate(t *testing.T) {
+	assert.Nil(t, passhash.Config{}.Validate())
+	assert.Nil(t, passhash.Config{Cost: bcrypt.MinCost}.Validate())
+	assert.Nil(t, passhash.Config{Cost: bcrypt.MaxCost}.Validate())
+	assert.Equal(t, passhash.ErrCostInvalid, passhash.Config{Cost: bcrypt.MinCost - 1}.Validate())
+	assert.Equal(t, passhash.ErrCostInvalid, passhash.Config{Cost: bcrypt.MaxCost + 1}.Validate())
+	assert.Equal(t, passhash.ErrCostInvalid, passhash.Config{Cost: -1}.Validate())
+
+	assert.Nil(t, passhash.Config{Algorithm: passhash.AlgorithmArgon2id}.Validate())
+	assert.Equal(t, passhash.ErrAlgorithmInvalid, passhash.Config{Algorithm: "scrypt"}.Validate())
+}
+
+// TestNeedsRehash tests a hash needs to be replaced when the cost is raised.
+func TestNeedsRehash(t *testing.T) {
+	ph := passhash.New()
+	ph.Cost = bcrypt.MinCost
+	hash, err := ph.HashString("This is a test.")
+	assert.Nil(t, err)
+	assert.False(t, ph.NeedsRehash(hash))
+
+	ph.Cost = bcrypt.MinCost + 1
+	assert.True(t, ph.NeedsRehash(hash))
+
+	// A lower cost does not replace the stronger hash.
+	ph.Cost = bcrypt.MinCost - 1
+	hash, err = ph.HashString("This is a test.")
+	assert.Nil(t, err)
+	ph.Cost = bcrypt.MinCost
+	assert.False(t, ph.NeedsRehash(hash))
+
+	// A hash from an unknown algorithm is replaced.
+	assert.True(t, ph.NeedsRehash("5f4dcc3b5aa765d61d8327deb882cf99"))
+}
+
+// TestArgon2id tests the Argon2id hash and that bcrypt hashes still match.
+func TestArgon2id(t *testing.T) {
+	bh, err := passhash.New().HashString("This is a test.")
+	assert.Nil(t, err)
+
+	ph := passhash.New()
+	ph.Algorithm = passhash.AlgorithmArgon2id
+	ph.Memory = 1024
+	ph.Time = 1
+	ph.Threads = 1
+
+	hash, err := ph.HashString("This is a test.")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, ph.MatchString(hash, "This is a test."))
+	assert.False(t, ph.MatchString(hash, "This is a test2."))
+
+	// Each hash has a different salt.
+	hash2, err := ph.HashString("This is a test.")
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, hash2)
+
+	// The algorithm is read from the hash.
+	assert.True(t, ph.MatchString(bh, "This is a test."))
+	assert.True(t, passhash.New().MatchString(hash, "This is a test."))
+
+	b, err := ph.HashBytes([]byte("This is a test."))
+	assert.Nil(t, err)
+	assert.True(t, ph.MatchBytes(b, []byte("This is a test.")))
+}
+
+// TestArgon2idRFC9106 tests a hash created by the reference implementation.
+func TestArgon2idRFC9106(t *testing.T) {
+	ph := passhash.New()
+	hash := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	assert.True(t, ph.MatchString(hash, "password"))
+	assert.False(t, ph.MatchString(hash, "Password"))
+}
+
+// TestArgon2idInvalid tests hashes that are not in the PHC format.
+func TestArgon2idInvalid(t *testing.T) {
+	ph := passhash.New()
+	for _, v := range []string{
+		"$argon2id$",
+		"$argon2id$v=18$m=1024,t=1,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=0,t=1,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+		"$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$",
+	} {
+		assert.False(t, ph.MatchString(v, "password"), v)
+	}
+
+	ph.Algorithm = "md5"
+	_, err := ph.HashString("password")
+	assert.Equal(t, passhash.ErrAlgorithmInvalid, err)
+}
+
+// TestNeedsRehashAlgorithm tests a hash needs to be replaced when the
+// algorithm or the Argon2id parameters change.
+func TestNeedsRehashAlgorithm(t *testing.T) {
+	bh, err := passhash.New().HashString("This is a test.")
+	assert.Nil(t, err)
+
+	ph := passhash.New()
+	ph.Algorithm = passhash.AlgorithmArgon2id
+	ph.Memory = 1024
+	ph.Time = 1
+	ph.Threads = 1
+	assert.True(t, ph.NeedsRehash(bh))
+
+	hash, err := ph.HashString("This is a test.")
+	assert.Nil(t, err)
+	assert.False(t, ph.NeedsRehash(hash))
+
+	ph.Time = 2
+	assert.True(t, ph.NeedsRehash(hash))
+
+	// Switching back to bcrypt replaces the Argon2id hash.
+	assert.True(t, passhash.New().NeedsRehash(hash))
+}
+
+// benchmarkArgon2id measures a hash with the parameters. Choose the largest
+// memory and time that keep a login under your target, like 250ms, on the
+// production hardware.
+func benchmarkArgon2id(b *testing.B, memory, time uint32, threads uint8) {
+	ph := passhash.New()
+	ph.Algorithm = passhash.AlgorithmArgon2id
+	ph.Memory = memory
+	ph.Time = time
+	ph.Threads = threads
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ph.HashString("This is a test."); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkArgon2idM19T2P1(b *testing.B)  { benchmarkArgon2id(b, 19*1024, 2, 1) }
+func BenchmarkArgon2idM46T1P1(b *testing.B)  { benchmarkArgon2id(b, 46*1024, 1, 1) }
+func BenchmarkArgon2idM64T3P4(b *testing.B)  { benchmarkArgon2id(b, 64*1024, 3, 4) }
+func BenchmarkArgon2idM128T3P4(b *testing.B) { benchmarkArgon2id(b, 128*1024, 3, 4) }
+func BenchmarkArgon2idM256T4P4(b *testing.B) { benchmarkArgon2id(b, 256*1024, 4, 4) }
+
+// benchmarkBcrypt measures a hash with the cost to compare with Argon2id.
+func benchmarkBcrypt(b *testing.B, cost int) {
+	ph := passhash.New()
+	ph.Cost = cost
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ph.HashString("This is a test."); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBcrypt10(b *testing.B) { benchmarkBcrypt(b, 10) }
+func BenchmarkBcrypt12(b *testing.B) { benchmarkBcrypt(b, 12) }
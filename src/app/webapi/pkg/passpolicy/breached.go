@@ -0,0 +1,88 @@
+package passpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefixLength is the number of hex characters of a SHA-1 hash that are used
+// to look up a range of hashes.
+const prefixLength = 5
+
+// IRange returns the suffixes of the SHA-1 hashes of breached passwords that
+// start with a prefix. Only the prefix of a hash is shared with the source so
+// it can be a remote service without revealing the password.
+type IRange interface {
+	Range(prefix string) ([]string, error)
+}
+
+// Breached returns true if the password is in the range source.
+func Breached(r IRange, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := r.Range(hash[:prefixLength])
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range suffixes {
+		if v == hash[prefixLength:] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// List is a range source that is loaded in memory.
+type List struct {
+	ranges map[string][]string
+}
+
+// LoadFile returns a list from a file with one uppercase or lowercase SHA-1
+// hash per line. Each line can end with a colon and the number of times the
+// password was seen, like the files from Have I Been Pwned. Blank lines and
+// lines that start with # are skipped.
+func LoadFile(name string) (*List, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &List{
+		ranges: make(map[string][]string),
+	}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if i := strings.Index(line, ":"); i >= 0 {
+			line = line[:i]
+		}
+
+		if _, err := hex.DecodeString(line); err != nil || len(line) != sha1.Size*2 {
+			return nil, fmt.Errorf("breached password file %v line %v is not a SHA-1 hash", name, n)
+		}
+
+		hash := strings.ToUpper(line)
+		prefix := hash[:prefixLength]
+		l.ranges[prefix] = append(l.ranges[prefix], hash[prefixLength:])
+	}
+
+	return l, scanner.Err()
+}
+
+// Range returns the suffixes of the hashes that start with the prefix.
+func (l *List) Range(prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
@@ -0,0 +1,151 @@
+// Package passpolicy provides a password strength policy with a check
+// against a list of breached passwords.
+package passpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrPersonal is when a password contains the name or email of the user.
+	ErrPersonal = errors.New("must not contain your name or email address")
+	// ErrBreached is when a password is in the list of breached passwords.
+	ErrBreached = errors.New("has appeared in a data breach, choose another one")
+)
+
+// Config contains the password policy settings.
+type Config struct {
+	MinLength    int    `json:"MinLength"`    // Minimum number of characters, defaults to 8.
+	MaxLength    int    `json:"MaxLength"`    // Maximum number of characters, defaults to 128.
+	MinClasses   int    `json:"MinClasses"`   // Character classes required from lowercase, uppercase, digits, and symbols, defaults to 1.
+	BreachedFile string `json:"BreachedFile"` // File of SHA-1 hashes of breached passwords, the check is skipped if empty.
+}
+
+// Policy checks the strength of passwords.
+type Policy struct {
+	config   Config
+	breached IRange
+}
+
+// New returns a policy from the config. The breached password file is
+// loaded if it is set.
+func New(c Config) (*Policy, error) {
+	p := &Policy{
+		config: c,
+	}
+
+	if len(c.BreachedFile) > 0 {
+		list, err := LoadFile(c.BreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		p.breached = list
+	}
+
+	return p, nil
+}
+
+// SetBreached will set the source of the breached passwords.
+func (p *Policy) SetBreached(r IRange) {
+	p.breached = r
+}
+
+// minLength returns the minimum number of characters.
+func (p *Policy) minLength() int {
+	if p.config.MinLength <= 0 {
+		return 8
+	}
+	return p.config.MinLength
+}
+
+// maxLength returns the maximum number of characters.
+func (p *Policy) maxLength() int {
+	if p.config.MaxLength <= 0 {
+		return 128
+	}
+	return p.config.MaxLength
+}
+
+// minClasses returns the number of character classes that are required.
+func (p *Policy) minClasses() int {
+	if p.config.MinClasses <= 0 {
+		return 1
+	} else if p.config.MinClasses > 4 {
+		return 4
+	}
+	return p.config.MinClasses
+}
+
+// Check returns an error if the password does not meet the policy. The
+// personal values, like the name and email of the user, must not be part of
+// the password. The error reads as a sentence after the field name, like
+// "password must be at least 8 characters".
+func (p *Policy) Check(password string, personal ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.minLength() {
+		return fmt.Errorf("must be at least %v characters", p.minLength())
+	} else if length > p.maxLength() {
+		return fmt.Errorf("must be at most %v characters", p.maxLength())
+	}
+
+	if classes(password) < p.minClasses() {
+		return fmt.Errorf("must contain %v of these: lowercase letters, "+
+			"uppercase letters, digits, and symbols", p.minClasses())
+	}
+
+	lower := strings.ToLower(password)
+	for _, v := range personalParts(personal) {
+		if strings.Contains(lower, v) {
+			return ErrPersonal
+		}
+	}
+
+	if p.breached != nil {
+		found, err := Breached(p.breached, password)
+		if err != nil {
+			return err
+		} else if found {
+			return ErrBreached
+		}
+	}
+
+	return nil
+}
+
+// classes returns the number of character classes in the password.
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// personalParts returns the lowercase parts of the personal values that are
+// long enough to check, like the name and the local part of an email.
+func personalParts(personal []string) []string {
+	parts := make([]string, 0)
+	for _, v := range personal {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if i := strings.Index(v, "@"); i > 0 {
+			v = v[:i]
+		}
+		if utf8.RuneCountInString(v) >= 3 {
+			parts = append(parts, v)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,140 @@
+package passpolicy_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"app/webapi/pkg/passpolicy"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// MockRange is a range source that returns an error.
+type MockRange struct {
+	err error
+}
+
+func (m *MockRange) Range(prefix string) ([]string, error) {
+	return nil, m.err
+}
+
+func TestCheck(t *testing.T) {
+	p, err := passpolicy.New(passpolicy.Config{
+		MinLength:  10,
+		MaxLength:  20,
+		MinClasses: 3,
+	})
+	assert.Nil(t, err)
+
+	for password, expected := range map[string]string{
+		"a":                     "must be at least 10 characters",
+		"Aa1!Aa1!Aa1!Aa1!Aa1!A": "must be at most 20 characters",
+		"abcdefghijkl":          "must contain 3 of these: lowercase letters, uppercase letters, digits, and symbols",
+		"abcdefghij12":          "must contain 3 of these: lowercase letters, uppercase letters, digits, and symbols",
+		"Abcdefghij12":          "",
+		"ébcdéfghîj1!":          "",
+	} {
+		err = p.Check(password)
+		if len(expected) == 0 {
+			assert.Nil(t, err, password)
+		} else if assert.NotNil(t, err, password) {
+			assert.Equal(t, expected, err.Error(), password)
+		}
+	}
+}
+
+func TestCheckDefaults(t *testing.T) {
+	p, err := passpolicy.New(passpolicy.Config{})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, p.Check("abcdefg"))
+	assert.Nil(t, p.Check("abcdefgh"))
+}
+
+func TestCheckPersonal(t *testing.T) {
+	p, err := passpolicy.New(passpolicy.Config{})
+	assert.Nil(t, err)
+
+	personal := []string{"John", "Smith", "jsmith@example.com", "Al"}
+
+	assert.Equal(t, passpolicy.ErrPersonal, p.Check("ILoveSmith99", personal...))
+	assert.Equal(t, passpolicy.ErrPersonal, p.Check("johnjohnjohn", personal...))
+	assert.Equal(t, passpolicy.ErrPersonal, p.Check("xJSMITHx2024", personal...))
+
+	// Short values are not checked.
+	assert.Nil(t, p.Check("always-pick-long-ones", personal...))
+}
+
+func TestBreached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passpolicy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The hashes of "password" and "P@ssw0rd".
+	name := filepath.Join(dir, "breached.txt")
+	err = ioutil.WriteFile(name, []byte("# Breached passwords.\n\n"+
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"+
+		"21bd12dc183f740ee76f27b78eb39c8ad972a757\n"), 0644)
+	assert.Nil(t, err)
+
+	l, err := passpolicy.LoadFile(name)
+	assert.Nil(t, err)
+
+	found, err := passpolicy.Breached(l, "password")
+	assert.Nil(t, err)
+	assert.True(t, found)
+
+	found, err = passpolicy.Breached(l, "P@ssw0rd")
+	assert.Nil(t, err)
+	assert.True(t, found)
+
+	found, err = passpolicy.Breached(l, "correct horse battery staple")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	// Only the prefix is used to get a range.
+	suffixes, err := l.Range("5baa6")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1E4C9B93F3F0682250B6CF8331B7EE68FD8"}, suffixes)
+
+	p, err := passpolicy.New(passpolicy.Config{
+		BreachedFile: name,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, passpolicy.ErrBreached, p.Check("password"))
+	assert.Nil(t, p.Check("correct horse battery staple"))
+
+	// The error of the source is returned.
+	p.SetBreached(&MockRange{err: errors.New("source is down")})
+	assert.EqualError(t, p.Check("password"), "source is down")
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passpolicy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "breached.txt")
+	err = ioutil.WriteFile(name, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\npassword\n"), 0644)
+	assert.Nil(t, err)
+
+	_, err = passpolicy.LoadFile(name)
+	assert.Contains(t, err.Error(), "line 2 is not a SHA-1 hash")
+
+	_, err = passpolicy.New(passpolicy.Config{
+		BreachedFile: filepath.Join(dir, "missing.txt"),
+	})
+	assert.NotNil(t, err)
+}
+
+func TestShippedFile(t *testing.T) {
+	l, err := passpolicy.LoadFile("../../../../../breached-passwords.txt")
+	assert.Nil(t, err)
+
+	found, err := passpolicy.Breached(l, "password")
+	assert.Nil(t, err)
+	assert.True(t, found)
+}
@@ -0,0 +1,312 @@
+package query
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCursorInvalid is when a cursor was not issued for the same sort or
+	// the signature does not match.
+	ErrCursorInvalid = errors.New("cursor is invalid")
+	// ErrDestInvalid is when the destination is not a pointer to a slice of
+	// structs with the sort and primary key fields.
+	ErrDestInvalid = errors.New("destination must be a pointer to a slice of structs with the sort field")
+)
+
+// CursorPage is a part of the records that match the filters, before or after
+// a cursor. The sort column should not contain NULL values.
+type CursorPage struct {
+	Filters []Filter // Conditions that every record must match.
+	Sort    string   // Column to sort by, the primary key if empty.
+	Desc    bool     // Sort in descending order.
+	Limit   int      // Maximum number of records.
+	After   string   // Cursor of the record before the page.
+	Before  string   // Cursor of the record after the page, used if After is empty.
+}
+
+// Cursors are the cursors of the pages next to a page. A cursor is empty if
+// there is no page.
+type Cursors struct {
+	Next string // Use as After to get the next page.
+	Prev string // Use as Before to get the previous page.
+}
+
+// cursor is the position of a record in a sort order.
+type cursor struct {
+	Sort  string `json:"s"`           // Column of the sort.
+	Desc  bool   `json:"d,omitempty"` // Sort is in descending order.
+	Value value  `json:"v"`           // Value of the sort column.
+	Key   value  `json:"k"`           // Value of the primary key.
+}
+
+// value is a column value that keeps its type in JSON.
+type value struct {
+	Type string `json:"t"`
+	Data string `json:"d"`
+}
+
+// newValue returns the value of a field.
+func newValue(v reflect.Value) (value, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return value{}, ErrDestInvalid
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return value{Type: "t", Data: t.UTC().Format(time.RFC3339Nano)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return value{Type: "s", Data: v.String()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value{Type: "i", Data: fmt.Sprint(v.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value{Type: "i", Data: fmt.Sprint(v.Uint())}, nil
+	}
+
+	return value{}, ErrDestInvalid
+}
+
+// arg returns the value as a query argument.
+func (v value) arg() (interface{}, error) {
+	switch v.Type {
+	case "t":
+		t, err := time.Parse(time.RFC3339Nano, v.Data)
+		if err != nil {
+			return nil, ErrCursorInvalid
+		}
+		return t, nil
+	case "s", "i":
+		return v.Data, nil
+	}
+	return nil, ErrCursorInvalid
+}
+
+// encodeCursor returns the signed cursor.
+func (q *Q) encodeCursor(c cursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + q.sign(payload), nil
+}
+
+// decodeCursor returns the cursor if the signature matches and it was issued
+// for the same sort.
+func (q *Q) decodeCursor(s, sort string, desc bool) (*cursor, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(q.sign(parts[0]))) {
+		return nil, ErrCursorInvalid
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+
+	c := new(cursor)
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, ErrCursorInvalid
+	} else if c.Sort != sort || c.Desc != desc {
+		return nil, ErrCursorInvalid
+	}
+
+	return c, nil
+}
+
+// sign returns the signature of the payload.
+func (q *Q) sign(payload string) string {
+	mac := hmac.New(sha256.New, q.cursorKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// FindCursor returns the records that match the filters of the page, sorted
+// and limited to the page, and the cursors of the pages next to it. Records
+// with the same sort value are sorted by the primary key so no record is
+// skipped or repeated between pages. The destination must be a pointer to a
+// slice of structs with db tags.
+func (q *Q) FindCursor(dest IRecord, page CursorPage) (cursors Cursors, err error) {
+	sort := page.Sort
+	if len(sort) == 0 {
+		sort = dest.PrimaryKey()
+	}
+
+	// Get the page before the cursor by reversing the order.
+	backward := len(page.After) == 0 && len(page.Before) > 0
+	p := Page{
+		Filters: page.Filters,
+		Sort:    sort,
+		Desc:    page.Desc != backward,
+	}
+
+	where, args, err := p.where()
+	if err != nil {
+		return cursors, err
+	}
+	where = q.scope(dest, where)
+
+	orderBy, err := p.orderBy(dest.PrimaryKey())
+	if err != nil {
+		return cursors, err
+	}
+
+	// Start after the position of the cursor.
+	position := page.After
+	if backward {
+		position = page.Before
+	}
+	if len(position) > 0 {
+		c, err := q.decodeCursor(position, sort, page.Desc)
+		if err != nil {
+			return cursors, err
+		}
+
+		condition, seekArgs, err := seek(sort, dest.PrimaryKey(), p.Desc, c)
+		if err != nil {
+			return cursors, err
+		}
+
+		where = and(where, condition)
+		args = append(args, seekArgs...)
+	}
+
+	// Get one more record to know if there is another page.
+	args = append(args, page.Limit+1)
+	err = q.db.Select(dest, fmt.Sprintf(`
+		SELECT * FROM %s
+		%s
+		%s
+		LIMIT ?`, dest.Table(), where, orderBy),
+		args...)
+	if err != nil {
+		return cursors, err
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return cursors, ErrDestInvalid
+	}
+	v = v.Elem()
+
+	more := v.Len() > page.Limit
+	if more {
+		v.Set(v.Slice(0, page.Limit))
+	}
+
+	if backward {
+		swap := reflect.Swapper(v.Interface())
+		for i, j := 0, v.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if v.Len() == 0 {
+		return cursors, nil
+	}
+
+	first, err := q.recordCursor(v.Index(0), sort, dest.PrimaryKey(), page.Desc)
+	if err != nil {
+		return cursors, err
+	}
+
+	last, err := q.recordCursor(v.Index(v.Len()-1), sort, dest.PrimaryKey(), page.Desc)
+	if err != nil {
+		return cursors, err
+	}
+
+	if backward {
+		cursors.Next = last
+		if more {
+			cursors.Prev = first
+		}
+	} else {
+		if more {
+			cursors.Next = last
+		}
+		if len(page.After) > 0 {
+			cursors.Prev = first
+		}
+	}
+
+	return cursors, nil
+}
+
+// seek returns the condition that only matches the records after the cursor
+// in the order. Records with the same sort value are compared by the primary
+// key.
+func seek(sort, primaryKey string, desc bool, c *cursor) (string, []interface{}, error) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	sortArg, err := c.Value.arg()
+	if err != nil {
+		return "", nil, err
+	}
+
+	keyArg, err := c.Key.arg()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if sort == primaryKey {
+		return fmt.Sprintf("%s %s ?", primaryKey, op), []interface{}{keyArg}, nil
+	}
+
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", sort, op, sort, primaryKey, op),
+		[]interface{}{sortArg, sortArg, keyArg}, nil
+}
+
+// recordCursor returns the cursor of a record.
+func (q *Q) recordCursor(record reflect.Value, sort, primaryKey string, desc bool) (string, error) {
+	sortValue, err := fieldValue(record, sort)
+	if err != nil {
+		return "", err
+	}
+
+	keyValue, err := fieldValue(record, primaryKey)
+	if err != nil {
+		return "", err
+	}
+
+	return q.encodeCursor(cursor{
+		Sort:  sort,
+		Desc:  desc,
+		Value: sortValue,
+		Key:   keyValue,
+	})
+}
+
+// fieldValue returns the value of the field of a struct with the db tag.
+func fieldValue(record reflect.Value, column string) (value, error) {
+	for record.Kind() == reflect.Ptr {
+		record = record.Elem()
+	}
+	if record.Kind() != reflect.Struct {
+		return value{}, ErrDestInvalid
+	}
+
+	t := record.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("db"), ",")[0] == column {
+			return newValue(record.Field(i))
+		}
+	}
+
+	return value{}, ErrDestInvalid
+}
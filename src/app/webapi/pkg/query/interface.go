@@ -1,6 +1,9 @@
 package query
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
 // IDatabase provides data query capabilities.
 type IDatabase interface {
@@ -15,3 +18,27 @@ type IRecord interface {
 	Table() string
 	PrimaryKey() string
 }
+
+// ISoftDelete is a record that is marked as deleted instead of being removed
+// from the table. The field is NULL until the record is deleted.
+type ISoftDelete interface {
+	IRecord
+	DeletedField() string
+}
+
+// IQuery provides default queries. WithDeleted returns the same queries so
+// they can be used through an interface that includes the deleted records.
+type IQuery interface {
+	FindOneByID(dest IRecord, ID string) (found bool, err error)
+	FindOneByField(dest IRecord, field string, value string) (found bool, err error)
+	FindAll(dest IRecord) (total int, err error)
+	FindPage(dest IRecord, page Page) (total int, err error)
+	FindCursor(dest IRecord, page CursorPage) (cursors Cursors, err error)
+	ExistsByID(db IRecord, s string) (found bool, err error)
+	ExistsByField(db IRecord, field string, value string) (found bool, ID string, err error)
+	DeleteOneByID(dest IRecord, ID string) (affected int, err error)
+	DeleteAll(dest IRecord) (affected int, err error)
+	Restore(dest IRecord, ID string) (affected int, err error)
+	Purge(dest IRecord, retention time.Duration) (affected int, err error)
+	WithDeleted() IQuery
+}
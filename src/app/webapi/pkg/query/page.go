@@ -0,0 +1,92 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrFieldInvalid is when a field is not a plain column name.
+	ErrFieldInvalid = errors.New("field is invalid")
+	// ErrOperatorInvalid is when a filter operator is not supported.
+	ErrOperatorInvalid = errors.New("operator is invalid")
+)
+
+// fieldPattern matches the column names that can be used in a query.
+var fieldPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// operators are the supported filter operators.
+var operators = map[string]bool{
+	"=":    true,
+	"<>":   true,
+	"<":    true,
+	"<=":   true,
+	">":    true,
+	">=":   true,
+	"LIKE": true,
+}
+
+// Filter is a condition on a field of a record.
+type Filter struct {
+	Field    string      // Column to compare.
+	Operator string      // One of =, <>, <, <=, >, >=, or LIKE.
+	Value    interface{} // Value to compare the column to.
+}
+
+// Page is a part of the records that match the filters. The fields are
+// column names so only pass fields from a whitelist.
+type Page struct {
+	Filters []Filter // Conditions that every record must match.
+	Sort    string   // Column to sort by, the primary key if empty.
+	Desc    bool     // Sort in descending order.
+	Limit   int      // Maximum number of records, all records if 0.
+	Offset  int      // Number of records to skip.
+}
+
+// where returns the WHERE clause and the arguments of the filters.
+func (p Page) where() (string, []interface{}, error) {
+	if len(p.Filters) == 0 {
+		return "", nil, nil
+	}
+
+	conditions := make([]string, 0, len(p.Filters))
+	args := make([]interface{}, 0, len(p.Filters))
+	for _, f := range p.Filters {
+		if !fieldPattern.MatchString(f.Field) {
+			return "", nil, ErrFieldInvalid
+		} else if !operators[f.Operator] {
+			return "", nil, ErrOperatorInvalid
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s ?", f.Field, f.Operator))
+		args = append(args, f.Value)
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// orderBy returns the ORDER BY clause. The primary key is always included so
+// the order is stable between pages.
+func (p Page) orderBy(primaryKey string) (string, error) {
+	direction := "ASC"
+	if p.Desc {
+		direction = "DESC"
+	}
+
+	if len(p.Sort) == 0 || p.Sort == primaryKey {
+		return fmt.Sprintf("ORDER BY %s %s", primaryKey, direction), nil
+	} else if !fieldPattern.MatchString(p.Sort) {
+		return "", ErrFieldInvalid
+	}
+
+	return fmt.Sprintf("ORDER BY %s %s, %s %s", p.Sort, direction, primaryKey, direction), nil
+}
+
+// EscapeLike returns the value with the LIKE wildcards escaped so it only
+// matches the value itself.
+func EscapeLike(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `%`, `\%`, -1)
+	return strings.Replace(s, `_`, `\_`, -1)
+}
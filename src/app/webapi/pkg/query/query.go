@@ -1,19 +1,43 @@
 package query
 
 import (
+	"crypto/rand"
 	"fmt"
 )
 
-// New returns a new query object.
+// New returns a new query object. The cursors are signed with a random key
+// until a key is set so they are only valid until a restart. It panics if the
+// random key cannot be read.
 func New(db IDatabase) *Q {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("query: cursor key could not be generated: %v", err))
+	}
+
 	return &Q{
-		db: db,
+		db:        db,
+		cursorKey: key,
 	}
 }
 
-// Q is a database wrapper that provides helpful utilities.
+// Config contains the query settings.
+type Config struct {
+	CursorSecret []byte `json:"CursorSecret"` // Base64 key that signs the page cursors, a random key per process if empty.
+}
+
+// Q is a database wrapper that provides helpful utilities. Records that
+// implement ISoftDelete are marked as deleted instead of being removed and the
+// deleted records are not found.
 type Q struct {
-	db IDatabase
+	db          IDatabase
+	cursorKey   []byte
+	withDeleted bool
+}
+
+// SetCursorKey sets the key that signs the page cursors so they stay valid
+// across restarts and between servers.
+func (q *Q) SetCursorKey(key []byte) {
+	q.cursorKey = key
 }
 
 // *****************************************************************************
@@ -24,27 +48,76 @@ type Q struct {
 func (q *Q) FindOneByID(dest IRecord, ID string) (exists bool, err error) {
 	err = q.db.Get(dest, fmt.Sprintf(`
 		SELECT * FROM %s
-		WHERE %s = ?
-		LIMIT 1`, dest.Table(), dest.PrimaryKey()),
+		%s
+		LIMIT 1`, dest.Table(), q.scope(dest, "WHERE "+dest.PrimaryKey()+" = ?")),
 		ID)
 	return recordExists(err)
 }
 
+// FindOneByField will find a record by a specified field.
+func (q *Q) FindOneByField(dest IRecord, field string, value string) (exists bool, err error) {
+	err = q.db.Get(dest, fmt.Sprintf(`
+		SELECT * FROM %s
+		%s
+		LIMIT 1`, dest.Table(), q.scope(dest, "WHERE "+field+" = ?")),
+		value)
+	return recordExists(err)
+}
+
 // FindAll returns all users.
 func (q *Q) FindAll(dest IRecord) (total int, err error) {
-	//TODO: Add in something to handle soft deletes.
-	//WHERE deleted_at IS NULL
+	where := q.scope(dest, "")
 
 	err = q.db.QueryRowScan(&total, fmt.Sprintf(`
 		SELECT COUNT(DISTINCT %s)
 		FROM %s
-		`, dest.PrimaryKey(), dest.Table()))
+		%s
+		`, dest.PrimaryKey(), dest.Table(), where))
 
 	if err != nil {
 		return total, suppressNoRowsError(err)
 	}
 
-	err = q.db.Select(dest, fmt.Sprintf(`SELECT * FROM %s`, dest.Table()))
+	err = q.db.Select(dest, fmt.Sprintf(`SELECT * FROM %s %s`, dest.Table(), where))
+	return total, err
+}
+
+// FindPage returns the records that match the filters of the page, sorted and
+// limited to the page, and the total number of records that match.
+func (q *Q) FindPage(dest IRecord, page Page) (total int, err error) {
+	where, args, err := page.where()
+	if err != nil {
+		return 0, err
+	}
+	where = q.scope(dest, where)
+
+	orderBy, err := page.orderBy(dest.PrimaryKey())
+	if err != nil {
+		return 0, err
+	}
+
+	err = q.db.QueryRowScan(&total, fmt.Sprintf(`
+		SELECT COUNT(DISTINCT %s)
+		FROM %s
+		%s
+		`, dest.PrimaryKey(), dest.Table(), where),
+		args...)
+	if err != nil {
+		return total, suppressNoRowsError(err)
+	}
+
+	limit := ""
+	if page.Limit > 0 {
+		limit = "LIMIT ? OFFSET ?"
+		args = append(args, page.Limit, page.Offset)
+	}
+
+	err = q.db.Select(dest, fmt.Sprintf(`
+		SELECT * FROM %s
+		%s
+		%s
+		%s`, dest.Table(), where, orderBy, limit),
+		args...)
 	return total, err
 }
 
@@ -52,10 +125,17 @@ func (q *Q) FindAll(dest IRecord) (total int, err error) {
 // Delete
 // *****************************************************************************
 
-// DeleteOneByID removes one record by ID.
+// DeleteOneByID removes one record by ID. A record that supports soft deletes
+// is marked as deleted instead.
 func (q *Q) DeleteOneByID(dest IRecord, ID string) (affected int, err error) {
-	result, err := q.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ? LIMIT 1",
-		dest.Table(), dest.PrimaryKey()), ID)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? LIMIT 1",
+		dest.Table(), dest.PrimaryKey())
+	if field, ok := deletedField(dest); ok {
+		query = fmt.Sprintf("UPDATE %s SET %s = NOW() WHERE %s = ? AND %s IS NULL LIMIT 1",
+			dest.Table(), field, dest.PrimaryKey(), field)
+	}
+
+	result, err := q.db.Exec(query, ID)
 	if err != nil {
 		return 0, err
 	}
@@ -63,9 +143,16 @@ func (q *Q) DeleteOneByID(dest IRecord, ID string) (affected int, err error) {
 	return affectedRows(result), err
 }
 
-// DeleteAll removes all records.
+// DeleteAll removes all records. Records that support soft deletes are marked
+// as deleted instead.
 func (q *Q) DeleteAll(dest IRecord) (affected int, err error) {
-	result, err := q.db.Exec(fmt.Sprintf(`DELETE FROM %s`, dest.Table()))
+	query := fmt.Sprintf(`DELETE FROM %s`, dest.Table())
+	if field, ok := deletedField(dest); ok {
+		query = fmt.Sprintf(`UPDATE %s SET %s = NOW() WHERE %s IS NULL`,
+			dest.Table(), field, field)
+	}
+
+	result, err := q.db.Exec(query)
 	if err != nil {
 		return 0, err
 	}
@@ -81,8 +168,8 @@ func (q *Q) DeleteAll(dest IRecord) (affected int, err error) {
 func (q *Q) ExistsByID(db IRecord, value string) (found bool, err error) {
 	err = q.db.Get(db, fmt.Sprintf(`
 		SELECT %s FROM %s
-		WHERE %s = ?
-		LIMIT 1`, db.PrimaryKey(), db.Table(), db.PrimaryKey()),
+		%s
+		LIMIT 1`, db.PrimaryKey(), db.Table(), q.scope(db, "WHERE "+db.PrimaryKey()+" = ?")),
 		value)
 	return recordExists(err)
 }
@@ -92,8 +179,8 @@ func (q *Q) ExistsByID(db IRecord, value string) (found bool, err error) {
 func (q *Q) ExistsByField(db IRecord, field string, value string) (found bool, ID string, err error) {
 	err = q.db.QueryRowScan(&ID, fmt.Sprintf(`
 		SELECT %s FROM %s
-		WHERE %s = ?
-		LIMIT 1`, db.PrimaryKey(), db.Table(), field),
+		%s
+		LIMIT 1`, db.PrimaryKey(), db.Table(), q.scope(db, "WHERE "+field+" = ?")),
 		value)
 
 	return recordExistsString(err, ID)
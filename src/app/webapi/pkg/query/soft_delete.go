@@ -0,0 +1,86 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSoftDeleteUnsupported is when a record does not support soft deletes.
+var ErrSoftDeleteUnsupported = errors.New("record does not support soft deletes")
+
+// WithDeleted returns a copy of the query object that includes the soft
+// deleted records when finding records.
+func (q *Q) WithDeleted() IQuery {
+	c := *q
+	c.withDeleted = true
+	return &c
+}
+
+// deletedField returns the soft delete field of the record and true if the
+// record supports soft deletes.
+func deletedField(dest IRecord) (string, bool) {
+	sd, ok := dest.(ISoftDelete)
+	if !ok {
+		return "", false
+	}
+	return sd.DeletedField(), true
+}
+
+// scope returns the WHERE clause with a condition that excludes the soft
+// deleted records unless they are included.
+func (q *Q) scope(dest IRecord, where string) string {
+	field, ok := deletedField(dest)
+	if !ok || q.withDeleted {
+		return where
+	}
+	return and(where, field+" IS NULL")
+}
+
+// and returns the WHERE clause with the condition added.
+func and(where, condition string) string {
+	if len(where) == 0 {
+		return "WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+// Restore will undo the soft delete of a record by ID.
+func (q *Q) Restore(dest IRecord, ID string) (affected int, err error) {
+	field, ok := deletedField(dest)
+	if !ok {
+		return 0, ErrSoftDeleteUnsupported
+	}
+
+	result, err := q.db.Exec(fmt.Sprintf(`
+		UPDATE %s
+		SET %s = NULL
+		WHERE %s = ?
+		AND %s IS NOT NULL
+		LIMIT 1`, dest.Table(), field, dest.PrimaryKey(), field),
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), err
+}
+
+// Purge will remove the records that were soft deleted longer than the
+// retention ago.
+func (q *Q) Purge(dest IRecord, retention time.Duration) (affected int, err error) {
+	field, ok := deletedField(dest)
+	if !ok {
+		return 0, ErrSoftDeleteUnsupported
+	}
+
+	result, err := q.db.Exec(fmt.Sprintf(`
+		DELETE FROM %s
+		WHERE %s < DATE_SUB(NOW(), INTERVAL ? SECOND)`, dest.Table(), field),
+		int(retention.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), err
+}
@@ -0,0 +1,123 @@
+// Package rbac provides role-based access control using the roles and
+// permissions that are stored in MySQL.
+package rbac
+
+import (
+	"sync"
+	"time"
+)
+
+// IDatabase provides data query capabilities.
+type IDatabase interface {
+	Select(dest interface{}, query string, args ...interface{}) error
+}
+
+// IClock provides clock capabilities.
+type IClock interface {
+	Now() time.Time
+}
+
+// clock is the standard system clock.
+type clock struct{}
+
+// Now returns the current time.
+func (c *clock) Now() time.Time {
+	return time.Now()
+}
+
+// Policy determines which permissions are granted to each role. The
+// permissions are cached in memory and reloaded from the database once the
+// reload interval has passed.
+type Policy struct {
+	db    IDatabase
+	clock IClock
+
+	mutex    sync.RWMutex
+	roles    map[string]map[string]bool
+	interval time.Duration
+	loadedAt time.Time
+}
+
+// New returns a new policy.
+func New(db IDatabase) *Policy {
+	return &Policy{
+		db:       db,
+		clock:    new(clock),
+		interval: 5 * time.Minute,
+	}
+}
+
+// SetClock will set the clock.
+func (p *Policy) SetClock(clock IClock) {
+	p.clock = clock
+}
+
+// SetReloadInterval will set how often the permissions are reloaded.
+func (p *Policy) SetReloadInterval(d time.Duration) {
+	p.interval = d
+}
+
+// Allowed returns true if any of the roles is granted the permission.
+func (p *Policy) Allowed(roles []string, permission string) (bool, error) {
+	if err := p.reloadIfDue(); err != nil {
+		return false, err
+	}
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for _, role := range roles {
+		if p.roles[role][permission] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Reload will load the permissions of every role from the database.
+func (p *Policy) Reload() error {
+	rows := make([]struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}, 0)
+
+	err := p.db.Select(&rows, `
+		SELECT role.name AS role, permission.name AS permission
+		FROM role_permission
+		INNER JOIN role ON role.id = role_permission.role_id
+		INNER JOIN permission ON permission.id = role_permission.permission_id
+		`)
+	if err != nil {
+		return err
+	}
+
+	roles := make(map[string]map[string]bool)
+	for _, v := range rows {
+		if _, found := roles[v.Role]; !found {
+			roles[v.Role] = make(map[string]bool)
+		}
+		roles[v.Role][v.Permission] = true
+	}
+
+	p.mutex.Lock()
+	p.roles = roles
+	p.loadedAt = p.clock.Now()
+	p.mutex.Unlock()
+
+	return nil
+}
+
+// reloadIfDue will reload the permissions if they were never loaded or the
+// reload interval has passed.
+func (p *Policy) reloadIfDue() error {
+	p.mutex.RLock()
+	due := p.roles == nil || p.clock.Now().Sub(p.loadedAt) >= p.interval
+	p.mutex.RUnlock()
+
+	if due {
+		return p.Reload()
+	}
+
+	return nil
+}
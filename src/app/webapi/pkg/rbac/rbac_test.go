@@ -0,0 +1,37 @@
+package rbac_test
+
+import (
+	"testing"
+
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/rbac"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	p := rbac.New(db)
+
+	for _, v := range []struct {
+		roles      []string
+		permission string
+		allowed    bool
+	}{
+		{[]string{"admin"}, "user:delete_all", true},
+		{[]string{"admin"}, "role:assign", true},
+		{[]string{"user"}, "user:read", true},
+		{[]string{"user"}, "user:delete_all", false},
+		{[]string{"user", "admin"}, "user:delete_all", true},
+		{[]string{"unknown"}, "user:read", false},
+		{nil, "user:read", false},
+		{[]string{"admin"}, "unknown", false},
+	} {
+		allowed, err := p.Allowed(v.roles, v.permission)
+		assert.Nil(t, err)
+		assert.Equal(t, v.allowed, allowed, "%v %v", v.roles, v.permission)
+	}
+
+	testutil.TeardownDatabase(unique)
+}
@@ -0,0 +1,222 @@
+// Package revocation provides a list of revoked token IDs that is stored in
+// MySQL and cached in memory.
+package revocation
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// IDatabase provides data query capabilities.
+type IDatabase interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Get(dest interface{}, query string, args ...interface{}) error
+}
+
+// IClock provides clock capabilities.
+type IClock interface {
+	Now() time.Time
+}
+
+// clock is the standard system clock.
+type clock struct{}
+
+// Now returns the current time.
+func (c *clock) Now() time.Time {
+	return time.Now()
+}
+
+// List is a list of revoked token IDs. Revoked IDs are cached in memory once
+// they are found so only IDs that are not revoked require a database lookup.
+// This allows a token revoked on one server to be rejected by every server.
+// The list also stores the time before which all the tokens of a user are
+// revoked.
+type List struct {
+	db    IDatabase
+	clock IClock
+
+	mutex     sync.RWMutex
+	cache     map[string]time.Time
+	users     map[string]time.Time
+	interval  time.Duration
+	lastPurge time.Time
+}
+
+// New returns a new revocation list.
+func New(db IDatabase) *List {
+	return &List{
+		db:       db,
+		clock:    new(clock),
+		cache:    make(map[string]time.Time),
+		users:    make(map[string]time.Time),
+		interval: 1 * time.Hour,
+	}
+}
+
+// SetClock will set the clock.
+func (l *List) SetClock(clock IClock) {
+	l.clock = clock
+}
+
+// SetPurgeInterval will set how often the expired entries are removed.
+func (l *List) SetPurgeInterval(d time.Duration) {
+	l.interval = d
+}
+
+// Revoke will add a token ID to the list until the token expires.
+func (l *List) Revoke(tokenID string, expiresAt time.Time) error {
+	l.purgeIfDue()
+
+	// The token is already expired so there is nothing to revoke.
+	if !expiresAt.After(l.clock.Now()) {
+		return nil
+	}
+
+	_, err := l.db.Exec(`
+		INSERT INTO token_revocation
+		(jti, expires_at)
+		VALUES
+		(?, FROM_UNIXTIME(?))
+		ON DUPLICATE KEY UPDATE jti = jti
+		`,
+		tokenID, expiresAt.Unix())
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	l.cache[tokenID] = expiresAt
+	l.mutex.Unlock()
+
+	return nil
+}
+
+// IsRevoked returns true if the token ID is in the list.
+func (l *List) IsRevoked(tokenID string) (bool, error) {
+	l.purgeIfDue()
+
+	// Check the cache first.
+	l.mutex.RLock()
+	expiresAt, found := l.cache[tokenID]
+	l.mutex.RUnlock()
+	if found {
+		return true, nil
+	}
+
+	// Check the database.
+	var unix int64
+	err := l.db.Get(&unix, `
+		SELECT UNIX_TIMESTAMP(expires_at) FROM token_revocation
+		WHERE jti = ?
+		LIMIT 1`,
+		tokenID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	expiresAt = time.Unix(unix, 0)
+
+	l.mutex.Lock()
+	l.cache[tokenID] = expiresAt
+	l.mutex.Unlock()
+
+	return true, nil
+}
+
+// RevokeUser will revoke every token of the user that was issued at or before
+// the time. Since tokens store the issue time in seconds, a token issued in
+// the same second as the revocation is also revoked.
+func (l *List) RevokeUser(userID string, revokedAt time.Time) error {
+	_, err := l.db.Exec(`
+		INSERT INTO user_revocation
+		(user_id, revoked_at)
+		VALUES
+		(?, FROM_UNIXTIME(?))
+		ON DUPLICATE KEY UPDATE revoked_at = GREATEST(revoked_at, VALUES(revoked_at))
+		`,
+		userID, revokedAt.Unix())
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	if revokedAt.After(l.users[userID]) {
+		l.users[userID] = revokedAt
+	}
+	l.mutex.Unlock()
+
+	return nil
+}
+
+// IsUserRevoked returns true if the tokens of the user that were issued at
+// the time are revoked.
+func (l *List) IsUserRevoked(userID string, issuedAt time.Time) (bool, error) {
+	// Check the cache first. A later revocation may be in the database so a
+	// token that is not revoked by the cache still requires a lookup.
+	l.mutex.RLock()
+	revokedAt, found := l.users[userID]
+	l.mutex.RUnlock()
+	if found && !issuedAt.After(revokedAt) {
+		return true, nil
+	}
+
+	// Check the database.
+	var unix int64
+	err := l.db.Get(&unix, `
+		SELECT UNIX_TIMESTAMP(revoked_at) FROM user_revocation
+		WHERE user_id = ?
+		LIMIT 1`,
+		userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	revokedAt = time.Unix(unix, 0)
+
+	l.mutex.Lock()
+	l.users[userID] = revokedAt
+	l.mutex.Unlock()
+
+	return !issuedAt.After(revokedAt), nil
+}
+
+// Purge will remove the entries for tokens that have expired. An expired
+// token is rejected by the signature check so it no longer needs to be in the
+// list.
+func (l *List) Purge() error {
+	now := l.clock.Now()
+
+	l.mutex.Lock()
+	l.lastPurge = now
+	for k, v := range l.cache {
+		if !v.After(now) {
+			delete(l.cache, k)
+		}
+	}
+	l.mutex.Unlock()
+
+	_, err := l.db.Exec(`
+		DELETE FROM token_revocation
+		WHERE expires_at <= FROM_UNIXTIME(?)
+		`,
+		now.Unix())
+	return err
+}
+
+// purgeIfDue will purge the list if the purge interval has passed.
+func (l *List) purgeIfDue() {
+	l.mutex.RLock()
+	due := l.clock.Now().Sub(l.lastPurge) >= l.interval
+	l.mutex.RUnlock()
+
+	if due {
+		// An error here is not fatal, the entries will be removed on the next
+		// purge.
+		l.Purge()
+	}
+}
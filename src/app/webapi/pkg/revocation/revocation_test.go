@@ -0,0 +1,160 @@
+package revocation_test
+
+import (
+	"testing"
+	"time"
+
+	"app/webapi/internal/testutil"
+	"app/webapi/pkg/query"
+	"app/webapi/pkg/revocation"
+	"app/webapi/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type NowFn func() time.Time
+
+type MockClock struct {
+	nowfn NowFn
+}
+
+func (c *MockClock) SetNow(fn NowFn) {
+	c.nowfn = fn
+}
+
+func (c *MockClock) Now() time.Time {
+	if c.nowfn == nil {
+		return time.Now()
+	}
+	return c.nowfn()
+}
+
+func TestRevoke(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	l := revocation.New(db)
+
+	revoked, err := l.IsRevoked("jti")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	err = l.Revoke("jti", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	revoked, err = l.IsRevoked("jti")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// Revoking twice is not an error.
+	err = l.Revoke("jti", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	// A different list sharing the database sees the revocation.
+	l2 := revocation.New(db)
+	revoked, err = l2.IsRevoked("jti")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestRevokeExpired(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	l := revocation.New(db)
+
+	err := l.Revoke("jti", time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+
+	revoked, err := l.IsRevoked("jti")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestPurge(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	mc := new(MockClock)
+	now := time.Now()
+	mc.SetNow(func() time.Time {
+		return now
+	})
+
+	l := revocation.New(db)
+	l.SetClock(mc)
+
+	err := l.Revoke("jti1", now.Add(1*time.Hour))
+	assert.Nil(t, err)
+	err = l.Revoke("jti2", now.Add(3*time.Hour))
+	assert.Nil(t, err)
+
+	// Move the clock past the first expiration.
+	mc.SetNow(func() time.Time {
+		return now.Add(2 * time.Hour)
+	})
+
+	err = l.Purge()
+	assert.Nil(t, err)
+
+	count := 0
+	err = db.Get(&count, `SELECT COUNT(*) FROM token_revocation`)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	revoked, err := l.IsRevoked("jti1")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = l.IsRevoked("jti2")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	testutil.TeardownDatabase(unique)
+}
+
+func TestRevokeUser(t *testing.T) {
+	db, unique := testutil.LoadDatabase()
+
+	u := store.NewUser(db, query.New(db))
+	userID, err := u.Create("John", "Smith", "jsmith@example.com", "password")
+	assert.Nil(t, err)
+
+	now := time.Now()
+	l := revocation.New(db)
+
+	revoked, err := l.IsUserRevoked(userID, now)
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	err = l.RevokeUser(userID, now)
+	assert.Nil(t, err)
+
+	// Tokens issued before the revocation are revoked.
+	revoked, err = l.IsUserRevoked(userID, now.Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// Tokens issued after the revocation are not.
+	revoked, err = l.IsUserRevoked(userID, now.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	// Tokens issued in the same second as the revocation are revoked.
+	revoked, err = l.IsUserRevoked(userID, now.Truncate(time.Second))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// An earlier revocation does not replace a later one.
+	err = l.RevokeUser(userID, now.Add(-time.Hour))
+	assert.Nil(t, err)
+
+	// A different list sharing the database sees the revocation.
+	l2 := revocation.New(db)
+	revoked, err = l2.IsUserRevoked(userID, now.Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	testutil.TeardownDatabase(unique)
+}
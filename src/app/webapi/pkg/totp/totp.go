@@ -0,0 +1,163 @@
+// Package totp provides time-based one-time passwords (RFC 6238) that are
+// compatible with authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"app/webapi/pkg/securegen"
+)
+
+// ErrSecretInvalid is when a secret is not valid base32.
+var ErrSecretInvalid = errors.New("totp secret is invalid")
+
+// encoding is the base32 encoding used by authenticator apps.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// IClock provides clock capabilities.
+type IClock interface {
+	Now() time.Time
+}
+
+// clock is the standard system clock.
+type clock struct{}
+
+// Now returns the current time.
+func (c *clock) Now() time.Time {
+	return time.Now()
+}
+
+// TOTP generates and validates codes with HMAC-SHA1 and a 30 second period.
+type TOTP struct {
+	clock  IClock
+	digits int
+	skew   int64
+}
+
+// New returns a TOTP that generates 6 digit codes and accepts the codes from
+// one period before and after the current period to allow for clock drift.
+func New() *TOTP {
+	return &TOTP{
+		clock:  new(clock),
+		digits: 6,
+		skew:   1,
+	}
+}
+
+// SetClock will set the clock.
+func (t *TOTP) SetClock(clock IClock) {
+	t.clock = clock
+}
+
+// SetDigits will set the number of digits in a code.
+func (t *TOTP) SetDigits(digits int) {
+	t.digits = digits
+}
+
+// NewSecret returns a random 160 bit secret encoded as base32.
+func NewSecret() (string, error) {
+	b, err := securegen.Bytes(20)
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Step returns the number of periods since the Unix epoch.
+func Step(at time.Time) int64 {
+	return at.Unix() / 30
+}
+
+// Code returns the code for the secret at the time.
+func (t *TOTP) Code(secret string, at time.Time) (string, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return "", err
+	}
+	return t.hotp(key, Step(at)), nil
+}
+
+// Match returns the step of the code if it is valid for the secret at the
+// current time. The step should be stored and only later steps accepted so a
+// code cannot be used twice.
+func (t *TOTP) Match(secret, code string) (int64, bool, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != t.digits {
+		return 0, false, nil
+	}
+
+	current := Step(t.clock.Now())
+	for step := current - t.skew; step <= current+t.skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(t.hotp(key, step)), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI returns the otpauth URI that authenticator apps read from a QR code.
+func (t *TOTP) URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(t.digits))
+	v.Set("period", "30")
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+// hotp returns the HMAC-based one-time password (RFC 4226) for the counter.
+func (t *TOTP) hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	h := hmac.New(sha1.New, key)
+	h.Write(msg)
+	sum := h.Sum(nil)
+
+	// Dynamic truncation.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < t.digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", t.digits, value%mod)
+}
+
+// decode returns the key from a base32 secret. Spaces and padding are
+// ignored and the secret is not case sensitive.
+func decode(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	s = strings.TrimRight(s, "=")
+
+	key, err := encoding.DecodeString(s)
+	if err != nil || len(key) == 0 {
+		return nil, ErrSecretInvalid
+	}
+	return key, nil
+}
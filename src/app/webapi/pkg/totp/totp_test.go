@@ -0,0 +1,131 @@
+package totp_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"app/webapi/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type NowFn func() time.Time
+
+type MockClock struct {
+	nowfn NowFn
+}
+
+func (c *MockClock) SetNow(fn NowFn) {
+	c.nowfn = fn
+}
+
+func (c *MockClock) Now() time.Time {
+	if c.nowfn == nil {
+		return time.Now()
+	}
+	return c.nowfn()
+}
+
+// secret is the base32 encoding of the RFC 6238 SHA1 test secret,
+// 12345678901234567890.
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	tp := totp.New()
+	tp.SetDigits(8)
+
+	for unix, expected := range map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	} {
+		code, err := tp.Code(secret, time.Unix(unix, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, code, unix)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	mc := new(MockClock)
+	now := time.Unix(1111111111, 0)
+	mc.SetNow(func() time.Time {
+		return now
+	})
+
+	tp := totp.New()
+	tp.SetClock(mc)
+
+	code, err := tp.Code(secret, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(code))
+
+	step, ok, err := tp.Match(secret, code)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// The code from the previous period is accepted for clock drift.
+	mc.SetNow(func() time.Time {
+		return now.Add(30 * time.Second)
+	})
+	step, ok, err = tp.Match(secret, code)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// The code expires after two periods.
+	mc.SetNow(func() time.Time {
+		return now.Add(60 * time.Second)
+	})
+	_, ok, err = tp.Match(secret, code)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// A wrong code is rejected.
+	_, ok, err = tp.Match(secret, "000000x")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestSecret(t *testing.T) {
+	s, err := totp.NewSecret()
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(s))
+
+	s2, err := totp.NewSecret()
+	assert.Nil(t, err)
+	assert.NotEqual(t, s, s2)
+
+	// Secrets are not case sensitive and may contain spaces.
+	tp := totp.New()
+	now := time.Now()
+	code, err := tp.Code("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", now)
+	assert.Nil(t, err)
+	code2, err := tp.Code(secret, now)
+	assert.Nil(t, err)
+	assert.Equal(t, code2, code)
+
+	_, err = tp.Code("not base32!", now)
+	assert.Equal(t, totp.ErrSecretInvalid, err)
+	_, _, err = tp.Match("", "123456")
+	assert.Equal(t, totp.ErrSecretInvalid, err)
+}
+
+func TestURI(t *testing.T) {
+	tp := totp.New()
+	s := tp.URI("webapi", "jsmith@example.com", secret)
+
+	u, err := url.Parse(s)
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/webapi:jsmith@example.com", u.Path)
+	assert.Equal(t, secret, u.Query().Get("secret"))
+	assert.Equal(t, "webapi", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+	assert.Equal(t, "30", u.Query().Get("period"))
+}
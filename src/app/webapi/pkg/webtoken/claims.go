@@ -0,0 +1,179 @@
+package webtoken
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// registered are the names of the claims that are set by the package and
+// cannot be overwritten by private claims.
+var registered = []string{"jti", "sub", "iss", "aud", "exp", "nbf", "iat", "scope", "roles", "client_id", "sid"}
+
+// Claims contains the claims of a token.
+type Claims struct {
+	ID        string                 // Unique ID of the token, generated if empty.
+	Subject   string                 // User the token was issued to.
+	Issuer    string                 // Service that issued the token, defaults to the configured issuer.
+	Audience  []string               // Services the token is intended for, defaults to the configured audience.
+	IssuedAt  time.Time              // Time the token was issued, set when generated.
+	NotBefore time.Time              // Time the token is valid from, set when generated.
+	ExpiresAt time.Time              // Time the token expires, set when generated.
+	Scopes    []string               // Scopes granted to the token.
+	Roles     []string               // Roles of the user when the token was issued.
+	ClientID  string                 // OAuth client the token was issued to.
+	SessionID string                 // Session the token was issued in.
+	Private   map[string]interface{} // Additional claims stored in the token.
+}
+
+// HasAudience returns true if the token is intended for the audience.
+func (c *Claims) HasAudience(audience string) bool {
+	for _, v := range c.Audience {
+		if v == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// audience is a list of audiences that is stored as a string when there is
+// only one value.
+type audience []string
+
+// MarshalJSON will marshal the audience as a string or an array.
+func (a audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON will unmarshal the audience from a string or an array.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var arr []string
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+	*a = audience(arr)
+	return nil
+}
+
+// standardClaims are the registered claims stored in the token.
+type standardClaims struct {
+	ID        string   `json:"jti,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+}
+
+// tokenClaims are the claims stored in the token.
+type tokenClaims struct {
+	standardClaims
+	Private map[string]interface{}
+}
+
+// newTokenClaims returns the claims to store in the token.
+func newTokenClaims(c Claims) *tokenClaims {
+	return &tokenClaims{
+		standardClaims: standardClaims{
+			ID:        c.ID,
+			Subject:   c.Subject,
+			Issuer:    c.Issuer,
+			Audience:  audience(c.Audience),
+			ExpiresAt: c.ExpiresAt.Unix(),
+			NotBefore: c.NotBefore.Unix(),
+			IssuedAt:  c.IssuedAt.Unix(),
+			Scope:     strings.Join(c.Scopes, " "),
+			Roles:     c.Roles,
+			ClientID:  c.ClientID,
+			SessionID: c.SessionID,
+		},
+		Private: c.Private,
+	}
+}
+
+// Valid always passes because the claims are validated by the configuration
+// after the signature is verified.
+func (t *tokenClaims) Valid() error {
+	return nil
+}
+
+// MarshalJSON will marshal the private claims and the registered claims
+// into a single object.
+func (t *tokenClaims) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(t.standardClaims)
+	if err != nil || len(t.Private) == 0 {
+		return b, err
+	}
+
+	m := make(map[string]interface{})
+	for k, v := range t.Private {
+		m[k] = v
+	}
+	for _, k := range registered {
+		delete(m, k)
+	}
+
+	// Add the registered claims.
+	var std map[string]json.RawMessage
+	if err = json.Unmarshal(b, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		m[k] = v
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON will unmarshal the registered claims and store the rest as
+// private claims.
+func (t *tokenClaims) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &t.standardClaims); err != nil {
+		return err
+	}
+
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	for _, k := range registered {
+		delete(m, k)
+	}
+	if len(m) > 0 {
+		t.Private = m
+	}
+
+	return nil
+}
+
+// claims returns the claims of the token.
+func (t *tokenClaims) claims() *Claims {
+	return &Claims{
+		ID:        t.ID,
+		Subject:   t.Subject,
+		Issuer:    t.Issuer,
+		Audience:  []string(t.Audience),
+		IssuedAt:  time.Unix(t.IssuedAt, 0),
+		NotBefore: time.Unix(t.NotBefore, 0),
+		ExpiresAt: time.Unix(t.ExpiresAt, 0),
+		Scopes:    strings.Fields(t.Scope),
+		Roles:     t.Roles,
+		ClientID:  t.ClientID,
+		SessionID: t.SessionID,
+		Private:   t.Private,
+	}
+}
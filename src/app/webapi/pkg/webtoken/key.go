@@ -0,0 +1,308 @@
+package webtoken
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrKeyNotFound is when a token is signed with an unknown key.
+	ErrKeyNotFound = errors.New("signing key was not found")
+	// ErrKeyUnsupported is when a key type or size is not supported.
+	ErrKeyUnsupported = errors.New("signing key is not supported")
+	// ErrKeyPrivateMissing is when a key without a private key is used to sign.
+	ErrKeyPrivateMissing = errors.New("signing key has no private key")
+	// ErrAlgorithmInvalid is when a token is signed with an unexpected
+	// algorithm.
+	ErrAlgorithmInvalid = errors.New("signing algorithm is invalid")
+)
+
+// KeyConfig contains the location of an asymmetric signing key.
+type KeyConfig struct {
+	ID             string `json:"ID"`             // Key ID that is stored in the kid header of the token.
+	PrivateKeyFile string `json:"PrivateKeyFile"` // PEM encoded private key, required to sign tokens.
+	PublicKeyFile  string `json:"PublicKeyFile"`  // PEM encoded public key, used when the key only verifies tokens.
+}
+
+// JSONWebKey is a public key in the JSON Web Key format.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// signingKey is a key that verifies and optionally signs tokens.
+type signingKey struct {
+	method     jwt.SigningMethod
+	privateKey interface{}
+	publicKey  interface{}
+}
+
+// AddKey will add an asymmetric key that is identified by the key ID. The
+// key can be an RSA (RS256), ECDSA P-256 (ES256), or Ed25519 (EdDSA) private
+// or public key. Keys that are public only can verify tokens, but not sign
+// them, which allows old keys to keep working while keys are rotated.
+func (c *Configuration) AddKey(kid string, key interface{}) error {
+	k := new(signingKey)
+
+	switch v := key.(type) {
+	case *rsa.PrivateKey:
+		k.privateKey, k.publicKey = v, &v.PublicKey
+	case *rsa.PublicKey:
+		k.publicKey = v
+	case *ecdsa.PrivateKey:
+		k.privateKey, k.publicKey = v, &v.PublicKey
+	case *ecdsa.PublicKey:
+		k.publicKey = v
+	case ed25519.PrivateKey:
+		k.privateKey, k.publicKey = v, v.Public()
+	case ed25519.PublicKey:
+		k.publicKey = v
+	default:
+		return ErrKeyUnsupported
+	}
+
+	switch v := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		if v.N.BitLen() < 2048 {
+			return ErrKeyUnsupported
+		}
+		k.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if v.Curve != elliptic.P256() {
+			return ErrKeyUnsupported
+		}
+		k.method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		k.method = SigningMethodEdDSA
+	}
+
+	if c.keys == nil {
+		c.keys = make(map[string]*signingKey)
+	}
+	c.keys[kid] = k
+
+	return nil
+}
+
+// AddKeyFile will add an asymmetric key from a PEM encoded file. The private
+// key is used if both files are set.
+func (c *Configuration) AddKeyFile(kc KeyConfig) error {
+	filename := kc.PrivateKeyFile
+	if len(filename) == 0 {
+		filename = kc.PublicKeyFile
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	key, err := parsePEM(b)
+	if err != nil {
+		return err
+	}
+
+	return c.AddKey(kc.ID, key)
+}
+
+// LoadKeys will add the keys from the PEM encoded files and then set the key
+// used to sign new tokens.
+func (c *Configuration) LoadKeys(keys []KeyConfig, signingKeyID string) error {
+	for _, kc := range keys {
+		if err := c.AddKeyFile(kc); err != nil {
+			return fmt.Errorf("key %v: %v", kc.ID, err)
+		}
+	}
+
+	return c.SetSigningKey(signingKeyID)
+}
+
+// SetSigningKey will set the key used to sign new tokens. If the key ID is
+// empty, tokens are signed with the secret using HS256.
+func (c *Configuration) SetSigningKey(kid string) error {
+	if len(kid) > 0 {
+		k, found := c.keys[kid]
+		if !found {
+			return ErrKeyNotFound
+		} else if k.privateKey == nil {
+			return ErrKeyPrivateMissing
+		}
+	}
+
+	c.signingKeyID = kid
+	return nil
+}
+
+// JWKS returns the public keys that verify tokens so other services can
+// verify the tokens without the private keys.
+func (c *Configuration) JWKS() []JSONWebKey {
+	kids := make([]string, 0, len(c.keys))
+	for kid := range c.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	arr := make([]JSONWebKey, 0, len(kids))
+	for _, kid := range kids {
+		k := c.keys[kid]
+		jwk := JSONWebKey{
+			Kid: kid,
+			Use: "sig",
+			Alg: k.method.Alg(),
+		}
+
+		switch v := k.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBytes(v.N.Bytes())
+			jwk.E = encodeBytes(big.NewInt(int64(v.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = encodeBytes(v.X.FillBytes(make([]byte, 32)))
+			jwk.Y = encodeBytes(v.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encodeBytes(v)
+		}
+
+		arr = append(arr, jwk)
+	}
+
+	return arr
+}
+
+// PublicKey returns the RSA, ECDSA P-256, or Ed25519 public key of the JSON
+// Web Key.
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBytes(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBytes(k.E)
+		if err != nil {
+			return nil, err
+		} else if len(e) == 0 || len(e) > 4 {
+			return nil, ErrKeyUnsupported
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrKeyUnsupported
+		}
+		x, err := decodeBytes(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBytes(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrKeyUnsupported
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, ErrKeyUnsupported
+		}
+		x, err := decodeBytes(k.X)
+		if err != nil {
+			return nil, err
+		} else if len(x) != ed25519.PublicKeySize {
+			return nil, ErrKeyUnsupported
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, ErrKeyUnsupported
+}
+
+// keyFunc returns the key that verifies the token. Tokens with a key ID must
+// use the algorithm of the key. Tokens without a key ID must use HS256 and
+// are verified with the secret.
+func (c *Configuration) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if len(kid) == 0 {
+		if token.Method != jwt.SigningMethodHS256 || len(c.Secret) < 32 {
+			return nil, ErrAlgorithmInvalid
+		}
+		return []byte(c.Secret), nil
+	}
+
+	k, found := c.keys[kid]
+	if !found {
+		return nil, ErrKeyNotFound
+	} else if token.Method.Alg() != k.method.Alg() {
+		return nil, ErrAlgorithmInvalid
+	}
+
+	return k.publicKey, nil
+}
+
+// parsePEM returns the first key in the PEM encoded bytes.
+func parsePEM(b []byte) (interface{}, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, ErrKeyUnsupported
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	return nil, ErrKeyUnsupported
+}
+
+// encodeBytes returns the bytes encoded as unpadded base64url.
+func encodeBytes(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeBytes returns the bytes from unpadded base64url.
+func decodeBytes(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrKeyUnsupported
+	}
+	return b, nil
+}
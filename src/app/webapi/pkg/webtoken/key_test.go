@@ -0,0 +1,290 @@
+package webtoken_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"app/webapi/pkg/webtoken"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	for _, v := range []struct {
+		kid string
+		key interface{}
+		alg string
+	}{
+		{"rsa", rsaKey, "RS256"},
+		{"ec", ecKey, "ES256"},
+		{"ed", edKey, "EdDSA"},
+	} {
+		token := webtoken.New(nil)
+		assert.Nil(t, token.AddKey(v.kid, v.key))
+		assert.Nil(t, token.SetSigningKey(v.kid))
+
+		ss, err := token.Generate(webtoken.Claims{Subject: "jsmith", Roles: []string{"user"}}, 1*time.Hour)
+		assert.Nil(t, err, v.alg)
+
+		// The header contains the algorithm and the key ID.
+		parsed, _, err := new(jwt.Parser).ParseUnverified(ss, jwt.MapClaims{})
+		assert.Nil(t, err)
+		assert.Equal(t, v.alg, parsed.Header["alg"])
+		assert.Equal(t, v.kid, parsed.Header["kid"])
+
+		claims, err := token.Verify(ss)
+		assert.Nil(t, err, v.alg)
+		assert.Equal(t, "jsmith", claims.Subject)
+		assert.Equal(t, []string{"user"}, claims.Roles)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	// Sign a token with the old key.
+	token := webtoken.New(nil)
+	assert.Nil(t, token.AddKey("old", oldKey))
+	assert.Nil(t, token.SetSigningKey("old"))
+	ss, err := token.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+
+	// Rotate to the new key and keep only the public part of the old key.
+	token2 := webtoken.New(nil)
+	assert.Nil(t, token2.AddKey("old", oldKey.Public()))
+	assert.Nil(t, token2.AddKey("new", newKey))
+	assert.Equal(t, webtoken.ErrKeyPrivateMissing, token2.SetSigningKey("old"))
+	assert.Nil(t, token2.SetSigningKey("new"))
+
+	// Tokens signed with either key are valid.
+	_, err = token2.Verify(ss)
+	assert.Nil(t, err)
+	ss2, err := token2.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+	_, err = token2.Verify(ss2)
+	assert.Nil(t, err)
+
+	// Tokens signed with a removed key are not valid.
+	token3 := webtoken.New(nil)
+	assert.Nil(t, token3.AddKey("new", newKey))
+	_, err = token3.Verify(ss)
+	assert.Equal(t, webtoken.ErrKeyNotFound, err)
+	assert.Equal(t, webtoken.ErrKeyNotFound, token3.SetSigningKey("old"))
+}
+
+func TestHS256Migration(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	// Sign a token with the secret.
+	ss, err := webtoken.New(secret).Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+
+	// The token is valid while the secret is still set.
+	token := webtoken.New(secret)
+	assert.Nil(t, token.AddKey("ed", edKey))
+	assert.Nil(t, token.SetSigningKey("ed"))
+	_, err = token.Verify(ss)
+	assert.Nil(t, err)
+
+	// The token is not valid once the secret is removed.
+	token2 := webtoken.New(nil)
+	assert.Nil(t, token2.AddKey("ed", edKey))
+	_, err = token2.Verify(ss)
+	assert.Equal(t, webtoken.ErrAlgorithmInvalid, err)
+}
+
+func TestAlgorithmMismatch(t *testing.T) {
+	secret := []byte("0123456789ABCDEF0123456789ABCDEF")
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	token := webtoken.New(secret)
+	assert.Nil(t, token.AddKey("ed", edKey))
+
+	// A token signed with HS256 cannot claim to use the Ed25519 key.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+		Id:        "1",
+		Audience:  "jsmith",
+		NotBefore: time.Now().Unix(),
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	})
+	forged.Header["kid"] = "ed"
+	ss, err := forged.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+	assert.Nil(t, err)
+
+	_, err = token.Verify(ss)
+	assert.Equal(t, webtoken.ErrAlgorithmInvalid, err)
+}
+
+func TestUnsupportedKeys(t *testing.T) {
+	token := webtoken.New(nil)
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+	assert.Equal(t, webtoken.ErrKeyUnsupported, token.AddKey("rsa", smallKey))
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+	assert.Equal(t, webtoken.ErrKeyUnsupported, token.AddKey("ec", p384Key))
+
+	assert.Equal(t, webtoken.ErrKeyUnsupported, token.AddKey("secret", []byte("secret")))
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	token := webtoken.New(nil)
+	assert.Nil(t, token.AddKey("c", rsaKey))
+	assert.Nil(t, token.AddKey("b", ecKey))
+	assert.Nil(t, token.AddKey("a", edKey))
+
+	keys := token.JWKS()
+	assert.Equal(t, 3, len(keys))
+
+	assert.Equal(t, "a", keys[0].Kid)
+	assert.Equal(t, "OKP", keys[0].Kty)
+	assert.Equal(t, "Ed25519", keys[0].Crv)
+	assert.Equal(t, "EdDSA", keys[0].Alg)
+	assert.Equal(t, jwt.EncodeSegment(edPublic), keys[0].X)
+
+	assert.Equal(t, "b", keys[1].Kid)
+	assert.Equal(t, "EC", keys[1].Kty)
+	assert.Equal(t, "P-256", keys[1].Crv)
+	assert.Equal(t, "ES256", keys[1].Alg)
+	assert.Equal(t, 43, len(keys[1].X))
+	assert.Equal(t, 43, len(keys[1].Y))
+
+	assert.Equal(t, "c", keys[2].Kid)
+	assert.Equal(t, "RSA", keys[2].Kty)
+	assert.Equal(t, "RS256", keys[2].Alg)
+	assert.Equal(t, "AQAB", keys[2].E)
+	assert.Equal(t, 342, len(keys[2].N))
+
+	for _, k := range keys {
+		assert.Equal(t, "sig", k.Use)
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	signer := webtoken.New(nil)
+	assert.Nil(t, signer.AddKey("rsa", rsaKey))
+	assert.Nil(t, signer.AddKey("ec", ecKey))
+	assert.Nil(t, signer.AddKey("ed", edKey))
+
+	// Another service can verify the tokens with only the published keys.
+	verifier := webtoken.New(nil)
+	for _, k := range signer.JWKS() {
+		key, err := k.PublicKey()
+		assert.Nil(t, err, k.Kid)
+		assert.Nil(t, verifier.AddKey(k.Kid, key), k.Kid)
+	}
+
+	for _, kid := range []string{"rsa", "ec", "ed"} {
+		assert.Nil(t, signer.SetSigningKey(kid))
+		ss, err := signer.Generate(webtoken.Claims{Subject: "jsmith"}, time.Hour)
+		assert.Nil(t, err)
+
+		claims, err := verifier.Verify(ss)
+		assert.Nil(t, err, kid)
+		assert.Equal(t, "jsmith", claims.Subject)
+	}
+
+	key, err := signer.JWKS()[2].PublicKey()
+	assert.Nil(t, err)
+	assert.Equal(t, &rsaKey.PublicKey, key)
+
+	// Keys that are not supported.
+	for _, k := range []webtoken.JSONWebKey{
+		{Kty: "oct", Kid: "a"},
+		{Kty: "EC", Kid: "b", Crv: "P-384"},
+		{Kty: "EC", Kid: "c", Crv: "P-256", X: "AQ", Y: "AQ"},
+		{Kty: "OKP", Kid: "d", Crv: "Ed25519", X: "AQ"},
+		{Kty: "RSA", Kid: "e", N: "not base64!", E: "AQAB"},
+	} {
+		_, err = k.PublicKey()
+		assert.Equal(t, webtoken.ErrKeyUnsupported, err, k.Kid)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webtoken")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	// Write the private and public keys.
+	b, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.Nil(t, err)
+	privateFile := filepath.Join(dir, "private.pem")
+	err = ioutil.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), 0600)
+	assert.Nil(t, err)
+
+	b, err = x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err)
+	publicFile := filepath.Join(dir, "public.pem")
+	err = ioutil.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}), 0644)
+	assert.Nil(t, err)
+
+	token := webtoken.New(nil)
+	err = token.LoadKeys([]webtoken.KeyConfig{
+		{ID: "private", PrivateKeyFile: privateFile},
+		{ID: "public", PublicKeyFile: publicFile},
+	}, "private")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(token.JWKS()))
+
+	ss, err := token.Generate(webtoken.Claims{Subject: "jsmith"}, 1*time.Hour)
+	assert.Nil(t, err)
+	_, err = token.Verify(ss)
+	assert.Nil(t, err)
+
+	// A missing file is an error.
+	err = webtoken.New(nil).LoadKeys([]webtoken.KeyConfig{
+		{ID: "missing", PrivateKeyFile: filepath.Join(dir, "missing.pem")},
+	}, "")
+	assert.NotNil(t, err)
+
+	// A public key cannot sign tokens.
+	err = webtoken.New(nil).LoadKeys([]webtoken.KeyConfig{
+		{ID: "public", PublicKeyFile: publicFile},
+	}, "public")
+	assert.Equal(t, webtoken.ErrKeyPrivateMissing, err)
+}
@@ -22,6 +22,8 @@ var (
 	ErrSignatureInvalid = errors.New("signature is invalid")
 	// ErrAudienceInvalid is when the audience is invalid.
 	ErrAudienceInvalid = errors.New("audience is invalid")
+	// ErrIDInvalid is when the token ID is invalid.
+	ErrIDInvalid = errors.New("token ID is invalid")
 	// ErrExpirationInvalid is when the expiration is invalid.
 	ErrExpirationInvalid = errors.New("expiration is invalid")
 	// ErrIssuedAtInvalid is when the issued date is invalid.
@@ -30,6 +32,10 @@ var (
 	ErrNotBeforeInvalid = errors.New("before date is invalid")
 	// ErrSecretTooShort is when the secret is not long enough.
 	ErrSecretTooShort = errors.New("secret must be 256 bit (32 bytes)")
+	// ErrSubjectInvalid is when the subject is invalid.
+	ErrSubjectInvalid = errors.New("subject is invalid")
+	// ErrIssuerInvalid is when the issuer is invalid.
+	ErrIssuerInvalid = errors.New("issuer is invalid")
 )
 
 // SecretKey is a byte array.
@@ -52,8 +58,17 @@ func (k *SecretKey) UnmarshalJSON(b []byte) error {
 
 // Configuration contains the JWT dependencies.
 type Configuration struct {
-	clock  IClock
-	Secret SecretKey `json:"Secret"`
+	clock        IClock
+	keys         map[string]*signingKey
+	signingKeyID string
+
+	Secret        SecretKey   `json:"Secret"`        // Shared secret for HS256, only required to sign or verify HS256 tokens.
+	Keys          []KeyConfig `json:"Keys"`          // Asymmetric keys that verify tokens.
+	SigningKeyID  string      `json:"SigningKeyID"`  // ID of the key that signs new tokens, HS256 is used if empty.
+	Issuer        string      `json:"Issuer"`        // Issuer of new tokens, verified tokens must have the same issuer if set.
+	Audience      string      `json:"Audience"`      // Audience of new tokens, verified tokens must include the audience if set.
+	LeewaySeconds int         `json:"LeewaySeconds"` // Clock skew allowed when checking the times of a token.
+	LegacyUntil   *time.Time  `json:"LegacyUntil"`   // Time until tokens with the user ID in the audience are accepted.
 }
 
 // New creates a new JWT configuration.
@@ -80,71 +95,122 @@ func randomID() (string, error) {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
 
-// Generate will generate a JWT.
-func (c *Configuration) Generate(userID string, duration time.Duration) (string, error) {
-	// Ensure a secret is present.
-	if len(c.Secret) < 32 {
+// Generate will generate a JWT that expires after the duration. The ID, times,
+// issuer, and audience are set if they are missing from the claims.
+func (c *Configuration) Generate(claims Claims, duration time.Duration) (string, error) {
+	// Ensure a secret is present if the token is signed with HS256.
+	if len(c.signingKeyID) == 0 && len(c.Secret) < 32 {
 		return "", ErrSecretTooShort
+	} else if len(claims.Subject) == 0 {
+		return "", ErrSubjectInvalid
 	}
 
 	// Get the current time.
 	now := c.clock.Now()
 
 	// Generate a unique ID.
-	unique, err := randomID()
-	if err != nil {
-		return "", err
+	if len(claims.ID) == 0 {
+		unique, err := randomID()
+		if err != nil {
+			return "", err
+		}
+		claims.ID = unique
 	}
 
-	// Create the claims.
-	claims := &jwt.StandardClaims{
-		Id:        unique,
-		Audience:  userID,
-		NotBefore: now.Unix(),
-		IssuedAt:  now.Unix(),
-		ExpiresAt: now.Add(duration).Unix(),
+	// Set the claims.
+	if len(claims.Issuer) == 0 {
+		claims.Issuer = c.Issuer
+	}
+	if len(claims.Audience) == 0 && len(c.Audience) > 0 {
+		claims.Audience = []string{c.Audience}
+	}
+	claims.IssuedAt = now
+	claims.NotBefore = now
+	claims.ExpiresAt = now.Add(duration)
+
+	// Sign the token with the signing key if one is set.
+	if len(c.signingKeyID) > 0 {
+		k := c.keys[c.signingKeyID]
+		token := jwt.NewWithClaims(k.method, newTokenClaims(claims))
+		token.Header["kid"] = c.signingKeyID
+		return token.SignedString(k.privateKey)
 	}
 
 	// Create the token.
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newTokenClaims(claims))
 
 	// Signe the token.
 	return token.SignedString([]byte(c.Secret))
 }
 
-// Verify will ensure a JWT is valid.
-func (c *Configuration) Verify(s string) (string, error) {
-	token, err := jwt.ParseWithClaims(s, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(c.Secret), nil
-	})
+// Verify will ensure a JWT is valid and return the claims.
+func (c *Configuration) Verify(s string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(s, &tokenClaims{}, c.keyFunc)
 	if err == nil {
-		// If a token is valid, return the audience.
-		if claims, ok := token.Claims.(*jwt.StandardClaims); ok && token.Valid {
-			if claims.ExpiresAt == 0 {
-				return "", ErrExpirationInvalid
-			} else if claims.NotBefore == 0 {
-				return "", ErrNotBeforeInvalid
-			} else if claims.IssuedAt == 0 {
-				return "", ErrIssuedAtInvalid
-			} else if len(claims.Audience) == 0 {
-				return "", ErrAudienceInvalid
-			}
-			return claims.Audience, nil
+		// If a token is valid, return the claims.
+		if claims, ok := token.Claims.(*tokenClaims); ok && token.Valid {
+			return c.validate(claims)
 		}
 	}
 
 	// Handle the error.
 	if ve, ok := err.(*jwt.ValidationError); ok {
 		if ve.Errors&jwt.ValidationErrorMalformed != 0 {
-			return "", ErrMalformed
+			return nil, ErrMalformed
 		} else if ve.Errors&(jwt.ValidationErrorSignatureInvalid) != 0 {
-			return "", ErrSignatureInvalid
-		} else if ve.Errors&(jwt.ValidationErrorExpired) != 0 {
-			return "", ErrExpired
-		} else if ve.Errors&(jwt.ValidationErrorNotValidYet) != 0 {
-			return "", ErrNotValidYet
+			return nil, ErrSignatureInvalid
+		} else if ve.Errors&(jwt.ValidationErrorUnverifiable) != 0 && ve.Inner != nil {
+			return nil, ve.Inner
 		}
+	} else if err == nil {
+		err = ErrMalformed
+	}
+
+	return nil, err
+}
+
+// validate will ensure the claims of a token with a valid signature are
+// valid and return them.
+func (c *Configuration) validate(t *tokenClaims) (*Claims, error) {
+	if t.ExpiresAt == 0 {
+		return nil, ErrExpirationInvalid
+	} else if t.NotBefore == 0 {
+		return nil, ErrNotBeforeInvalid
+	} else if t.IssuedAt == 0 {
+		return nil, ErrIssuedAtInvalid
+	} else if len(t.ID) == 0 {
+		return nil, ErrIDInvalid
+	}
+
+	// Allow for clock skew between the servers.
+	now := c.clock.Now().Unix()
+	leeway := int64(c.LeewaySeconds)
+	if now >= t.ExpiresAt+leeway {
+		return nil, ErrExpired
+	} else if now < t.NotBefore-leeway {
+		return nil, ErrNotValidYet
+	}
+
+	claims := t.claims()
+
+	// Tokens in the old format store the user ID in the audience and are
+	// only accepted until the end of the transition window.
+	if len(t.Subject) == 0 {
+		if len(t.Audience) != 1 || len(t.Audience[0]) == 0 {
+			return nil, ErrAudienceInvalid
+		} else if c.LegacyUntil == nil || !c.clock.Now().Before(*c.LegacyUntil) {
+			return nil, ErrSubjectInvalid
+		}
+		claims.Subject = t.Audience[0]
+		claims.Audience = nil
+		return claims, nil
+	}
+
+	if len(c.Issuer) > 0 && claims.Issuer != c.Issuer {
+		return nil, ErrIssuerInvalid
+	} else if len(c.Audience) > 0 && !claims.HasAudience(c.Audience) {
+		return nil, ErrAudienceInvalid
 	}
 
-	return "", err
+	return claims, nil
 }
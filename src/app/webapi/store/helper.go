@@ -0,0 +1,54 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+
+	"app/webapi/pkg/securegen"
+)
+
+// newToken returns a random token to give to the client and the hash of the
+// token to store in the database.
+func newToken() (token string, hash string, err error) {
+	b, err := securegen.Bytes(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the SHA-256 hash of a token. A fast hash is safe here
+// because the tokens have 256 bits of entropy.
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// recordExists returns if the record exists or not.
+func recordExists(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	} else if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return false, err
+}
+
+// affectedRows returns the number of rows affected by the query.
+func affectedRows(result sql.Result) int {
+	if result == nil {
+		return 0
+	}
+
+	// If successful, get the number of affected rows.
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+
+	return int(count)
+}
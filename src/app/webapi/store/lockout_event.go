@@ -0,0 +1,105 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// Lockout events.
+const (
+	LockoutLocked   = "locked"
+	LockoutUnlocked = "unlocked"
+)
+
+// NewLockoutEvent returns a new query object.
+func NewLockoutEvent(db component.IDatabase, q component.IQuery) *LockoutEvent {
+	return &LockoutEvent{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// LockoutEvent is a record of an account that was locked after too many
+// failed logins or that was unlocked by an admin.
+type LockoutEvent struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID          string     `db:"id"`
+	UserID      *string    `db:"user_id"`
+	Email       string     `db:"email"`
+	Event       string     `db:"event"`
+	IPAddress   string     `db:"ip_address"`
+	Failures    int        `db:"failures"`
+	LockedUntil *time.Time `db:"locked_until"`
+	CreatedBy   *string    `db:"created_by"`
+	CreatedAt   *time.Time `db:"created_at"`
+}
+
+// Table returns the table name.
+func (x *LockoutEvent) Table() string {
+	return "lockout_event"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *LockoutEvent) PrimaryKey() string {
+	return "id"
+}
+
+// NewGroup returns an empty group.
+func (x *LockoutEvent) NewGroup() *LockoutEventGroup {
+	group := make(LockoutEventGroup, 0)
+	return &group
+}
+
+// LockoutEventGroup represents a group of lockout events.
+type LockoutEventGroup []LockoutEvent
+
+// Locked adds an event for an account that is locked for the duration. The
+// user ID is empty if the email does not belong to a user.
+func (x *LockoutEvent) Locked(userID, email, ip string, failures int, duration time.Duration) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO lockout_event
+		(id, user_id, email, event, ip_address, failures, locked_until)
+		VALUES
+		(?,NULLIF(?, ''),?,?,?,?,DATE_ADD(NOW(), INTERVAL ? SECOND))
+		`,
+		uuid, userID, email, LockoutLocked, ip, failures, int(duration.Seconds()))
+
+	return uuid, err
+}
+
+// Unlocked adds an event for an account that was unlocked by a user.
+func (x *LockoutEvent) Unlocked(userID, email, createdBy string) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO lockout_event
+		(id, user_id, email, event, created_by)
+		VALUES
+		(?,?,?,?,?)
+		`,
+		uuid, userID, email, LockoutUnlocked, createdBy)
+
+	return uuid, err
+}
+
+// FindAllByEmail will find the events of an email address, newest first.
+func (x *LockoutEvent) FindAllByEmail(dest *LockoutEventGroup, email string) (err error) {
+	err = x.db.Select(dest, `
+		SELECT * FROM lockout_event
+		WHERE email = ?
+		ORDER BY created_at DESC`,
+		email)
+	return
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"app/webapi/component"
+)
+
+// Scopes of a login throttle.
+const (
+	ThrottleAccount   = "account"
+	ThrottleIP        = "ip"
+	ThrottleMagicLink = "magic_link"
+)
+
+// NewLoginThrottle returns a new query object.
+func NewLoginThrottle(db component.IDatabase, q component.IQuery) *LoginThrottle {
+	return &LoginThrottle{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// LoginThrottle counts the failed logins of an account or an IP address and
+// blocks further logins until a time. It also counts the login links sent to
+// an email address. The subject of an account is the email address, which is
+// not case sensitive.
+type LoginThrottle struct {
+	component.IQuery
+	db component.IDatabase
+
+	Scope        string     `db:"scope"`
+	Subject      string     `db:"subject"`
+	Failures     int        `db:"failures"`
+	BlockedUntil *time.Time `db:"blocked_until"`
+	CreatedAt    *time.Time `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
+
+	// RetryAfter is the number of seconds until the block ends.
+	RetryAfter int `db:"retry_after"`
+}
+
+// Table returns the table name.
+func (x *LoginThrottle) Table() string {
+	return "login_throttle"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *LoginThrottle) PrimaryKey() string {
+	return "scope"
+}
+
+// Find will find the throttle of the subject.
+func (x *LoginThrottle) Find(scope, subject string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT *,
+			GREATEST(TIMESTAMPDIFF(SECOND, NOW(), COALESCE(blocked_until, NOW())), 0) AS retry_after
+		FROM login_throttle
+		WHERE scope = ?
+		AND subject = ?
+		LIMIT 1`,
+		scope, strings.ToLower(subject))
+	return recordExists(err)
+}
+
+// Fail will count a failed login of the subject and return the number of
+// failures. The count starts over when the last failure is older than the
+// window.
+func (x *LoginThrottle) Fail(scope, subject string, window time.Duration) (int, error) {
+	_, err := x.db.Exec(`
+		INSERT INTO login_throttle
+		(scope, subject, failures)
+		VALUES
+		(?,?,1)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(updated_at < DATE_SUB(NOW(), INTERVAL ? SECOND), 1, failures + 1)
+		`,
+		scope, strings.ToLower(subject), int(window.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = x.Find(scope, subject)
+	return x.Failures, err
+}
+
+// Block will block the logins of the subject for the duration.
+func (x *LoginThrottle) Block(scope, subject string, duration time.Duration) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE login_throttle
+		SET blocked_until = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE scope = ?
+		AND subject = ?
+		`,
+		int(duration.Seconds()), scope, strings.ToLower(subject))
+	return
+}
+
+// Clear will remove the failures and the block of the subject.
+func (x *LoginThrottle) Clear(scope, subject string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		DELETE FROM login_throttle
+		WHERE scope = ?
+		AND subject = ?
+		`,
+		scope, strings.ToLower(subject))
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
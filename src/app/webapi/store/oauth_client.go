@@ -0,0 +1,127 @@
+package store
+
+import (
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewOAuthClient returns a new query object.
+func NewOAuthClient(db component.IDatabase, q component.IQuery) *OAuthClient {
+	return &OAuthClient{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// OAuthClient is an application that can request tokens from the OAuth
+// server. A confidential client has a secret and a public client, like a
+// mobile app, does not. Only the hash of the secret is stored.
+type OAuthClient struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID           string     `db:"id"`
+	Name         string     `db:"name"`
+	SecretHash   string     `db:"secret_hash"`
+	Scope        string     `db:"scope"`
+	RedirectURIs string     `db:"redirect_uris"`
+	CreatedBy    *string    `db:"created_by"`
+	CreatedAt    *time.Time `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *OAuthClient) Table() string {
+	return "oauth_client"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *OAuthClient) PrimaryKey() string {
+	return "id"
+}
+
+// Create adds a new client and returns the ID and the secret to give to the
+// client. The secret is empty for a public client. The scope and the redirect
+// URIs are separated by spaces.
+func (x *OAuthClient) Create(name, scope, redirectURIs string, confidential bool,
+	createdBy string) (string, string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, hash := "", ""
+	if confidential {
+		secret, hash, err = newToken()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	var creator *string
+	if len(createdBy) > 0 {
+		creator = &createdBy
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO oauth_client
+		(id, name, secret_hash, scope, redirect_uris, created_by)
+		VALUES
+		(?,?,?,?,?,?)
+		`,
+		uuid, name, hash, strings.Join(strings.Fields(scope), " "),
+		strings.Join(strings.Fields(redirectURIs), " "), creator)
+	if err != nil {
+		return "", "", err
+	}
+
+	return uuid, secret, nil
+}
+
+// Confidential returns true if the client has a secret.
+func (x *OAuthClient) Confidential() bool {
+	return len(x.SecretHash) > 0
+}
+
+// MatchSecret returns true if the secret belongs to a confidential client.
+func (x *OAuthClient) MatchSecret(secret string) bool {
+	if !x.Confidential() {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(x.SecretHash)) == 1
+}
+
+// Scopes returns the scopes the client can request.
+func (x *OAuthClient) Scopes() []string {
+	return strings.Fields(x.Scope)
+}
+
+// AllowsScope returns true if the client can request the scope.
+func (x *OAuthClient) AllowsScope(scope string) bool {
+	for _, v := range x.Scopes() {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectURIList returns the registered redirect URIs.
+func (x *OAuthClient) RedirectURIList() []string {
+	return strings.Fields(x.RedirectURIs)
+}
+
+// AllowsRedirectURI returns true if the URI exactly matches one of the
+// registered redirect URIs.
+func (x *OAuthClient) AllowsRedirectURI(uri string) bool {
+	for _, v := range x.RedirectURIList() {
+		if v == uri {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewOAuthCode returns a new query object.
+func NewOAuthCode(db component.IDatabase, q component.IQuery) *OAuthCode {
+	return &OAuthCode{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// OAuthCode is a short lived authorization code that a client exchanges for
+// tokens after a user approves the client. Only the hash of the code is
+// stored.
+type OAuthCode struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID            string     `db:"id"`
+	ClientID      string     `db:"client_id"`
+	UserID        string     `db:"user_id"`
+	CodeHash      string     `db:"code_hash"`
+	RedirectURI   string     `db:"redirect_uri"`
+	Scope         string     `db:"scope"`
+	CodeChallenge string     `db:"code_challenge"`
+	ExpiresAt     *time.Time `db:"expires_at"`
+	UsedAt        *time.Time `db:"used_at"`
+	CreatedAt     *time.Time `db:"created_at"`
+	UpdatedAt     *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *OAuthCode) Table() string {
+	return "oauth_code"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *OAuthCode) PrimaryKey() string {
+	return "id"
+}
+
+// Create adds a new code and returns the code to give to the client. The
+// code challenge is the S256 PKCE challenge and can be empty for a
+// confidential client.
+func (x *OAuthCode) Create(clientID, userID, redirectURI, scope, codeChallenge string,
+	duration time.Duration) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	code, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO oauth_code
+		(id, client_id, user_id, code_hash, redirect_uri, scope, code_challenge, expires_at)
+		VALUES
+		(?,?,?,?,?,?,?,DATE_ADD(NOW(), INTERVAL ? SECOND))
+		`,
+		uuid, clientID, userID, hash, redirectURI, scope, codeChallenge,
+		int(duration.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// FindOneByCode will find an unexpired code. A used code is returned as well
+// so the reuse can be detected.
+func (x *OAuthCode) FindOneByCode(code string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM oauth_code
+		WHERE code_hash = ?
+		AND expires_at > NOW()
+		LIMIT 1`,
+		hashToken(code))
+	return recordExists(err)
+}
+
+// MarkUsed will mark a code as exchanged. The affected count is 0 if the code
+// was already used.
+func (x *OAuthCode) MarkUsed(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE oauth_code
+		SET used_at = NOW()
+		WHERE id = ?
+		AND used_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
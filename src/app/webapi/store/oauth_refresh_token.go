@@ -0,0 +1,146 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewOAuthRefreshToken returns a new query object.
+func NewOAuthRefreshToken(db component.IDatabase, q component.IQuery) *OAuthRefreshToken {
+	return &OAuthRefreshToken{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// OAuthRefreshToken is a single use token that an OAuth client can exchange
+// for a new access token. Each rotation creates a new token in the same
+// family and the family of a code grant is the ID of the code.
+type OAuthRefreshToken struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID        string     `db:"id"`
+	ClientID  string     `db:"client_id"`
+	UserID    string     `db:"user_id"`
+	FamilyID  string     `db:"family_id"`
+	TokenHash string     `db:"token_hash"`
+	Scope     string     `db:"scope"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *OAuthRefreshToken) Table() string {
+	return "oauth_refresh_token"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *OAuthRefreshToken) PrimaryKey() string {
+	return "id"
+}
+
+// Create adds a new refresh token to a family and returns the token to give
+// to the client. Only the hash of the token is stored.
+func (x *OAuthRefreshToken) Create(clientID, userID, familyID, scope string,
+	duration time.Duration) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO oauth_refresh_token
+		(id, client_id, user_id, family_id, token_hash, scope, expires_at)
+		VALUES
+		(?,?,?,?,?,?,DATE_ADD(NOW(), INTERVAL ? SECOND))
+		`,
+		uuid, clientID, userID, familyID, hash, scope, int(duration.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// FindOneByToken will find an unexpired refresh token.
+func (x *OAuthRefreshToken) FindOneByToken(token string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM oauth_refresh_token
+		WHERE token_hash = ?
+		AND expires_at > NOW()
+		LIMIT 1`,
+		hashToken(token))
+	return recordExists(err)
+}
+
+// Used returns true if the token was already exchanged or revoked.
+func (x *OAuthRefreshToken) Used() bool {
+	return x.UsedAt != nil || x.RevokedAt != nil
+}
+
+// MarkUsed will mark a token as exchanged. The affected count is 0 if the
+// token was already used or revoked.
+func (x *OAuthRefreshToken) MarkUsed(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE oauth_refresh_token
+		SET used_at = NOW()
+		WHERE id = ?
+		AND used_at IS NULL
+		AND revoked_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
+
+// RevokeFamily will revoke every token in a family.
+func (x *OAuthRefreshToken) RevokeFamily(familyID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE oauth_refresh_token
+		SET revoked_at = NOW()
+		WHERE family_id = ?
+		AND revoked_at IS NULL
+		`,
+		familyID)
+	return
+}
+
+// RevokeUser will revoke every token of a user.
+func (x *OAuthRefreshToken) RevokeUser(userID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE oauth_refresh_token
+		SET revoked_at = NOW()
+		WHERE user_id = ?
+		AND revoked_at IS NULL
+		`,
+		userID)
+	return
+}
+
+// RevokeUserExceptFamily will revoke every token of a user that is not in
+// the family.
+func (x *OAuthRefreshToken) RevokeUserExceptFamily(userID, familyID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE oauth_refresh_token
+		SET revoked_at = NOW()
+		WHERE user_id = ?
+		AND family_id <> ?
+		AND revoked_at IS NULL
+		`,
+		userID, familyID)
+	return
+}
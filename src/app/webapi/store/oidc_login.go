@@ -0,0 +1,119 @@
+package store
+
+import (
+	"encoding/base64"
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewOIDCLogin returns a new query object.
+func NewOIDCLogin(db component.IDatabase, q component.IQuery) *OIDCLogin {
+	return &OIDCLogin{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// OIDCLogin is a login with an identity provider that is waiting for the
+// user to return. The state identifies the login when the provider redirects
+// back and only the hash of the state is stored.
+type OIDCLogin struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID           string     `db:"id"`
+	Provider     string     `db:"provider"`
+	StateHash    string     `db:"state_hash"`
+	Nonce        string     `db:"nonce"`
+	CodeVerifier string     `db:"code_verifier"`
+	ExpiresAt    *time.Time `db:"expires_at"`
+	UsedAt       *time.Time `db:"used_at"`
+	CreatedAt    *time.Time `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *OIDCLogin) Table() string {
+	return "oidc_login"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *OIDCLogin) PrimaryKey() string {
+	return "id"
+}
+
+// Create adds a new login for the provider with a random nonce and PKCE code
+// verifier that are set on the object. The state to send to the provider is
+// returned.
+func (x *OIDCLogin) Create(provider string, duration time.Duration) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	state, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := securegen.Bytes(32)
+	if err != nil {
+		return "", err
+	}
+
+	verifier, err := securegen.Bytes(32)
+	if err != nil {
+		return "", err
+	}
+
+	x.ID = uuid
+	x.Provider = provider
+	x.StateHash = hash
+	x.Nonce = base64.RawURLEncoding.EncodeToString(nonce)
+	x.CodeVerifier = base64.RawURLEncoding.EncodeToString(verifier)
+
+	_, err = x.db.Exec(`
+		INSERT INTO oidc_login
+		(id, provider, state_hash, nonce, code_verifier, expires_at)
+		VALUES
+		(?,?,?,?,?,DATE_ADD(NOW(), INTERVAL ? SECOND))
+		`,
+		x.ID, x.Provider, x.StateHash, x.Nonce, x.CodeVerifier,
+		int(duration.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return state, nil
+}
+
+// FindOneByState will find an unused and unexpired login.
+func (x *OIDCLogin) FindOneByState(state string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM oidc_login
+		WHERE state_hash = ?
+		AND used_at IS NULL
+		AND expires_at > NOW()
+		LIMIT 1`,
+		hashToken(state))
+	return recordExists(err)
+}
+
+// MarkUsed will mark a login as finished. The affected count is 0 if the
+// login was already used.
+func (x *OIDCLogin) MarkUsed(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE oidc_login
+		SET used_at = NOW()
+		WHERE id = ?
+		AND used_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewRecoveryCode returns a new query object.
+func NewRecoveryCode(db component.IDatabase, q component.IQuery) *RecoveryCode {
+	return &RecoveryCode{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// RecoveryCode is a one-time code that can be used in place of the second
+// factor. Only the hash of the code is stored.
+type RecoveryCode struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	CodeHash  string     `db:"code_hash"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *RecoveryCode) Table() string {
+	return "recovery_code"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *RecoveryCode) PrimaryKey() string {
+	return "id"
+}
+
+// NewGroup returns an empty group.
+func (x *RecoveryCode) NewGroup() *RecoveryCodeGroup {
+	group := make(RecoveryCodeGroup, 0)
+	return &group
+}
+
+// RecoveryCodeGroup represents a group of recovery codes.
+type RecoveryCodeGroup []RecoveryCode
+
+// Table returns the table name.
+func (x RecoveryCodeGroup) Table() string {
+	return "recovery_code"
+}
+
+// PrimaryKey returns the primary key field.
+func (x RecoveryCodeGroup) PrimaryKey() string {
+	return "id"
+}
+
+// Replace will remove the recovery codes of the user and add the hashes of
+// the new codes.
+func (x *RecoveryCode) Replace(userID string, hashes []string) error {
+	err := x.DeleteByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		uuid, err := securegen.UUID()
+		if err != nil {
+			return err
+		}
+
+		_, err = x.db.Exec(`
+			INSERT INTO recovery_code
+			(id, user_id, code_hash)
+			VALUES
+			(?,?,?)
+			`,
+			uuid, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindUnused returns the recovery codes of the user that were not used.
+func (x *RecoveryCode) FindUnused(userID string) (*RecoveryCodeGroup, error) {
+	group := x.NewGroup()
+	err := x.db.Select(group, `
+		SELECT * FROM recovery_code
+		WHERE user_id = ?
+		AND used_at IS NULL`,
+		userID)
+	return group, err
+}
+
+// MarkUsed will mark a code as used. The affected count is 0 if the code was
+// already used.
+func (x *RecoveryCode) MarkUsed(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE recovery_code
+		SET used_at = NOW()
+		WHERE id = ?
+		AND used_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
+
+// DeleteByUserID will remove the recovery codes of the user.
+func (x *RecoveryCode) DeleteByUserID(userID string) (err error) {
+	_, err = x.db.Exec(`
+		DELETE FROM recovery_code
+		WHERE user_id = ?
+		`,
+		userID)
+	return
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewRefreshToken returns a new query object.
+func NewRefreshToken(db component.IDatabase, q component.IQuery) *RefreshToken {
+	return &RefreshToken{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// RefreshToken is a single use token that can be exchanged for a new access
+// token. Each rotation creates a new token in the same family.
+type RefreshToken struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	FamilyID  string     `db:"family_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *RefreshToken) Table() string {
+	return "refresh_token"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *RefreshToken) PrimaryKey() string {
+	return "id"
+}
+
+// Create adds a new refresh token to a family and returns the token to give
+// to the client. Only the hash of the token is stored.
+func (x *RefreshToken) Create(userID, familyID string, duration time.Duration) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO refresh_token
+		(id, user_id, family_id, token_hash, expires_at)
+		VALUES
+		(?,?,?,?,DATE_ADD(NOW(), INTERVAL ? SECOND))
+		`,
+		uuid, userID, familyID, hash, int(duration.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// FindOneByToken will find an unexpired refresh token.
+func (x *RefreshToken) FindOneByToken(token string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM refresh_token
+		WHERE token_hash = ?
+		AND expires_at > NOW()
+		LIMIT 1`,
+		hashToken(token))
+	return recordExists(err)
+}
+
+// Used returns true if the token was already exchanged or revoked.
+func (x *RefreshToken) Used() bool {
+	return x.UsedAt != nil || x.RevokedAt != nil
+}
+
+// MarkUsed will mark a token as exchanged. The affected count is 0 if the
+// token was already used or revoked.
+func (x *RefreshToken) MarkUsed(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE refresh_token
+		SET used_at = NOW()
+		WHERE id = ?
+		AND used_at IS NULL
+		AND revoked_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
+
+// RevokeFamily will revoke every token in a family.
+func (x *RefreshToken) RevokeFamily(familyID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE family_id = ?
+		AND revoked_at IS NULL
+		`,
+		familyID)
+	return
+}
+
+// RevokeUser will revoke every token of a user.
+func (x *RefreshToken) RevokeUser(userID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE user_id = ?
+		AND revoked_at IS NULL
+		`,
+		userID)
+	return
+}
+
+// RevokeUserExceptFamily will revoke every token of a user that is not in
+// the family.
+func (x *RefreshToken) RevokeUserExceptFamily(userID, familyID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE refresh_token
+		SET revoked_at = NOW()
+		WHERE user_id = ?
+		AND family_id <> ?
+		AND revoked_at IS NULL
+		`,
+		userID, familyID)
+	return
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+)
+
+// NewRole returns a new query object.
+func NewRole(db component.IDatabase, q component.IQuery) *Role {
+	return &Role{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// Role is a named set of permissions that can be assigned to users.
+type Role struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID        uint8      `db:"id"`
+	Name      string     `db:"name"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *Role) Table() string {
+	return "role"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *Role) PrimaryKey() string {
+	return "id"
+}
+
+// NewGroup returns an empty group.
+func (x *Role) NewGroup() *RoleGroup {
+	group := make(RoleGroup, 0)
+	return &group
+}
+
+// RoleGroup represents a group of roles.
+type RoleGroup []Role
+
+// Table returns the table name.
+func (x RoleGroup) Table() string {
+	return "role"
+}
+
+// PrimaryKey returns the primary key field.
+func (x RoleGroup) PrimaryKey() string {
+	return "id"
+}
+
+// NamesByUserID returns the names of the roles assigned to a user.
+func (x *Role) NamesByUserID(userID string) ([]string, error) {
+	names := make([]string, 0)
+	err := x.db.Select(&names, `
+		SELECT role.name FROM user_role
+		INNER JOIN role ON role.id = user_role.role_id
+		WHERE user_role.user_id = ?
+		ORDER BY role.name`,
+		userID)
+	return names, err
+}
+
+// Assign gives a role to a user. Assigning a role the user already has is not
+// an error.
+func (x *Role) Assign(userID, name string) (err error) {
+	_, err = x.db.Exec(`
+		INSERT INTO user_role
+		(user_id, role_id)
+		SELECT ?, id FROM role WHERE name = ?
+		ON DUPLICATE KEY UPDATE user_id = user_id
+		`,
+		userID, name)
+	return
+}
+
+// Unassign removes a role from a user.
+func (x *Role) Unassign(userID, name string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		DELETE user_role FROM user_role
+		INNER JOIN role ON role.id = user_role.role_id
+		WHERE user_role.user_id = ?
+		AND role.name = ?
+		`,
+		userID, name)
+	return affectedRows(result), err
+}
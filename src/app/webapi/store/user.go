@@ -1,12 +1,20 @@
 package store
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"app/webapi/component"
 	"app/webapi/pkg/securegen"
 )
 
+// Statuses of a user from the user_status table.
+const (
+	StatusActive   uint8 = 1
+	StatusInactive uint8 = 2
+)
+
 // NewUser returns a new query object.
 func NewUser(db component.IDatabase, q component.IQuery) *User {
 	return &User{
@@ -20,15 +28,16 @@ type User struct {
 	component.IQuery
 	db component.IDatabase
 
-	ID        string     `db:"id"`
-	FirstName string     `db:"first_name"`
-	LastName  string     `db:"last_name"`
-	Email     string     `db:"email"`
-	Password  string     `db:"password"`
-	StatusID  uint8      `db:"status_id"`
-	CreatedAt *time.Time `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"`
-	DeletedAt *time.Time `db:"deleted_at"`
+	ID           string     `db:"id"`
+	FirstName    string     `db:"first_name"`
+	LastName     string     `db:"last_name"`
+	Email        string     `db:"email"`
+	PendingEmail *string    `db:"pending_email"`
+	Password     string     `db:"password"`
+	StatusID     uint8      `db:"status_id"`
+	CreatedAt    *time.Time `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
+	DeletedAt    *time.Time `db:"deleted_at"`
 }
 
 // Table returns the table name.
@@ -41,6 +50,11 @@ func (x *User) PrimaryKey() string {
 	return "id"
 }
 
+// DeletedField returns the soft delete field.
+func (x *User) DeletedField() string {
+	return "deleted_at"
+}
+
 // NewGroup returns an empty group.
 func (x *User) NewGroup() *UserGroup {
 	group := make(UserGroup, 0)
@@ -60,7 +74,12 @@ func (x UserGroup) PrimaryKey() string {
 	return "id"
 }
 
-// Create adds a new user.
+// DeletedField returns the soft delete field.
+func (x UserGroup) DeletedField() string {
+	return "deleted_at"
+}
+
+// Create adds a new user that is inactive until the email is verified.
 func (x *User) Create(firstName, lastName, email, password string) (string, error) {
 	uuid, err := securegen.UUID()
 	if err != nil {
@@ -73,22 +92,109 @@ func (x *User) Create(firstName, lastName, email, password string) (string, erro
 		VALUES
 		(?,?,?,?,?,?)
 		`,
-		uuid, firstName, lastName, email, password, 1)
+		uuid, firstName, lastName, email, password, StatusInactive)
 
 	return uuid, err
 }
 
-// Update makes changes to a user.
-func (x *User) Update(ID, firstName, lastName, email, password string) (err error) {
+// Update makes changes to a user. The email is changed with
+// UpdatePendingEmail once the new address is verified.
+func (x *User) Update(ID, firstName, lastName string) (err error) {
 	_, err = x.db.Exec(`
 		UPDATE user
 		SET
 			first_name = ?,
-			last_name = ?,
-			email = ?,
-			password = ?
+			last_name = ?
+		WHERE id = ?
+		`,
+		firstName, lastName, ID)
+	return
+}
+
+// UserChanges are the fields of a user to change. A nil field is not changed.
+type UserChanges struct {
+	FirstName *string
+	LastName  *string
+}
+
+// UpdateFields makes changes to only the fields of a user that are set.
+func (x *User) UpdateFields(ID string, c UserChanges) (err error) {
+	fields := make([]string, 0, 2)
+	args := make([]interface{}, 0, 3)
+	for _, v := range []struct {
+		column string
+		value  *string
+	}{
+		{"first_name", c.FirstName},
+		{"last_name", c.LastName},
+	} {
+		if v.value != nil {
+			fields = append(fields, v.column+" = ?")
+			args = append(args, *v.value)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	_, err = x.db.Exec(fmt.Sprintf(`
+		UPDATE user
+		SET %s
+		WHERE id = ?
+		`, strings.Join(fields, ", ")),
+		append(args, ID)...)
+	return
+}
+
+// UpdatePendingEmail will store a new email of a user that is not used
+// until it is verified.
+func (x *User) UpdatePendingEmail(ID, email string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user
+		SET pending_email = ?
+		WHERE id = ?
+		`,
+		email, ID)
+	return
+}
+
+// ConfirmPendingEmail will change the email of a user to the pending email.
+func (x *User) ConfirmPendingEmail(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE user
+		SET
+			email = pending_email,
+			pending_email = NULL
+		WHERE id = ?
+		AND pending_email IS NOT NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
+
+// UpdatePassword will change the password of a user.
+func (x *User) UpdatePassword(ID, password string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user
+		SET password = ?
+		WHERE id = ?
+		`,
+		password, ID)
+	return
+}
+
+// UpdateStatus will change the status of a user.
+func (x *User) UpdateStatus(ID string, statusID uint8) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user
+		SET status_id = ?
 		WHERE id = ?
 		`,
-		firstName, lastName, email, password, ID)
+		statusID, ID)
 	return
 }
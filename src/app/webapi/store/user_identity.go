@@ -0,0 +1,70 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// NewUserIdentity returns a new query object.
+func NewUserIdentity(db component.IDatabase, q component.IQuery) *UserIdentity {
+	return &UserIdentity{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// UserIdentity links a user to an account at an identity provider. The
+// account is identified by the issuer and the subject of its ID tokens.
+type UserIdentity struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	Issuer    string     `db:"issuer"`
+	Subject   string     `db:"subject"`
+	Email     string     `db:"email"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *UserIdentity) Table() string {
+	return "user_identity"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *UserIdentity) PrimaryKey() string {
+	return "id"
+}
+
+// Create will link the user to the account at the issuer.
+func (x *UserIdentity) Create(userID, issuer, subject, email string) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO user_identity
+		(id, user_id, issuer, subject, email)
+		VALUES
+		(?,?,?,?,?)
+		`,
+		uuid, userID, issuer, subject, email)
+
+	return uuid, err
+}
+
+// FindOneBySubject will find the link to the account at the issuer.
+func (x *UserIdentity) FindOneBySubject(issuer, subject string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM user_identity
+		WHERE issuer = ?
+		AND subject = ?
+		LIMIT 1`,
+		issuer, subject)
+	return recordExists(err)
+}
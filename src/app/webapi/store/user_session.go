@@ -0,0 +1,167 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+)
+
+// maxUserAgentLength is the number of characters of the user agent that are
+// stored with a session.
+const maxUserAgentLength = 255
+
+// NewUserSession returns a new query object.
+func NewUserSession(db component.IDatabase, q component.IQuery) *UserSession {
+	return &UserSession{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// UserSession is a login of a user on a device or a grant of the user to an
+// OAuth client. The ID is the ID of the refresh token family so the session
+// lasts as long as the refresh tokens and every access token issued in it
+// has the ID in the sid claim.
+type UserSession struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	ClientID   string     `db:"client_id"`
+	UserAgent  string     `db:"user_agent"`
+	IPAddress  string     `db:"ip_address"`
+	LastSeenAt *time.Time `db:"last_seen_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  *time.Time `db:"created_at"`
+	UpdatedAt  *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *UserSession) Table() string {
+	return "user_session"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *UserSession) PrimaryKey() string {
+	return "id"
+}
+
+// NewGroup returns an empty group.
+func (x *UserSession) NewGroup() *UserSessionGroup {
+	group := make(UserSessionGroup, 0)
+	return &group
+}
+
+// UserSessionGroup represents a group of sessions.
+type UserSessionGroup []UserSession
+
+// Table returns the table name.
+func (x UserSessionGroup) Table() string {
+	return "user_session"
+}
+
+// PrimaryKey returns the primary key field.
+func (x UserSessionGroup) PrimaryKey() string {
+	return "id"
+}
+
+// Save adds a session or records that it was used again when tokens are
+// issued in it. The client ID is empty for a login. The session expires with
+// the refresh token, after the duration. A revoked session stays revoked.
+func (x *UserSession) Save(ID, userID, clientID, userAgent, ipAddress string, duration time.Duration) (err error) {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO user_session
+		(id, user_id, client_id, user_agent, ip_address, last_seen_at, expires_at)
+		VALUES
+		(?,?,?,?,?,NOW(),DATE_ADD(NOW(), INTERVAL ? SECOND))
+		ON DUPLICATE KEY UPDATE
+			user_agent = VALUES(user_agent),
+			ip_address = VALUES(ip_address),
+			last_seen_at = VALUES(last_seen_at),
+			expires_at = VALUES(expires_at)
+		`,
+		ID, userID, clientID, userAgent, ipAddress, int(duration.Seconds()))
+	return
+}
+
+// FindOneActiveByUser will find a session of a user that is not revoked or
+// expired.
+func (x *UserSession) FindOneActiveByUser(ID, userID string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM user_session
+		WHERE id = ?
+		AND user_id = ?
+		AND revoked_at IS NULL
+		AND expires_at > NOW()
+		LIMIT 1`,
+		ID, userID)
+	return recordExists(err)
+}
+
+// FindAllActiveByUser will find the sessions of a user that are not revoked
+// or expired, most recently used first.
+func (x *UserSession) FindAllActiveByUser(dest *UserSessionGroup, userID string) (err error) {
+	err = x.db.Select(dest, `
+		SELECT * FROM user_session
+		WHERE user_id = ?
+		AND revoked_at IS NULL
+		AND expires_at > NOW()
+		ORDER BY last_seen_at DESC`,
+		userID)
+	return
+}
+
+// RevokeOne will mark a session as revoked.
+func (x *UserSession) RevokeOne(ID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user_session
+		SET revoked_at = NOW()
+		WHERE id = ?
+		AND revoked_at IS NULL
+		`,
+		ID)
+	return
+}
+
+// RevokeUser will mark every session of a user as revoked.
+func (x *UserSession) RevokeUser(userID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user_session
+		SET revoked_at = NOW()
+		WHERE user_id = ?
+		AND revoked_at IS NULL
+		`,
+		userID)
+	return
+}
+
+// RevokeClient will mark every session with an OAuth client as revoked.
+func (x *UserSession) RevokeClient(clientID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user_session
+		SET revoked_at = NOW()
+		WHERE client_id = ?
+		AND revoked_at IS NULL
+		`,
+		clientID)
+	return
+}
+
+// RevokeUserExcept will mark every session of a user except one as revoked.
+func (x *UserSession) RevokeUserExcept(userID, ID string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user_session
+		SET revoked_at = NOW()
+		WHERE user_id = ?
+		AND id <> ?
+		AND revoked_at IS NULL
+		`,
+		userID, ID)
+	return
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+	"app/webapi/pkg/securegen"
+)
+
+// Purposes of a user token.
+const (
+	TokenPasswordReset = "password_reset"
+	TokenEmailVerify   = "email_verify"
+	TokenTwoFactor     = "two_factor"
+	TokenMagicLink     = "magic_link"
+	TokenEmailChange   = "email_change"
+)
+
+// NewUserToken returns a new query object.
+func NewUserToken(db component.IDatabase, q component.IQuery) *UserToken {
+	return &UserToken{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// UserToken is a single use token that is sent to a user to prove they own
+// the email address, like a password reset token.
+type UserToken struct {
+	component.IQuery
+	db component.IDatabase
+
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	Attempts  uint8      `db:"attempts"`
+	ExpiresAt *time.Time `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *UserToken) Table() string {
+	return "user_token"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *UserToken) PrimaryKey() string {
+	return "id"
+}
+
+// Create adds a new token for the purpose and returns the token to send to
+// the user. Only the hash of the token is stored.
+func (x *UserToken) Create(userID, purpose string, duration time.Duration) (string, error) {
+	uuid, err := securegen.UUID()
+	if err != nil {
+		return "", err
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = x.db.Exec(`
+		INSERT INTO user_token
+		(id, user_id, purpose, token_hash, expires_at)
+		VALUES
+		(?,?,?,?,DATE_ADD(NOW(), INTERVAL ? SECOND))
+		`,
+		uuid, userID, purpose, hash, int(duration.Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// FindOneByToken will find an unused and unexpired token for the purpose.
+func (x *UserToken) FindOneByToken(token, purpose string) (bool, error) {
+	err := x.db.Get(x, `
+		SELECT * FROM user_token
+		WHERE token_hash = ?
+		AND purpose = ?
+		AND used_at IS NULL
+		AND expires_at > NOW()
+		LIMIT 1`,
+		hashToken(token), purpose)
+	return recordExists(err)
+}
+
+// MarkUsed will mark a token as used. The affected count is 0 if the token
+// was already used.
+func (x *UserToken) MarkUsed(ID string) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE user_token
+		SET used_at = NOW()
+		WHERE id = ?
+		AND used_at IS NULL
+		`,
+		ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
+
+// MarkAllUsed will mark every unused token of the user for the purpose as
+// used.
+func (x *UserToken) MarkAllUsed(userID, purpose string) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user_token
+		SET used_at = NOW()
+		WHERE user_id = ?
+		AND purpose = ?
+		AND used_at IS NULL
+		`,
+		userID, purpose)
+	return
+}
+
+// AddAttempt will count a failed attempt to use a token and mark the token
+// as used once the maximum attempts are reached.
+func (x *UserToken) AddAttempt(ID string, max int) (err error) {
+	_, err = x.db.Exec(`
+		UPDATE user_token
+		SET
+			attempts = attempts + 1,
+			used_at = IF(attempts >= ?, NOW(), used_at)
+		WHERE id = ?
+		AND used_at IS NULL
+		`,
+		max, ID)
+	return
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"time"
+
+	"app/webapi/component"
+)
+
+// NewUserTOTP returns a new query object.
+func NewUserTOTP(db component.IDatabase, q component.IQuery) *UserTOTP {
+	return &UserTOTP{
+		IQuery: q,
+		db:     db,
+	}
+}
+
+// UserTOTP is the time-based one-time password secret of a user. The second
+// factor is enabled once the secret is confirmed with a valid code.
+type UserTOTP struct {
+	component.IQuery
+	db component.IDatabase
+
+	UserID      string     `db:"user_id"`
+	Secret      string     `db:"secret"`
+	LastStep    int64      `db:"last_step"`
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+	CreatedAt   *time.Time `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+}
+
+// Table returns the table name.
+func (x *UserTOTP) Table() string {
+	return "user_totp"
+}
+
+// PrimaryKey returns the primary key field.
+func (x *UserTOTP) PrimaryKey() string {
+	return "user_id"
+}
+
+// Enabled returns true if the secret is confirmed.
+func (x *UserTOTP) Enabled() bool {
+	return x.ConfirmedAt != nil
+}
+
+// Save will store a new unconfirmed secret for the user. The secret replaces
+// any unconfirmed secret.
+func (x *UserTOTP) Save(userID, secret string) (err error) {
+	_, err = x.db.Exec(`
+		INSERT INTO user_totp
+		(user_id, secret)
+		VALUES
+		(?,?)
+		ON DUPLICATE KEY UPDATE
+			secret = VALUES(secret),
+			last_step = 0,
+			confirmed_at = NULL
+		`,
+		userID, secret)
+	return
+}
+
+// Confirm will enable the second factor for the user.
+func (x *UserTOTP) Confirm(userID string, step int64) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE user_totp
+		SET
+			confirmed_at = NOW(),
+			last_step = ?
+		WHERE user_id = ?
+		AND confirmed_at IS NULL
+		`,
+		step, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
+
+// UseStep will record the step of a code that was used. The affected count
+// is 0 if the code of the step or a later step was already used so a code
+// cannot be replayed.
+func (x *UserTOTP) UseStep(userID string, step int64) (affected int, err error) {
+	result, err := x.db.Exec(`
+		UPDATE user_totp
+		SET last_step = ?
+		WHERE user_id = ?
+		AND last_step < ?
+		`,
+		step, userID, step)
+	if err != nil {
+		return 0, err
+	}
+
+	return affectedRows(result), nil
+}
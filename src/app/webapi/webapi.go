@@ -35,6 +35,8 @@ import (
 
 	"app/webapi/component"
 	"app/webapi/component/auth"
+	"app/webapi/component/oauth"
+	"app/webapi/component/role"
 	"app/webapi/component/root"
 	"app/webapi/component/user"
 	"app/webapi/internal/basemigrate"
@@ -42,10 +44,15 @@ import (
 	"app/webapi/internal/response"
 	"app/webapi/middleware"
 	"app/webapi/model"
+	"app/webapi/pkg/apikey"
 	"app/webapi/pkg/database"
 	"app/webapi/pkg/logger"
+	"app/webapi/pkg/mail"
 	"app/webapi/pkg/passhash"
+	"app/webapi/pkg/passpolicy"
 	"app/webapi/pkg/query"
+	"app/webapi/pkg/rbac"
+	"app/webapi/pkg/revocation"
 	"app/webapi/pkg/router"
 	"app/webapi/pkg/server"
 	"app/webapi/pkg/webtoken"
@@ -57,9 +64,14 @@ import (
 
 // AppConfig contains the application settings with JSON tags.
 type AppConfig struct {
-	Database database.Connection    `json:"Database"`
-	Server   server.Config          `json:"Server"`
-	JWT      webtoken.Configuration `json:"JWT"`
+	Database       database.Connection    `json:"Database"`
+	Server         server.Config          `json:"Server"`
+	JWT            webtoken.Configuration `json:"JWT"`
+	Auth           component.AuthConfig   `json:"Auth"`
+	Mail           mail.Config            `json:"Mail"`
+	Password       passhash.Config        `json:"Password"`
+	PasswordPolicy passpolicy.Config      `json:"PasswordPolicy"`
+	Query          query.Config           `json:"Query"`
 }
 
 // ParseJSON unmarshals the JSON bytes to the struct.
@@ -76,17 +88,47 @@ func Services(config *AppConfig, l logger.ILog) component.Core {
 	// Set up the dependencies.
 	db := Database(config.Database, l)
 	q := query.New(db)
+	if len(config.Query.CursorSecret) > 0 {
+		q.SetCursorKey(config.Query.CursorSecret)
+	} else {
+		l.Printf("Query.CursorSecret is empty, page cursors are only valid until a restart and on this server")
+	}
 	b := bind.New()
+	policy, err := passpolicy.New(config.PasswordPolicy)
+	if err != nil {
+		l.Fatalf("Password policy error: %v", err)
+	}
+	b.SetPasswordPolicy(policy)
 	resp := response.New()
 	t := webtoken.New(config.JWT.Secret)
+	t.Issuer = config.JWT.Issuer
+	t.Audience = config.JWT.Audience
+	t.LeewaySeconds = config.JWT.LeewaySeconds
+	t.LegacyUntil = config.JWT.LegacyUntil
+	err = t.LoadKeys(config.JWT.Keys, config.JWT.SigningKeyID)
+	if err != nil {
+		l.Fatalf("JWT error: %v", err)
+	}
+	if err = config.Password.Validate(); err != nil {
+		l.Fatalf("Password hash error: %v", err)
+	}
 	p := passhash.New()
+	p.Config = config.Password
+	rev := revocation.New(db)
+	acc := rbac.New(db)
+	m := mail.New(config.Mail, l)
 
 	// Create the component core.
-	core := component.NewCore(l, db, q, b, resp, t, p)
+	core := component.NewCore(l, db, q, b, resp, t, p, rev, acc, m, config.Auth)
 
 	return core
 }
 
+// bodyError is an error that provides the body of the error response.
+type bodyError interface {
+	ResponseBody() interface{}
+}
+
 // Routes will set up the components and return the router.
 func Routes(core component.Core) *router.Mux {
 	// Set up the routes.
@@ -94,6 +136,8 @@ func Routes(core component.Core) *router.Mux {
 	root.New(core).Routes(r)
 	auth.New(core).Routes(r)
 	user.New(core).Routes(r)
+	role.New(core).Routes(r)
+	oauth.New(core).Routes(r)
 
 	// Set up the 404 page.
 	r.Instance().NotFound = router.Handler(
@@ -111,10 +155,16 @@ func Routes(core component.Core) *router.Mux {
 				resp.Body.Message = err.Error()
 			}
 
+			// Some errors have their own body, like the OAuth errors.
+			var body interface{} = resp.Body
+			if be, ok := err.(bodyError); ok {
+				body = be.ResponseBody()
+			}
+
 			// Write the content.
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(status)
-			err := json.NewEncoder(w).Encode(resp.Body)
+			err := json.NewEncoder(w).Encode(body)
 			if err != nil {
 				w.Write([]byte(`{"status":"Internal Server Error","message":"problem encoding JSON"}`))
 				return
@@ -133,7 +183,10 @@ func Routes(core component.Core) *router.Mux {
 }
 
 // Handlers returns the HTTP and HTTPS handlers.
-func Handlers(config *AppConfig, l logger.ILog, r *router.Mux) (*http.Server, *http.Server) {
+func Handlers(config *AppConfig, core component.Core, r *router.Mux) (*http.Server, *http.Server) {
+	// Set up the API keys.
+	keys := apikey.New(core.DB)
+
 	// Set up the HTTP listener.
 	httpServer := new(http.Server)
 	httpServer.Addr = config.Server.HTTPAddress()
@@ -144,13 +197,13 @@ func Handlers(config *AppConfig, l logger.ILog, r *router.Mux) (*http.Server, *h
 			http.Redirect(w, req, "https://"+req.Host, http.StatusMovedPermanently)
 		})
 	} else {
-		httpServer.Handler = middleware.Wrap(r, l, config.JWT.Secret)
+		httpServer.Handler = middleware.Wrap(r, core.Log, core.Token, core.Revocation, keys, core.Auth.Cookie.Enabled)
 	}
 
 	// Set up the HTTPS listener.
 	httpsServer := new(http.Server)
 	httpsServer.Addr = config.Server.HTTPSAddress()
-	httpsServer.Handler = middleware.Wrap(r, l, config.JWT.Secret)
+	httpsServer.Handler = middleware.Wrap(r, core.Log, core.Token, core.Revocation, keys, core.Auth.Cookie.Enabled)
 
 	return httpServer, httpsServer
 }
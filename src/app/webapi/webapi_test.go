@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"app/webapi"
+	"app/webapi/internal/principal"
 	"app/webapi/pkg/database"
 	"app/webapi/pkg/jsonconfig"
 
@@ -144,6 +145,10 @@ func TestRoutes500(t *testing.T) {
 	mux := webapi.Routes(core)
 
 	r := httptest.NewRequest("GET", "/v1/user", nil)
+	r = r.WithContext(principal.NewContext(r.Context(), &principal.Principal{
+		UserID: "1",
+		Roles:  []string{principal.RoleUser},
+	}))
 	w := httptest.NewRecorder()
 	mux.ServeHTTP(w, r)
 
@@ -159,4 +164,5 @@ func TestParseAppConfig(t *testing.T) {
 
 	assert.Equal(t, "127.0.0.1", config.Database.Hostname)
 	assert.Equal(t, 8080, config.Server.HTTPPort)
+	assert.Equal(t, true, config.Auth.AnonymousToken)
 }